@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"git.sr.ht/~jakintosh/command-go/pkg/args"
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/importer"
+	"git.sr.ht/~jakintosh/teller/internal/parser"
+	"git.sr.ht/~jakintosh/teller/internal/session"
+)
+
+var importCommand = &args.Command{
+	Name: "import",
+	Help: "Import bank statements into the pending batch.",
+	Subcommands: []*args.Command{
+		importCSVCommand,
+		importOFXCommand,
+		importQIFCommand,
+	},
+}
+
+var importCSVCommand = &args.Command{
+	Name: "csv",
+	Help: "import a bank CSV export using a column-mapping profile",
+	Options: []args.Option{
+		{Short: 'p', Long: "profile", Type: args.OptionTypeParameter, Help: "name of the mapping profile to use"},
+		{Short: 'a', Long: "account", Type: args.OptionTypeParameter, Help: "funding account for every row"},
+		{Long: "date-column", Type: args.OptionTypeParameter, Help: "1-based column holding the date"},
+		{Long: "date-format", Type: args.OptionTypeParameter, Help: "Go date layout (default 01/02/2006)"},
+		{Long: "payee-column", Type: args.OptionTypeParameter, Help: "1-based column holding the description"},
+		{Long: "amount-column", Type: args.OptionTypeParameter, Help: "1-based column holding a signed amount"},
+		{Long: "debit-column", Type: args.OptionTypeParameter, Help: "1-based column for money out"},
+		{Long: "credit-column", Type: args.OptionTypeParameter, Help: "1-based column for money in"},
+		{Long: "skip-rows", Type: args.OptionTypeParameter, Help: "number of header rows to skip"},
+		{Long: "invert", Type: args.OptionTypeFlag, Help: "flip the sign of every amount"},
+		{Long: "commodity", Type: args.OptionTypeParameter, Help: "commodity of the amounts (default $)"},
+		{Long: "save", Type: args.OptionTypeFlag, Help: "save the resulting profile for reuse"},
+		{Long: "profiles", Type: args.OptionTypeParameter, Help: "path to the profiles file"},
+		{Long: "rules", Type: args.OptionTypeParameter, Help: "path to the categorization rules file"},
+	},
+	Operands: []args.Operand{
+		{
+			Name: "csv-file",
+			Help: "path to the CSV statement",
+		},
+	},
+	Handler: func(i *args.Input) error {
+		csvFile := i.GetOperand("csv-file")
+
+		name := i.GetParameter("profile")
+		if name == nil || *name == "" {
+			return fmt.Errorf("a profile name is required (--profile)")
+		}
+
+		profilesPath, err := pathOption(i, "profiles", importer.DefaultProfilesPath)
+		if err != nil {
+			return err
+		}
+
+		profiles, err := importer.LoadProfiles(profilesPath)
+		if err != nil {
+			return err
+		}
+
+		// Start from the saved profile and layer any command line overrides on top
+		profile := profiles[*name]
+		applyProfileOptions(i, &profile)
+		if err := profile.Validate(); err != nil {
+			return fmt.Errorf("profile '%s': %w", *name, err)
+		}
+
+		if i.GetFlag("save") {
+			profiles[*name] = profile
+			if err := importer.SaveProfiles(profilesPath, profiles); err != nil {
+				return err
+			}
+			fmt.Printf("Saved profile '%s' to %s\n", *name, profilesPath)
+		}
+
+		file, err := os.Open(csvFile)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		result, err := importer.ParseCSV(file, profile)
+		if err != nil {
+			return fmt.Errorf("failed to import '%s': %w", csvFile, err)
+		}
+		return stageImport(i, result, nil)
+	},
+}
+
+var importOFXCommand = &args.Command{
+	Name: "ofx",
+	Help: "import an OFX or QFX statement, skipping transactions already recorded",
+	Options: []args.Option{
+		{Short: 'a', Long: "account", Type: args.OptionTypeParameter, Help: "funding account for every transaction"},
+		{Short: 'l', Long: "ledger", Type: args.OptionTypeParameter, Help: "ledger file to check for known FITIDs (required)"},
+		{Long: "rules", Type: args.OptionTypeParameter, Help: "path to the categorization rules file"},
+	},
+	Operands: []args.Operand{
+		{
+			Name: "ofx-file",
+			Help: "path to the OFX/QFX statement",
+		},
+	},
+	Handler: func(i *args.Input) error {
+		ofxFile := i.GetOperand("ofx-file")
+
+		account := i.GetParameterOr("account", "")
+		if account == "" {
+			return fmt.Errorf("a funding account is required (--account)")
+		}
+
+		// FITIDs already present in the ledger mark transactions imported before
+		ledgerFile := i.GetParameterOr("ledger", "")
+		if ledgerFile == "" {
+			return fmt.Errorf("a ledger file is required to skip known transactions (--ledger)")
+		}
+		parseResult, err := parser.ParseFile(ledgerFile)
+		if err != nil {
+			return fmt.Errorf("failed to parse ledger file '%s': %w", ledgerFile, err)
+		}
+
+		file, err := os.Open(ofxFile)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		result, err := importer.ParseOFX(file, account)
+		if err != nil {
+			return fmt.Errorf("failed to import '%s': %w", ofxFile, err)
+		}
+		return stageImport(i, result, parseResult.Transactions)
+	},
+}
+
+var importQIFCommand = &args.Command{
+	Name: "qif",
+	Help: "import a QIF export, mapping its categories to ledger accounts",
+	Options: []args.Option{
+		{Short: 'a', Long: "account", Type: args.OptionTypeParameter, Help: "funding account for every transaction"},
+		{Long: "categories", Type: args.OptionTypeParameter, Help: "path to the category mapping file"},
+		{Long: "day-first", Type: args.OptionTypeFlag, Help: "dates are written DD/MM/YY"},
+		{Long: "rules", Type: args.OptionTypeParameter, Help: "path to the categorization rules file"},
+	},
+	Operands: []args.Operand{
+		{
+			Name: "qif-file",
+			Help: "path to the QIF export",
+		},
+	},
+	Handler: func(i *args.Input) error {
+		qifFile := i.GetOperand("qif-file")
+
+		account := i.GetParameterOr("account", "")
+		if account == "" {
+			return fmt.Errorf("a funding account is required (--account)")
+		}
+
+		categoriesPath, err := pathOption(i, "categories", importer.DefaultCategoriesPath)
+		if err != nil {
+			return err
+		}
+		categories, err := importer.LoadCategories(categoriesPath)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(qifFile)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		result, err := importer.ParseQIF(file, importer.QIFOptions{
+			Account:    account,
+			Categories: categories,
+			DayFirst:   i.GetFlag("day-first"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import '%s': %w", qifFile, err)
+		}
+		return stageImport(i, result, nil)
+	},
+}
+
+// stageImport applies categorization rules to imported drafts and appends them
+// to the pending batch session. Drafts whose FITID already appears in the known
+// transactions or the pending batch are skipped.
+func stageImport(i *args.Input, result core.ParseResult, known []core.Transaction) error {
+	for _, issue := range result.Issues {
+		fmt.Printf("Skipped line %d: %s\n", issue.Line, issue.Message)
+	}
+
+	rulesPath, err := pathOption(i, "rules", importer.DefaultRulesPath)
+	if err != nil {
+		return err
+	}
+	rules, err := importer.LoadRules(rulesPath)
+	if err != nil {
+		return err
+	}
+
+	batch, err := session.LoadBatch()
+	if err != nil {
+		return err
+	}
+
+	recorded := make([]core.Transaction, 0, len(known)+len(batch))
+	recorded = append(recorded, known...)
+	recorded = append(recorded, batch...)
+	fitids := importer.KnownFITIDs(recorded)
+	drafts, duplicates := importer.SkipKnownFITIDs(result.Transactions, fitids)
+	matched := importer.ApplyRules(rules, drafts)
+
+	batch = append(batch, drafts...)
+	if err := session.SaveBatch(batch); err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d transaction(s) into the pending batch (%d total).\n", len(drafts), len(batch))
+	if duplicates > 0 {
+		fmt.Printf("Skipped %d transaction(s) already recorded.\n", duplicates)
+	}
+	if matched > 0 {
+		fmt.Printf("Rules matched %d transaction(s).\n", matched)
+	}
+	return nil
+}
+
+// pathOption returns the value of a path option, falling back to a default location.
+func pathOption(i *args.Input, name string, fallback func() (string, error)) (string, error) {
+	if path := i.GetParameterOr(name, ""); path != "" {
+		return path, nil
+	}
+	return fallback()
+}
+
+// applyProfileOptions overrides profile fields with any values given on the command line.
+func applyProfileOptions(i *args.Input, profile *importer.Profile) {
+	if account := i.GetParameter("account"); account != nil {
+		profile.Account = *account
+	}
+	if format := i.GetParameter("date-format"); format != nil {
+		profile.DateFormat = *format
+	}
+	profile.DateColumn = i.GetIntParameterOr("date-column", profile.DateColumn)
+	profile.PayeeColumn = i.GetIntParameterOr("payee-column", profile.PayeeColumn)
+	profile.AmountColumn = i.GetIntParameterOr("amount-column", profile.AmountColumn)
+	profile.DebitColumn = i.GetIntParameterOr("debit-column", profile.DebitColumn)
+	profile.CreditColumn = i.GetIntParameterOr("credit-column", profile.CreditColumn)
+	profile.SkipRows = i.GetIntParameterOr("skip-rows", profile.SkipRows)
+	if commodity := i.GetParameter("commodity"); commodity != nil {
+		profile.Commodity = *commodity
+	}
+	if i.GetFlag("invert") {
+		profile.Invert = true
+	}
+}
@@ -35,6 +35,18 @@ var root = &args.Command{
 			Type:  args.OptionTypeFlag,
 			Help:  "use verbose output",
 		},
+		{
+			Short: 'c',
+			Long:  "currency",
+			Type:  args.OptionTypeParameter,
+			Help:  "reporting currency for converted totals (default $)",
+		},
+		{
+			Short: 's',
+			Long:  "strict",
+			Type:  args.OptionTypeFlag,
+			Help:  "only allow accounts declared with account directives",
+		},
 	},
 	Operands: []args.Operand{
 		{
@@ -44,6 +56,8 @@ var root = &args.Command{
 	},
 	Subcommands: []*args.Command{
 		version.Command(VersionInfo),
+		importCommand,
+		rulesCommand,
 	},
 	Handler: func(i *args.Input) error {
 
@@ -84,6 +98,10 @@ var root = &args.Command{
 
 		// Create and start the TUI
 		model := tui.NewModel(db, ledgerFile, buildReport)
+		if currency := i.GetParameterOr("currency", ""); currency != "" {
+			model.SetCurrency(currency)
+		}
+		model.SetStrict(i.GetFlag("strict"))
 		if len(previousBatch) > 0 {
 			model.SetBatch(previousBatch)
 		}
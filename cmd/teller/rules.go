@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+
+	"git.sr.ht/~jakintosh/command-go/pkg/args"
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/importer"
+	"github.com/shopspring/decimal"
+)
+
+var rulesCommand = &args.Command{
+	Name: "rules",
+	Help: "Inspect the rules that categorize imported transactions.",
+	Subcommands: []*args.Command{
+		rulesTestCommand,
+	},
+}
+
+var rulesTestCommand = &args.Command{
+	Name: "test",
+	Help: "show which rule fires for a bank description",
+	Options: []args.Option{
+		{Short: 'a', Long: "account", Type: args.OptionTypeParameter, Help: "funding account of the imported row"},
+		{Long: "amount", Type: args.OptionTypeParameter, Help: "signed amount of the imported row"},
+		{Long: "rules", Type: args.OptionTypeParameter, Help: "path to the categorization rules file"},
+	},
+	Operands: []args.Operand{
+		{
+			Name: "description",
+			Help: "raw description from the bank statement",
+		},
+	},
+	Handler: func(i *args.Input) error {
+		description := i.GetOperand("description")
+
+		rulesPath, err := pathOption(i, "rules", importer.DefaultRulesPath)
+		if err != nil {
+			return err
+		}
+		rules, err := importer.LoadRules(rulesPath)
+		if err != nil {
+			return err
+		}
+
+		// Build a draft shaped like an imported row so amount and account conditions apply
+		draft := core.Transaction{
+			Payee:       description,
+			Draft:       true,
+			Description: description,
+		}
+		account := i.GetParameterOr("account", "")
+		rawAmount := i.GetParameterOr("amount", "")
+		if account != "" || rawAmount != "" {
+			amount := core.NewAmount(decimal.Zero, "")
+			if rawAmount != "" {
+				if amount, err = core.ParseAmount(rawAmount); err != nil {
+					return fmt.Errorf("invalid amount '%s'", rawAmount)
+				}
+			}
+			draft.Postings = []core.Posting{{Account: account, Amount: amount}}
+		}
+
+		index, ok := importer.MatchRule(rules, draft)
+		if !ok {
+			fmt.Printf("No rule matched (%d rule(s) in %s)\n", len(rules), rulesPath)
+			return nil
+		}
+
+		rule := rules[index]
+		fmt.Printf("Matched %s\n", rule.Label(index))
+		result := rule.Apply(draft)
+		fmt.Printf("  payee:   %s\n", result.Payee)
+		if len(rule.Targets) > 0 {
+			for _, target := range rule.Targets {
+				fmt.Printf("  target:  %s\n", target.Account)
+			}
+		}
+		if rule.Cleared != nil {
+			fmt.Printf("  cleared: %t\n", *rule.Cleared)
+		}
+		if rule.Comment != "" {
+			fmt.Printf("  comment: %s\n", rule.Comment)
+		}
+		return nil
+	},
+}
@@ -0,0 +1,326 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCommodity is the commodity of bare numbers. They balance against it
+// and are written back with it.
+const DefaultCommodity = "$"
+
+// Amount is a quantity of a commodity, such as $12.34, €5.00 or 10 AAPL.
+// The placement of the commodity and its spacing are kept so amounts are
+// written back the way they were entered.
+type Amount struct {
+	Quantity  decimal.Decimal
+	Commodity string      // empty for bare numbers
+	Suffix    bool        // commodity follows the quantity, as in "10 AAPL"
+	Spaced    bool        // commodity and quantity are separated by a space
+	Style     NumberStyle // decimal mark and digit grouping, as written
+}
+
+// Commodity is a commodity declared with a commodity directive.
+type Commodity struct {
+	Symbol string
+	Format string // sample amount giving its style, e.g. "$1,000.00"; empty when not given
+}
+
+// NewAmount creates an amount of the commodity in its conventional style:
+// symbols such as $ or € go in front, names such as AAPL or EUR follow.
+func NewAmount(quantity decimal.Decimal, commodity string) *Amount {
+	amount := &Amount{Quantity: quantity, Commodity: commodity}
+	if isCommodityName(commodity) {
+		amount.Suffix = true
+		amount.Spaced = true
+	}
+	return amount
+}
+
+// ParseAmount parses an amount such as "12.34", "$-12.34", "-€ 5", "10 AAPL"
+// or `3 "Gold Coin"`. The sign may come before or after a leading commodity
+// but must be directly attached to the digits. Thousands may be grouped with
+// commas, as in "$1,234.56", or with points before a decimal comma, as in
+// "1.234,56 €", and a comma that can't group thousands is a decimal mark, as
+// in "12,50 €"; the style is kept so the amount is written back the same way.
+func ParseAmount(s string) (*Amount, error) {
+	return parseAmount(s, CommodityFormats{})
+}
+
+// parseAmount parses an amount, reading the digits of commodities declared in
+// formats in their declared style and inferring the style of the rest.
+func parseAmount(s string, formats CommodityFormats) (*Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty amount")
+	}
+	amount := &Amount{}
+	rest := s
+
+	sign, rest := takeSign(rest)
+	if commodity, after, ok := takeCommodity(rest); ok {
+		amount.Commodity = commodity
+		trimmed := strings.TrimLeft(after, " \t")
+		amount.Spaced = len(trimmed) != len(after)
+		rest = trimmed
+		if sign == "" {
+			sign, rest = takeSign(rest)
+		}
+	}
+
+	number, after := takeNumber(rest)
+	if number == "" {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if after != "" {
+		if amount.Commodity != "" {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		trimmed := strings.TrimLeft(after, " \t")
+		commodity, tail, ok := takeCommodity(trimmed)
+		if !ok || tail != "" {
+			return nil, fmt.Errorf("invalid amount %q", s)
+		}
+		amount.Commodity = commodity
+		amount.Suffix = true
+		amount.Spaced = len(trimmed) != len(after)
+	}
+
+	// The commodity's declared style decides how its digits are read
+	if format, ok := formats.Sample(amount.Commodity); ok {
+		amount.Style = format.Style
+	} else {
+		amount.Style = inferStyle(number, formats.DecimalComma)
+	}
+	digits, ok := amount.Style.read(number)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	quantity, err := decimal.NewFromString(digits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	if sign == "-" {
+		quantity = quantity.Neg()
+	}
+	amount.Quantity = quantity
+	return amount, nil
+}
+
+// SplitCommodity separates a commodity written before or after s from the
+// rest of it, as in "€12*2" or "3+4 AAPL". The sign may come before a
+// leading commodity. It returns an empty commodity if there is none.
+func SplitCommodity(s string) (commodity, rest string, suffix bool) {
+	s = strings.TrimSpace(s)
+	sign, unsigned := takeSign(s)
+	if commodity, after, ok := takeCommodity(unsigned); ok {
+		return commodity, sign + strings.TrimSpace(after), false
+	}
+	end := len(s)
+	if strings.HasSuffix(s, `"`) {
+		start := strings.LastIndex(s[:end-1], `"`)
+		if start >= 0 && start < end-2 {
+			return s[start+1 : end-1], strings.TrimSpace(s[:start]), true
+		}
+		return "", s, false
+	}
+	for end > 0 {
+		r, size := utf8.DecodeLastRuneInString(s[:end])
+		if !isCommodityRune(r) {
+			break
+		}
+		end -= size
+	}
+	if end == len(s) || end == 0 {
+		return "", s, false
+	}
+	return s[end:], strings.TrimSpace(s[:end]), true
+}
+
+// Unit returns the commodity the amount balances in.
+func (a Amount) Unit() string {
+	if a.Commodity == "" {
+		return DefaultCommodity
+	}
+	return a.Commodity
+}
+
+// WithQuantity returns an amount of the same commodity and style with a new quantity.
+func (a Amount) WithQuantity(quantity decimal.Decimal) *Amount {
+	a.Quantity = quantity
+	return &a
+}
+
+// Neg returns the amount with its sign flipped.
+func (a Amount) Neg() *Amount {
+	return a.WithQuantity(a.Quantity.Neg())
+}
+
+// Equal reports whether two amounts have the same quantity and commodity.
+func (a Amount) Equal(other Amount) bool {
+	return a.Unit() == other.Unit() && a.Quantity.Equal(other.Quantity)
+}
+
+// Number returns the quantity with the precision and style it was written with.
+func (a Amount) Number() string {
+	return a.Style.format(a.Quantity.StringFixed(a.places()))
+}
+
+// places is the number of decimal places the quantity was written with.
+func (a Amount) places() int32 {
+	return max(-a.Quantity.Exponent(), 0)
+}
+
+// String formats the amount in ledger style, e.g. "$-12.34" or "-10 AAPL".
+func (a Amount) String() string {
+	if a.Commodity == "" {
+		return a.Number()
+	}
+	symbol := quoteCommodity(a.Commodity)
+	space := ""
+	if a.Spaced {
+		space = " "
+	}
+	if a.Suffix {
+		return a.Number() + space + symbol
+	}
+	return symbol + space + a.Number()
+}
+
+// Balance sums quantities per commodity.
+type Balance map[string]decimal.Decimal
+
+// Add adds an amount to the balance of its commodity.
+func (b Balance) Add(amount Amount) {
+	b[amount.Unit()] = b[amount.Unit()].Add(amount.Quantity)
+}
+
+// IsZero reports whether every commodity balances to zero.
+func (b Balance) IsZero() bool {
+	for _, quantity := range b {
+		if !quantity.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// Commodities returns the commodities with a non-zero balance, sorted.
+func (b Balance) Commodities() []string {
+	var commodities []string
+	for commodity, quantity := range b {
+		if !quantity.IsZero() {
+			commodities = append(commodities, commodity)
+		}
+	}
+	sort.Strings(commodities)
+	return commodities
+}
+
+// String formats the non-zero balances, e.g. "$12.00, 3 AAPL".
+func (b Balance) String() string {
+	var parts []string
+	for _, commodity := range b.Commodities() {
+		parts = append(parts, NewAmount(b[commodity], commodity).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// takeSign removes a leading + or - from s.
+func takeSign(s string) (string, string) {
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		return s[:1], s[1:]
+	}
+	return "", s
+}
+
+// takeNumber removes a leading run of digits, points and commas from s.
+func takeNumber(s string) (string, string) {
+	end := 0
+	digits := false
+	for end < len(s) && (isDigit(s[end]) || s[end] == '.' || s[end] == ',') {
+		digits = digits || isDigit(s[end])
+		end++
+	}
+	if !digits {
+		return "", s
+	}
+	return s[:end], s[end:]
+}
+
+// takeCommodity removes a leading commodity symbol, quoted or not, from s.
+func takeCommodity(s string) (string, string, bool) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.Index(s[1:], `"`)
+		if end <= 0 {
+			return "", s, false
+		}
+		return s[1 : end+1], s[end+2:], true
+	}
+	end := 0
+	for end < len(s) {
+		r, size := utf8.DecodeRuneInString(s[end:])
+		if !isCommodityRune(r) {
+			break
+		}
+		end += size
+	}
+	if end == 0 {
+		return "", s, false
+	}
+	return s[:end], s[end:], true
+}
+
+// isCommodityRune reports whether r may appear in an unquoted commodity symbol.
+func isCommodityRune(r rune) bool {
+	if unicode.IsSpace(r) || unicode.IsDigit(r) {
+		return false
+	}
+	return !strings.ContainsRune(`-+.,;:@=!?*/^&|<>(){}[]"'`, r)
+}
+
+// isCommodityName reports whether a commodity is a word, like AAPL or EUR,
+// rather than a currency symbol.
+func isCommodityName(commodity string) bool {
+	if commodity == "" {
+		return false
+	}
+	for _, r := range commodity {
+		if !unicode.IsLetter(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteCommodity quotes commodities that could not be read back unquoted.
+func quoteCommodity(commodity string) string {
+	for _, r := range commodity {
+		if !isCommodityRune(r) {
+			return `"` + commodity + `"`
+		}
+	}
+	return commodity
+}
+
+// isGroupSeparator reports whether s has sep at i between a digit and a group of three digits.
+func isGroupSeparator(s string, i int, sep byte) bool {
+	if s[i] != sep || i == 0 || !isDigit(s[i-1]) || i+4 > len(s) {
+		return false
+	}
+	group := s[i+1 : i+4]
+	if !isDigit(group[0]) || !isDigit(group[1]) || !isDigit(group[2]) {
+		return false
+	}
+	return i+4 == len(s) || !isDigit(s[i+4])
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
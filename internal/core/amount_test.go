@@ -0,0 +1,143 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input     string
+		quantity  string
+		commodity string
+		output    string
+	}{
+		{input: "12.34", quantity: "12.34", output: "12.34"},
+		{input: "-12.34", quantity: "-12.34", output: "-12.34"},
+		{input: "$12.34", quantity: "12.34", commodity: "$", output: "$12.34"},
+		{input: "$-12.34", quantity: "-12.34", commodity: "$", output: "$-12.34"},
+		{input: "-$12.34", quantity: "-12.34", commodity: "$", output: "$-12.34"},
+		{input: "€ 5", quantity: "5", commodity: "€", output: "€ 5"},
+		{input: "10 AAPL", quantity: "10", commodity: "AAPL", output: "10 AAPL"},
+		{input: "-2.5 EUR", quantity: "-2.5", commodity: "EUR", output: "-2.5 EUR"},
+		{input: `3 "Gold Coin"`, quantity: "3", commodity: "Gold Coin", output: `3 "Gold Coin"`},
+		{input: "$1,234.56", quantity: "1234.56", commodity: "$", output: "$1,234.56"},
+		{input: "-1,000,000 EUR", quantity: "-1000000", commodity: "EUR", output: "-1,000,000 EUR"},
+		{input: "1.234,56 €", quantity: "1234.56", commodity: "€", output: "1.234,56 €"},
+		{input: "-1.234.567 EUR", quantity: "-1234567", commodity: "EUR", output: "-1.234.567 EUR"},
+	}
+	for _, tc := range tests {
+		amount, err := ParseAmount(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if !amount.Quantity.Equal(decimal.RequireFromString(tc.quantity)) || amount.Commodity != tc.commodity {
+			t.Errorf("%q: expected %s %q, got %s %q", tc.input, tc.quantity, tc.commodity, amount.Quantity, amount.Commodity)
+		}
+		if amount.String() != tc.output {
+			t.Errorf("%q: expected to format as %q, got %q", tc.input, tc.output, amount.String())
+		}
+	}
+
+	for _, input := range []string{"", "$", "AAPL", "$- 12", "$12 3.45", "12 AAPL MSFT", "12 $ AAPL", "$12,34.00", "1.234,5.6 €", "1,23.456,00 €"} {
+		if _, err := ParseAmount(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestSplitCommodity(t *testing.T) {
+	tests := []struct {
+		input     string
+		commodity string
+		rest      string
+		suffix    bool
+	}{
+		{input: "12*2", rest: "12*2"},
+		{input: "€12*2", commodity: "€", rest: "12*2"},
+		{input: "-€ 5", commodity: "€", rest: "-5"},
+		{input: "3+4 AAPL", commodity: "AAPL", rest: "3+4", suffix: true},
+		{input: `2 "Gold Coin"`, commodity: "Gold Coin", rest: "2", suffix: true},
+		{input: "(1+2)", rest: "(1+2)"},
+	}
+	for _, tc := range tests {
+		commodity, rest, suffix := SplitCommodity(tc.input)
+		if commodity != tc.commodity || rest != tc.rest || suffix != tc.suffix {
+			t.Errorf("%q: expected (%q, %q, %v), got (%q, %q, %v)", tc.input, tc.commodity, tc.rest, tc.suffix, commodity, rest, suffix)
+		}
+	}
+}
+
+func TestBalanceTracksCommodities(t *testing.T) {
+	balance := Balance{}
+	balance.Add(*NewAmount(decimal.RequireFromString("12.00"), ""))
+	balance.Add(*NewAmount(decimal.RequireFromString("-12.00"), "$"))
+	balance.Add(*NewAmount(decimal.RequireFromString("10"), "AAPL"))
+	balance.Add(*NewAmount(decimal.RequireFromString("3.50"), "€"))
+
+	if balance.IsZero() {
+		t.Fatalf("expected unbalanced commodities")
+	}
+	if got := balance.String(); got != "10 AAPL, €3.50" {
+		t.Fatalf("expected bare numbers to balance against dollars, got %q", got)
+	}
+
+	balance.Add(*NewAmount(decimal.RequireFromString("-10"), "AAPL"))
+	balance.Add(*NewAmount(decimal.RequireFromString("-3.50"), "€"))
+	if !balance.IsZero() {
+		t.Fatalf("expected balance to be zero, got %q", balance.String())
+	}
+}
+
+func TestTransactionStringMixedCommodities(t *testing.T) {
+	tx := Transaction{
+		Date:   time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC),
+		Payee:  "Broker",
+		Status: Cleared,
+		Postings: []Posting{
+			{Account: "Assets:Brokerage", Amount: NewAmount(decimal.RequireFromString("10"), "AAPL")},
+			{Account: "Assets:Euro", Amount: NewAmount(decimal.RequireFromString("12.50"), "€")},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-1500.00"), "")},
+			{Account: "Equity:Conversion"},
+		},
+	}
+
+	expected := "2025/04/01 * Broker\n" +
+		"\tAssets:Brokerage\t\t\t\t\t\t     10 AAPL\n" +
+		"\tAssets:Euro\t\t\t\t\t\t\t\t€    12.50\n" +
+		"\tAssets:Checking\t\t\t\t\t\t\t$ -1500.00\n" +
+		"\tEquity:Conversion\n"
+	if got := tx.String(); got != expected {
+		t.Fatalf("unexpected output:\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestPostingJSONKeepsCommodity(t *testing.T) {
+	postings := []Posting{
+		{Account: "Assets:Brokerage", Amount: NewAmount(decimal.RequireFromString("10"), "AAPL")},
+		{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-12.30"), "")},
+		{Account: "Equity:Conversion"},
+	}
+	data, err := json.Marshal(postings)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"10 AAPL"`) {
+		t.Fatalf("expected amounts to be stored as ledger text, got %s", data)
+	}
+
+	var decoded []Posting
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	for i := range postings {
+		if !decoded[i].Equal(postings[i]) {
+			t.Fatalf("posting %d: expected %+v, got %+v", i, postings[i], decoded[i])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/shopspring/decimal"
+)
+
+// RunningBalances tracks the balance of each account as transactions are
+// applied in journal order.
+type RunningBalances map[string]Balance
+
+// AssertionFailure describes a balance assertion that doesn't hold.
+type AssertionFailure struct {
+	Posting  int // index of the posting in its transaction
+	Account  string
+	Expected Amount
+	Actual   Amount
+}
+
+// String describes the failure, e.g. "balance assertion failed for Assets:Checking: expected $10.00, got $12.00".
+func (f AssertionFailure) String() string {
+	return fmt.Sprintf("balance assertion failed for %s: expected %s, got %s", f.Account, f.Expected, f.Actual)
+}
+
+// Apply adds the postings of tx to the running balances and checks their
+// balance assertions. Balance assignments and a single elided amount are
+// filled in along the way, and an unbalanced transaction without one gets a
+// posting to its bucket; the returned transaction carries those amounts.
+func (r RunningBalances) Apply(tx Transaction) (Transaction, []AssertionFailure) {
+	tx.Postings = slices.Clone(tx.Postings)
+
+	// Work out the amounts that aren't written, starting with assignments
+	pending := RunningBalances{}
+	balance := Balance{}
+	elided := -1
+	missing := 0
+	for i := range tx.Postings {
+		posting := &tx.Postings[i]
+		if posting.IsAssignment() {
+			current := r.quantity(posting.Account, posting.Assertion.Unit()).Add(pending.quantity(posting.Account, posting.Assertion.Unit()))
+			posting.Amount = posting.Assertion.WithQuantity(posting.Assertion.Quantity.Sub(current))
+		}
+		if posting.Amount == nil {
+			if posting.Balances() {
+				elided = i
+				missing++
+			}
+			continue
+		}
+		pending.add(posting.Account, *posting.Amount)
+		if posting.Balances() {
+			balance.Add(*posting.Weight())
+		}
+	}
+
+	// An elided amount takes whatever is left in each commodity, as does the bucket when there's none
+	if missing == 0 && tx.Bucket != "" && !balance.IsZero() {
+		tx.Postings = append(tx.Postings, Posting{Account: tx.Bucket})
+		elided = len(tx.Postings) - 1
+		missing = 1
+	}
+	var remainder Balance
+	if missing == 1 {
+		remainder = Balance{}
+		for _, commodity := range balance.Commodities() {
+			remainder[commodity] = balance[commodity].Neg()
+		}
+		if commodities := remainder.Commodities(); len(commodities) == 1 {
+			tx.Postings[elided].Amount = NewAmount(remainder[commodities[0]], commodities[0])
+		}
+	}
+
+	var failures []AssertionFailure
+	for i, posting := range tx.Postings {
+		switch {
+		case posting.Amount != nil:
+			r.add(posting.Account, *posting.Amount)
+		case i == elided && remainder != nil:
+			for commodity, quantity := range remainder {
+				r.add(posting.Account, *NewAmount(quantity, commodity))
+			}
+		}
+		if posting.Assertion == nil {
+			continue
+		}
+		actual := r.quantity(posting.Account, posting.Assertion.Unit())
+		if !actual.Equal(posting.Assertion.Quantity) {
+			failures = append(failures, AssertionFailure{
+				Posting:  i,
+				Account:  posting.Account,
+				Expected: *posting.Assertion,
+				Actual:   *posting.Assertion.WithQuantity(actual),
+			})
+		}
+	}
+	return tx, failures
+}
+
+// quantity returns the running balance of an account in one commodity.
+func (r RunningBalances) quantity(account, commodity string) decimal.Decimal {
+	return r[account][commodity]
+}
+
+// add adds an amount to the running balance of an account.
+func (r RunningBalances) add(account string, amount Amount) {
+	if r[account] == nil {
+		r[account] = Balance{}
+	}
+	r[account].Add(amount)
+}
@@ -0,0 +1,67 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRunningBalancesCheckAssertions(t *testing.T) {
+	date := time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC)
+	balances := RunningBalances{}
+
+	opening := Transaction{
+		Date:  date,
+		Payee: "Opening Balance",
+		Postings: []Posting{
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("1284.56"), "$")},
+			{Account: "Equity:Opening"},
+		},
+	}
+	if _, failures := balances.Apply(opening); len(failures) != 0 {
+		t.Fatalf("expected no failures, got %v", failures)
+	}
+
+	purchase := Transaction{
+		Date:  date,
+		Payee: "Grocery Store",
+		Postings: []Posting{
+			{Account: "Expenses:Food", Amount: NewAmount(decimal.RequireFromString("50.00"), "$"), Assertion: NewAmount(decimal.RequireFromString("40.00"), "$")},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-50.00"), "$"), Assertion: NewAmount(decimal.RequireFromString("1234.56"), "$")},
+		},
+	}
+	_, failures := balances.Apply(purchase)
+	if len(failures) != 1 {
+		t.Fatalf("expected one failure, got %v", failures)
+	}
+	if failures[0].Posting != 0 || failures[0].String() != "balance assertion failed for Expenses:Food: expected $40.00, got $50.00" {
+		t.Fatalf("unexpected failure: %+v", failures[0])
+	}
+
+	// An assignment brings the account to its balance, and the elided posting takes the difference
+	adjustment := Transaction{
+		Date:  date,
+		Payee: "Reconcile",
+		Postings: []Posting{
+			{Account: "Assets:Checking", Assertion: NewAmount(decimal.RequireFromString("1200.00"), "$")},
+			{Account: "Expenses:Misc"},
+		},
+	}
+	resolved, failures := balances.Apply(adjustment)
+	if len(failures) != 0 {
+		t.Fatalf("expected no failures, got %v", failures)
+	}
+	if got := resolved.Postings[0].Amount.String(); got != "$-34.56" {
+		t.Fatalf("expected assignment amount $-34.56, got %s", got)
+	}
+	if got := resolved.Postings[1].Amount.String(); got != "$34.56" {
+		t.Fatalf("expected elided amount $34.56, got %s", got)
+	}
+	if adjustment.Postings[0].Amount != nil {
+		t.Fatalf("expected the original transaction to keep its assignment")
+	}
+	if got := balances["Assets:Checking"].String(); got != "$1200.00" {
+		t.Fatalf("expected checking to end at $1200.00, got %s", got)
+	}
+}
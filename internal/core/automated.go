@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AutomatedTransaction adds its postings to every transaction with a posting
+// its predicate matches, written "= /^Expenses:Food/" followed by postings.
+// Amounts without a commodity scale the matched posting's amount; amounts
+// with one are added as they are.
+type AutomatedTransaction struct {
+	Predicate Predicate
+	Comment   string
+	Postings  []Posting
+}
+
+// Predicate selects the postings an automated transaction applies to.
+type Predicate struct {
+	Text    string         // as written, e.g. "/^Expenses:Food/" or "payee =~ /Market/"
+	Payee   bool           // Pattern matches the transaction's payee rather than the account
+	Pattern *regexp.Regexp // case-insensitive, like ledger's own
+}
+
+// ParsePredicate parses an automated transaction's predicate: "/REGEX/" or a
+// bare account pattern match accounts, "@PAYEE" matches payees, and
+// "account =~ /REGEX/" or "payee =~ /REGEX/" name the field explicitly.
+func ParsePredicate(s string) (Predicate, error) {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return Predicate{}, fmt.Errorf("automated transaction missing a predicate")
+	}
+	predicate := Predicate{Text: text}
+	pattern := text
+	if strings.HasPrefix(pattern, "expr ") {
+		pattern = strings.Trim(strings.TrimSpace(pattern[len("expr "):]), `'"`)
+	}
+	if field, value, ok := strings.Cut(pattern, "=~"); ok {
+		switch strings.TrimSpace(field) {
+		case "account":
+		case "payee":
+			predicate.Payee = true
+		default:
+			return Predicate{}, fmt.Errorf("unsupported predicate %q", text)
+		}
+		pattern = strings.TrimSpace(value)
+	} else if strings.HasPrefix(pattern, "@") {
+		predicate.Payee = true
+		pattern = pattern[1:]
+	}
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		pattern = pattern[1 : len(pattern)-1]
+	}
+
+	compiled, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return Predicate{}, fmt.Errorf("invalid predicate %q: %w", text, err)
+	}
+	predicate.Pattern = compiled
+	return predicate, nil
+}
+
+// Matches reports whether the predicate selects a posting of tx.
+func (p Predicate) Matches(tx Transaction, posting Posting) bool {
+	if p.Pattern == nil {
+		return false
+	}
+	if p.Payee {
+		return p.Pattern.MatchString(tx.Payee)
+	}
+	return p.Pattern.MatchString(posting.Account)
+}
+
+// Generate returns the postings the automated transaction adds to tx, in the
+// order of the postings they were generated for.
+func (a AutomatedTransaction) Generate(tx Transaction) []Posting {
+	var generated []Posting
+	for _, matched := range tx.Postings {
+		if !a.Predicate.Matches(tx, matched) {
+			continue
+		}
+		for _, posting := range a.Postings {
+			posting.Notes = nil
+			switch {
+			case posting.Amount == nil:
+				// No amount copies the matched amount
+				if matched.Amount == nil {
+					continue
+				}
+				posting.Amount = matched.Amount
+			case posting.Amount.Commodity == "":
+				// A bare number is a multiplier of the matched amount, kept to its precision
+				if matched.Amount == nil {
+					continue
+				}
+				quantity := matched.Amount.Quantity.Mul(posting.Amount.Quantity).Round(matched.Amount.places())
+				posting.Amount = matched.Amount.WithQuantity(quantity)
+			}
+			generated = append(generated, posting)
+		}
+	}
+	return generated
+}
+
+// ApplyAutomated returns the postings every automated transaction adds to tx.
+func ApplyAutomated(automated []AutomatedTransaction, tx Transaction) []Posting {
+	var generated []Posting
+	for _, auto := range automated {
+		generated = append(generated, auto.Generate(tx)...)
+	}
+	return generated
+}
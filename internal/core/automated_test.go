@@ -0,0 +1,77 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		input   string
+		payee   bool
+		matches string
+		misses  string
+	}{
+		{input: "/^Expenses:Food/", matches: "expenses:food:groceries", misses: "Assets:Food"},
+		{input: "Expenses:Food", matches: "Expenses:Food", misses: "Expenses:Fun"},
+		{input: "account =~ /Dining$/", matches: "Expenses:Dining", misses: "Expenses:Dining:Out"},
+		{input: "expr 'payee =~ /market/'", payee: true, matches: "Farmers Market", misses: "Cafe"},
+		{input: "@Employer", payee: true, matches: "Employer Inc", misses: "Bank"},
+	}
+	for _, tc := range tests {
+		predicate, err := ParsePredicate(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if predicate.Payee != tc.payee || predicate.Text != tc.input {
+			t.Errorf("%q: expected payee %v, got %+v", tc.input, tc.payee, predicate)
+		}
+		if !predicate.Pattern.MatchString(tc.matches) || predicate.Pattern.MatchString(tc.misses) {
+			t.Errorf("%q: expected to match %q but not %q", tc.input, tc.matches, tc.misses)
+		}
+	}
+
+	for _, input := range []string{"", "/(/", "date =~ /2025/"} {
+		if _, err := ParsePredicate(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestAutomatedTransactionGeneratesPostings(t *testing.T) {
+	predicate, err := ParsePredicate("/^Expenses:Food/")
+	if err != nil {
+		t.Fatalf("ParsePredicate returned error: %v", err)
+	}
+	auto := AutomatedTransaction{
+		Predicate: predicate,
+		Postings: []Posting{
+			{Account: "Budget:Food", Kind: VirtualPosting, Amount: NewAmount(decimal.RequireFromString("-1"), "")},
+			{Account: "Savings:Roundup", Kind: VirtualPosting, Amount: NewAmount(decimal.RequireFromString("0.1"), "")},
+			{Account: "Fees:Flat", Kind: VirtualPosting, Amount: NewAmount(decimal.RequireFromString("1.00"), "$")},
+		},
+	}
+	tx := Transaction{
+		Payee: "Grocery Store",
+		Postings: []Posting{
+			{Account: "Expenses:Food:Groceries", Amount: NewAmount(decimal.RequireFromString("12.34"), "$")},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-12.34"), "$")},
+		},
+	}
+
+	generated := ApplyAutomated([]AutomatedTransaction{auto}, tx)
+	expected := []string{"$-12.34", "$1.23", "$1.00"}
+	if len(generated) != len(expected) {
+		t.Fatalf("expected %d generated postings, got %+v", len(expected), generated)
+	}
+	for i, want := range expected {
+		if got := generated[i].Amount.String(); got != want || generated[i].Kind != VirtualPosting {
+			t.Errorf("posting %d: expected virtual %s, got %s (%d)", i, want, got, generated[i].Kind)
+		}
+	}
+	if len(auto.Generate(Transaction{Postings: tx.Postings[1:]})) != 0 {
+		t.Fatalf("expected no postings without a matching account")
+	}
+}
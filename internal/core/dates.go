@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the date formats accepted in ledger files.
+var dateLayouts = []string{"2006-01-02", "2006/01/02", "2006-1-2", "2006/1/2", "2006.01.02", "2006.1.2"}
+
+// ParseDate parses a ledger date such as "2025/01/15" or "2025-1-5".
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date format '%s'", s)
+}
+
+// shortDateLayouts are the month and day formats read with a default year.
+var shortDateLayouts = []string{"01/02", "1/2", "01-02", "1-2", "01.02", "1.2"}
+
+// ParseDateInYear parses a ledger date, or a month and day such as "01/15"
+// that falls in year.
+func ParseDateInYear(s string, year int) (time.Time, error) {
+	if date, err := ParseDate(s); err == nil {
+		return date, nil
+	}
+	for _, layout := range shortDateLayouts {
+		short, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		date := time.Date(year, short.Month(), short.Day(), 0, 0, 0, 0, time.UTC)
+		if date.Day() != short.Day() {
+			return time.Time{}, fmt.Errorf("date '%s' doesn't exist in %d", s, year)
+		}
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date format '%s'", s)
+}
+
+// FormatDate formats a date the way transactions are written, e.g. "2025/01/15".
+func FormatDate(date time.Time) string {
+	return date.Format("2006/01/02")
+}
+
+// ParseDateTag reads the posting dates written in comments as "[DATE]",
+// "[=AUX]" or "[DATE=AUX]". Dates that aren't given are zero.
+func ParseDateTag(comments ...string) (date, aux time.Time) {
+	for _, comment := range comments {
+		if start, end := findDateTag(comment); start >= 0 {
+			date, aux, _ = parseDateTag(comment[start+1 : end-1])
+			return date, aux
+		}
+	}
+	return time.Time{}, time.Time{}
+}
+
+// FormatDateTag formats posting dates as a comment tag, e.g. "[=2025/01/17]".
+// Returns an empty string when both dates are zero.
+func FormatDateTag(date, aux time.Time) string {
+	if date.IsZero() && aux.IsZero() {
+		return ""
+	}
+	tag := "["
+	if !date.IsZero() {
+		tag += FormatDate(date)
+	}
+	if !aux.IsZero() {
+		tag += "=" + FormatDate(aux)
+	}
+	return tag + "]"
+}
+
+// withDateTag returns comment with its date tag replaced by one for date and
+// aux, added at the end when it has none, or removed when both are zero.
+func withDateTag(comment string, date, aux time.Time) string {
+	tag := FormatDateTag(date, aux)
+	start, end := findDateTag(comment)
+	if start < 0 {
+		return strings.TrimSpace(comment + " " + tag)
+	}
+	return strings.Join(strings.Fields(comment[:start]+tag+comment[end:]), " ")
+}
+
+// findDateTag returns the byte range of the first date tag in comment, or -1, -1.
+func findDateTag(comment string) (int, int) {
+	offset := 0
+	for {
+		start := strings.IndexByte(comment[offset:], '[')
+		if start < 0 {
+			return -1, -1
+		}
+		start += offset
+		end := strings.IndexByte(comment[start:], ']')
+		if end < 0 {
+			return -1, -1
+		}
+		end += start + 1
+		if _, _, ok := parseDateTag(comment[start+1 : end-1]); ok {
+			return start, end
+		}
+		offset = start + 1
+	}
+}
+
+// parseDateTag parses the inside of a date tag: "DATE", "=AUX" or "DATE=AUX".
+func parseDateTag(s string) (date, aux time.Time, ok bool) {
+	primary, auxiliary, hasAux := strings.Cut(s, "=")
+	if primary == "" && (!hasAux || auxiliary == "") {
+		return time.Time{}, time.Time{}, false
+	}
+	var err error
+	if primary != "" {
+		if date, err = ParseDate(primary); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	if hasAux {
+		if aux, err = ParseDate(auxiliary); err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+	}
+	return date, aux, true
+}
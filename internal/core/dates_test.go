@@ -0,0 +1,82 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseDateTag(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, time.January, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		comment string
+		date    time.Time
+		aux     time.Time
+	}{
+		{comment: "[=2025/01/17]", aux: day(17)},
+		{comment: "lunch [2025-01-16] with team", date: day(16)},
+		{comment: "[Savings] [2025/01/16=2025/01/17]", date: day(16), aux: day(17)},
+		{comment: "[=not a date]"},
+		{comment: "no tag"},
+	}
+	for _, tc := range tests {
+		date, aux := ParseDateTag(tc.comment)
+		if !date.Equal(tc.date) || !aux.Equal(tc.aux) {
+			t.Errorf("%q: expected %v=%v, got %v=%v", tc.comment, tc.date, tc.aux, date, aux)
+		}
+	}
+}
+
+func TestParseDateInYear(t *testing.T) {
+	tests := []struct {
+		input string
+		date  string
+	}{
+		{input: "01/15", date: "2019-01-15"},
+		{input: "1-5", date: "2019-01-05"},
+		{input: "12.31", date: "2019-12-31"},
+		{input: "2025/01/15", date: "2025-01-15"},
+	}
+	for _, tc := range tests {
+		date, err := ParseDateInYear(tc.input, 2019)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if got := date.Format("2006-01-02"); got != tc.date {
+			t.Errorf("%q: expected %s, got %s", tc.input, tc.date, got)
+		}
+	}
+
+	for _, input := range []string{"02/29", "13/01", "15", "Jan 15"} {
+		if _, err := ParseDateInYear(input, 2019); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestTransactionStringWritesAuxiliaryDates(t *testing.T) {
+	tx := Transaction{
+		Date:    time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC),
+		AuxDate: time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC),
+		Payee:   "Card Purchase",
+		Status:  Cleared,
+		Postings: []Posting{
+			{Account: "Expenses:Food", Amount: NewAmount(decimal.RequireFromString("12.00"), ""), Comment: "lunch", AuxDate: time.Date(2025, time.January, 18, 0, 0, 0, 0, time.UTC)},
+			{Account: "Liabilities:Card", Amount: NewAmount(decimal.RequireFromString("-12.00"), ""), Comment: "[=2025/01/16]", AuxDate: time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	lines := strings.Split(strings.TrimSuffix(tx.String(), "\n"), "\n")
+	if lines[0] != "2025/01/15=2025/01/17 * Card Purchase" {
+		t.Fatalf("unexpected header: %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "; lunch [=2025/01/18]") {
+		t.Fatalf("expected the posting date in the comment, got %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "; [=2025/01/16]") {
+		t.Fatalf("expected the written date tag to be kept once, got %q", lines[2])
+	}
+}
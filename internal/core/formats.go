@@ -0,0 +1,149 @@
+package core
+
+import "strings"
+
+// NumberStyle is how the digits of a quantity are written: its decimal mark
+// and the separator, if any, grouping the whole part in thousands.
+type NumberStyle struct {
+	DecimalComma bool // decimal mark is a comma rather than a point
+	Group        byte // ',' or '.' between groups of three digits, zero when not grouped
+}
+
+// mark returns the decimal mark.
+func (s NumberStyle) mark() byte {
+	if s.DecimalComma {
+		return ','
+	}
+	return '.'
+}
+
+// read converts digits written in the style, such as "1.234,56", to the
+// plain form "1234.56". Group separators must have three digits after them
+// and may only appear before the decimal mark.
+func (s NumberStyle) read(number string) (string, bool) {
+	var b strings.Builder
+	decimal := false
+	for i := 0; i < len(number); i++ {
+		c := number[i]
+		switch {
+		case isDigit(c):
+			b.WriteByte(c)
+		case c == s.mark() && !decimal:
+			decimal = true
+			b.WriteByte('.')
+		case c == s.Group && !decimal && isGroupSeparator(number, i, c):
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// format writes a plain number such as "-1234.56" in the style.
+func (s NumberStyle) format(number string) string {
+	sign, unsigned := takeSign(number)
+	whole, fraction, hasFraction := strings.Cut(unsigned, ".")
+	if s.Group != 0 && len(whole) > 3 {
+		var b strings.Builder
+		for i := range len(whole) {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteByte(s.Group)
+			}
+			b.WriteByte(whole[i])
+		}
+		whole = b.String()
+	}
+	if !hasFraction {
+		return sign + whole
+	}
+	return sign + whole + string(s.mark()) + fraction
+}
+
+// inferStyle works out the style of digits written without a declared
+// format. With both points and commas, the last is the decimal mark. Several
+// of one kind group thousands, and a lone one that can't group thousands, as
+// in "12,50" or "1.5", is the decimal mark. A lone one with three digits
+// after it, as in "1,234" or "1.234", is read as a decimal comma, or as a
+// decimal point when decimalComma is false.
+func inferStyle(number string, decimalComma bool) NumberStyle {
+	point := strings.LastIndexByte(number, '.')
+	comma := strings.LastIndexByte(number, ',')
+	switch {
+	case point >= 0 && comma >= 0:
+		if comma > point {
+			return NumberStyle{DecimalComma: true, Group: '.'}
+		}
+		return NumberStyle{Group: ','}
+	case comma >= 0:
+		if strings.Count(number, ",") == 1 && (decimalComma || !isGroupSeparator(number, comma, ',')) {
+			return NumberStyle{DecimalComma: true}
+		}
+		return NumberStyle{Group: ','}
+	case point >= 0:
+		if strings.Count(number, ".") > 1 || decimalComma && isGroupSeparator(number, point, '.') {
+			return NumberStyle{DecimalComma: true, Group: '.'}
+		}
+	}
+	return NumberStyle{}
+}
+
+// CommodityFormats holds the sample amount of each commodity declared with a
+// format, which sets how its amounts are read and written, and how to read
+// the digits of other amounts.
+type CommodityFormats struct {
+	samples      map[string]Amount
+	DecimalComma bool // read "1,234" and "1.234" of undeclared commodities with a decimal comma
+}
+
+// NewCommodityFormats collects the formats of declared commodities, leaving
+// out those declared without one, for reading amounts with decimalComma.
+func NewCommodityFormats(commodities []Commodity, decimalComma bool) CommodityFormats {
+	formats := CommodityFormats{samples: map[string]Amount{}, DecimalComma: decimalComma}
+	for _, commodity := range commodities {
+		formats.Declare(commodity)
+	}
+	return formats
+}
+
+// Declare records the format of a declared commodity, if it has a valid one.
+// An ambiguous sample such as "1.000 €" is read according to DecimalComma.
+func (f *CommodityFormats) Declare(commodity Commodity) {
+	if commodity.Format == "" {
+		return
+	}
+	if sample, err := parseAmount(commodity.Format, CommodityFormats{DecimalComma: f.DecimalComma}); err == nil {
+		if f.samples == nil {
+			f.samples = map[string]Amount{}
+		}
+		f.samples[commodity.Symbol] = *sample
+	}
+}
+
+// Sample returns the sample amount of a commodity declared with a format.
+func (f CommodityFormats) Sample(commodity string) (Amount, bool) {
+	sample, ok := f.samples[commodity]
+	return sample, ok && commodity != ""
+}
+
+// ParseAmount is like the ParseAmount function, but reads the digits of
+// declared commodities in their declared style, so "1,234 €" is 1.234 when €
+// is declared with a decimal comma, and infers the style of the rest with
+// DecimalComma.
+func (f CommodityFormats) ParseAmount(s string) (*Amount, error) {
+	return parseAmount(s, f)
+}
+
+// Apply returns the amount written with its commodity's declared placement,
+// spacing and digit style, and at least its declared decimal places, or
+// unchanged when it has no declared format.
+func (f CommodityFormats) Apply(amount Amount) *Amount {
+	if format, ok := f.Sample(amount.Commodity); ok {
+		amount.Suffix = format.Suffix
+		amount.Spaced = format.Spaced
+		amount.Style = format.Style
+		if amount.places() < format.places() {
+			amount.Quantity = amount.Quantity.Round(format.places())
+		}
+	}
+	return &amount
+}
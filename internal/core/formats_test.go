@@ -0,0 +1,114 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestInferStyle(t *testing.T) {
+	tests := []struct {
+		input        string
+		decimalComma bool
+		quantity     string
+		style        NumberStyle
+	}{
+		{input: "$1,000.00", quantity: "1000", style: NumberStyle{Group: ','}},
+		{input: "1.000,00 €", quantity: "1000", style: NumberStyle{DecimalComma: true, Group: '.'}},
+		{input: "1000,00 EUR", quantity: "1000", style: NumberStyle{DecimalComma: true}},
+		{input: "12,50 €", quantity: "12.5", style: NumberStyle{DecimalComma: true}},
+		{input: "1,5 EUR", quantity: "1.5", style: NumberStyle{DecimalComma: true}},
+		{input: "$1,000", quantity: "1000", style: NumberStyle{Group: ','}},
+		{input: "1,234,567", quantity: "1234567", style: NumberStyle{Group: ','}},
+		{input: "1000.00 AAPL", quantity: "1000", style: NumberStyle{}},
+		{input: "1.234", quantity: "1.234", style: NumberStyle{}},
+		{input: "1,234", decimalComma: true, quantity: "1.234", style: NumberStyle{DecimalComma: true}},
+		{input: "1.234", decimalComma: true, quantity: "1234", style: NumberStyle{DecimalComma: true, Group: '.'}},
+		{input: "1.5", decimalComma: true, quantity: "1.5", style: NumberStyle{}},
+		{input: "$1,234.56", decimalComma: true, quantity: "1234.56", style: NumberStyle{Group: ','}},
+	}
+	for _, tc := range tests {
+		amount, err := CommodityFormats{DecimalComma: tc.decimalComma}.ParseAmount(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if !amount.Quantity.Equal(decimal.RequireFromString(tc.quantity)) {
+			t.Errorf("%q: expected %s, got %s", tc.input, tc.quantity, amount.Quantity)
+		}
+		if amount.Style != tc.style {
+			t.Errorf("%q: expected style %+v, got %+v", tc.input, tc.style, amount.Style)
+		}
+	}
+}
+
+func TestCommodityFormatsReadDeclaredStyle(t *testing.T) {
+	formats := NewCommodityFormats([]Commodity{
+		{Symbol: "€", Format: "1.000,00 €"},
+		{Symbol: "EUR", Format: "1000,00 EUR"},
+		{Symbol: "AAPL"},
+	}, false)
+	if _, ok := formats.Sample("AAPL"); ok {
+		t.Errorf("expected commodity without a format to be left out")
+	}
+
+	tests := []struct {
+		input    string
+		quantity string
+		output   string
+	}{
+		{input: "1,234 €", quantity: "1.234", output: "1,234 €"},
+		{input: "1.234 €", quantity: "1234", output: "1.234 €"},
+		{input: "-12,50 EUR", quantity: "-12.5", output: "-12,50 EUR"},
+		{input: "$1,234.56", quantity: "1234.56", output: "$1,234.56"},
+	}
+	for _, tc := range tests {
+		amount, err := formats.ParseAmount(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if !amount.Quantity.Equal(decimal.RequireFromString(tc.quantity)) {
+			t.Errorf("%q: expected %s, got %s", tc.input, tc.quantity, amount.Quantity)
+		}
+		if amount.String() != tc.output {
+			t.Errorf("%q: expected to format as %q, got %q", tc.input, tc.output, amount.String())
+		}
+	}
+
+	if _, err := formats.ParseAmount("1,234.56 €"); err == nil {
+		t.Errorf("expected grouping commas to be invalid for a decimal comma commodity")
+	}
+}
+
+func TestCommodityFormatsApplyPlacement(t *testing.T) {
+	formats := NewCommodityFormats([]Commodity{{Symbol: "€", Format: "1.000,00 €"}}, false)
+
+	amount := formats.Apply(*NewAmount(decimal.RequireFromString("-1234.5"), "€"))
+	if got := amount.String(); got != "-1.234,50 €" {
+		t.Errorf("expected declared placement and style, got %q", got)
+	}
+	if got := formats.Apply(*NewAmount(decimal.RequireFromString("10"), "AAPL")).String(); got != "10 AAPL" {
+		t.Errorf("expected undeclared commodity unchanged, got %q", got)
+	}
+}
+
+func TestTransactionStringAlignsDecimalCommas(t *testing.T) {
+	formats := NewCommodityFormats([]Commodity{{Symbol: "€", Format: "1.000,00 €"}}, false)
+	amount := formats.Apply(*NewAmount(decimal.RequireFromString("1234.56"), "€"))
+	tx := Transaction{
+		Date:  time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC),
+		Payee: "Bäckerei",
+		Postings: []Posting{
+			{Account: "Expenses:Food", Amount: amount},
+			{Account: "Assets:Bank", Amount: amount.Neg()},
+		},
+	}
+	want := "2025/01/15   Bäckerei\n" +
+		"\tExpenses:Food\t\t\t\t\t\t\t 1.234,56 €\n" +
+		"\tAssets:Bank\t\t\t\t\t\t\t\t-1.234,56 €\n"
+	if got := tx.String(); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
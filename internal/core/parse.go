@@ -1,13 +1,31 @@
 package core
 
+import "fmt"
+
 // ParseIssue captures a non-fatal problem encountered while reading a ledger file.
 type ParseIssue struct {
+	File    string // file the issue occurred in, empty when reading a single stream
 	Line    int
 	Message string
 }
 
-// ParseResult contains the parsed transactions along with any issues that occurred.
+// Location describes where the issue occurred, e.g. "2024.ledger line 12".
+func (i ParseIssue) Location() string {
+	if i.File == "" {
+		return fmt.Sprintf("line %d", i.Line)
+	}
+	return fmt.Sprintf("%s line %d", i.File, i.Line)
+}
+
+// ParseResult contains the parsed transactions and price history along with any issues that occurred.
 type ParseResult struct {
 	Transactions []Transaction
+	Prices       []MarketPrice
+	Automated    []AutomatedTransaction
+	Periodic     []PeriodicTransaction
+	Accounts     []string    // declared with account directives
+	Payees       []string    // declared with payee directives
+	Commodities  []Commodity // declared with commodity directives
 	Issues       []ParseIssue
+	DecimalComma bool // "1,234" and "1.234" were read with a decimal comma
 }
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PeriodicTransaction is a budget entry that repeats on a schedule, written
+// "~ Monthly" followed by postings. A description may follow the period
+// after two spaces, as in "~ Monthly  Rent".
+type PeriodicTransaction struct {
+	Period      string    // as written, e.g. "Monthly" or "every 2 weeks from 2025/01/03"
+	Interval    Interval  // time between occurrences
+	Start       time.Time // first occurrence from a "from DATE" clause, zero when none
+	Description string
+	Comment     string
+	Postings    []Posting
+}
+
+// Interval is the time between occurrences of a periodic transaction.
+type Interval struct {
+	Days   int
+	Months int
+}
+
+// periodWords are the single-word periods and the intervals they stand for.
+var periodWords = map[string]Interval{
+	"daily":       {Days: 1},
+	"weekly":      {Days: 7},
+	"biweekly":    {Days: 14},
+	"fortnightly": {Days: 14},
+	"monthly":     {Months: 1},
+	"bimonthly":   {Months: 2},
+	"quarterly":   {Months: 3},
+	"yearly":      {Months: 12},
+	"annually":    {Months: 12},
+}
+
+// periodUnits are the units an "every N ..." period counts in.
+var periodUnits = map[string]Interval{
+	"day":     {Days: 1},
+	"week":    {Days: 7},
+	"month":   {Months: 1},
+	"quarter": {Months: 3},
+	"year":    {Months: 12},
+}
+
+// ParsePeriod parses a period expression such as "Monthly", "every 2 weeks"
+// or "Yearly from 2025/01/01".
+func ParsePeriod(s string) (Interval, time.Time, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	var start time.Time
+	for i, field := range fields {
+		if field != "from" {
+			continue
+		}
+		if i != len(fields)-2 {
+			return Interval{}, time.Time{}, fmt.Errorf("unrecognized period %q", s)
+		}
+		date, err := ParseDate(fields[i+1])
+		if err != nil {
+			return Interval{}, time.Time{}, fmt.Errorf("unrecognized period %q: %w", s, err)
+		}
+		start = date
+		fields = fields[:i]
+		break
+	}
+
+	switch {
+	case len(fields) == 1:
+		if interval, ok := periodWords[fields[0]]; ok {
+			return interval, start, nil
+		}
+	case len(fields) == 2 && fields[0] == "every":
+		if unit, ok := periodUnits[fields[1]]; ok {
+			return unit, start, nil
+		}
+	case len(fields) == 3 && fields[0] == "every":
+		count, err := strconv.Atoi(fields[1])
+		unit, ok := periodUnits[strings.TrimSuffix(fields[2], "s")]
+		if err == nil && count > 0 && ok {
+			return Interval{Days: unit.Days * count, Months: unit.Months * count}, start, nil
+		}
+	}
+	return Interval{}, time.Time{}, fmt.Errorf("unrecognized period %q", s)
+}
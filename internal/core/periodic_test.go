@@ -0,0 +1,37 @@
+package core
+
+import "testing"
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		input    string
+		interval Interval
+		start    string
+	}{
+		{input: "Monthly", interval: Interval{Months: 1}},
+		{input: "weekly", interval: Interval{Days: 7}},
+		{input: "Every 2 weeks", interval: Interval{Days: 14}},
+		{input: "every quarter", interval: Interval{Months: 3}},
+		{input: "Yearly from 2025/03/01", interval: Interval{Months: 12}, start: "2025-03-01"},
+		{input: "every 6 months from 2025-01-15", interval: Interval{Months: 6}, start: "2025-01-15"},
+	}
+	for _, tc := range tests {
+		interval, start, err := ParsePeriod(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if interval != tc.interval {
+			t.Errorf("%q: expected %+v, got %+v", tc.input, tc.interval, interval)
+		}
+		if (tc.start == "" && !start.IsZero()) || (tc.start != "" && start.Format("2006-01-02") != tc.start) {
+			t.Errorf("%q: expected start %q, got %v", tc.input, tc.start, start)
+		}
+	}
+
+	for _, input := range []string{"", "sometimes", "every 0 days", "monthly from", "monthly from soon"} {
+		if _, _, err := ParsePeriod(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Price is the cost of a posting's amount in another commodity. As a price it
+// is written "@ $150.00" per unit or "@@ $1500.00" for the whole amount; as a
+// lot cost it is written "{$150.00}" or "{{$1500.00}}".
+type Price struct {
+	Amount Amount
+	Total  bool // the price is for the whole amount rather than per unit
+}
+
+// Cost returns what quantity units cost at this price. A total price takes
+// the sign of the quantity, as ledger does.
+func (p Price) Cost(quantity decimal.Decimal) Amount {
+	if p.Total {
+		cost := p.Amount.Quantity.Abs()
+		if quantity.Sign() < 0 {
+			cost = cost.Neg()
+		}
+		return *p.Amount.WithQuantity(cost)
+	}
+	return *p.Amount.WithQuantity(p.Amount.Quantity.Mul(quantity))
+}
+
+// Equal reports whether two prices have the same amount and kind.
+func (p Price) Equal(other Price) bool {
+	return p.Total == other.Total && p.Amount.Equal(other.Amount)
+}
+
+// String formats the price as a price annotation, e.g. "@ $150.00".
+func (p Price) String() string {
+	if p.Total {
+		return "@@ " + p.Amount.String()
+	}
+	return "@ " + p.Amount.String()
+}
+
+// LotString formats the price as a lot cost annotation, e.g. "{$148.00}".
+func (p Price) LotString() string {
+	if p.Total {
+		return "{{" + p.Amount.String() + "}}"
+	}
+	return "{" + p.Amount.String() + "}"
+}
+
+// ParseAnnotatedAmount parses an amount followed by an optional lot cost and
+// price, such as "10 AAPL {$148.00} @ $150.00" or "-5 AAPL @@ $800".
+func ParseAnnotatedAmount(s string) (amount *Amount, lot *Price, price *Price, err error) {
+	return CommodityFormats{}.ParseAnnotatedAmount(s)
+}
+
+// ParseAnnotatedAmount is like the ParseAnnotatedAmount function, but reads
+// declared commodities in their declared style.
+func (f CommodityFormats) ParseAnnotatedAmount(s string) (amount *Amount, lot *Price, price *Price, err error) {
+	s = strings.TrimSpace(s)
+	end := len(s)
+	if idx := indexUnquoted(s, '{'); idx >= 0 {
+		end = idx
+	}
+	if idx := indexUnquoted(s, '@'); idx >= 0 && idx < end {
+		end = idx
+	}
+	amount, err = f.ParseAmount(s[:end])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	lot, price, err = f.parseAnnotations(s[end:])
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return amount, lot, price, nil
+}
+
+// ParseAnnotations parses the lot cost and price written after an amount,
+// such as "{$148.00} @ $150.00", "{{$1480}}" or "@@ $1500". Either may be
+// missing; an empty string has neither.
+func ParseAnnotations(s string) (lot *Price, price *Price, err error) {
+	return CommodityFormats{}.parseAnnotations(s)
+}
+
+// parseAnnotations parses a lot cost and price, reading declared commodities
+// in their declared style.
+func (f CommodityFormats) parseAnnotations(s string) (lot *Price, price *Price, err error) {
+	s = strings.TrimSpace(s)
+	if idx := indexUnquoted(s, '@'); idx >= 0 {
+		text := s[idx+1:]
+		total := strings.HasPrefix(text, "@")
+		if total {
+			text = text[1:]
+		}
+		amount, err := f.ParseAmount(text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid price %q", strings.TrimSpace(s[idx:]))
+		}
+		price = &Price{Amount: *amount, Total: total}
+		s = strings.TrimSpace(s[:idx])
+	}
+	if s == "" {
+		return nil, price, nil
+	}
+	text := s
+	total := strings.HasPrefix(text, "{{") && strings.HasSuffix(text, "}}")
+	switch {
+	case total:
+		text = text[2 : len(text)-2]
+	case strings.HasPrefix(text, "{") && strings.HasSuffix(text, "}"):
+		text = text[1 : len(text)-1]
+	default:
+		return nil, nil, fmt.Errorf("invalid lot cost %q", s)
+	}
+	amount, err := f.ParseAmount(text)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid lot cost %q", s)
+	}
+	return &Price{Amount: *amount, Total: total}, price, nil
+}
+
+// indexUnquoted returns the index of the first c in s outside a quoted
+// commodity, or -1.
+func indexUnquoted(s string, c byte) int {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			quoted = !quoted
+		case c:
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// MarketPrice is an entry in the price history, as recorded by a directive
+// such as "P 2025/01/15 AAPL $182.31": one unit of Commodity was worth Price
+// on Date.
+type MarketPrice struct {
+	Date      time.Time
+	Commodity string
+	Price     Amount
+}
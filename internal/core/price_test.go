@@ -0,0 +1,74 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseAnnotatedAmount(t *testing.T) {
+	tests := []struct {
+		input  string
+		amount string
+		lot    string
+		price  string
+		weight string
+	}{
+		{input: "10 AAPL", amount: "10 AAPL", weight: "10 AAPL"},
+		{input: "10 AAPL @ $150.00", amount: "10 AAPL", price: "@ $150.00", weight: "$1500.00"},
+		{input: "-10 AAPL @@ $1500", amount: "-10 AAPL", price: "@@ $1500", weight: "$-1500"},
+		{input: "10 AAPL {$148.00}", amount: "10 AAPL", lot: "{$148.00}", weight: "$1480.00"},
+		{input: "-4 AAPL {{$592.00}} @ $155.00", amount: "-4 AAPL", lot: "{{$592.00}}", price: "@ $155.00", weight: "$-620.00"},
+		{input: "€100@1.10 USD", amount: "€100", price: "@ 1.10 USD", weight: "110.00 USD"},
+	}
+	for _, tc := range tests {
+		amount, lot, price, err := ParseAnnotatedAmount(tc.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tc.input, err)
+			continue
+		}
+		if amount.String() != tc.amount {
+			t.Errorf("%q: expected amount %q, got %q", tc.input, tc.amount, amount.String())
+		}
+		if (lot == nil) != (tc.lot == "") || (lot != nil && lot.LotString() != tc.lot) {
+			t.Errorf("%q: expected lot %q, got %v", tc.input, tc.lot, lot)
+		}
+		if (price == nil) != (tc.price == "") || (price != nil && price.String() != tc.price) {
+			t.Errorf("%q: expected price %q, got %v", tc.input, tc.price, price)
+		}
+		posting := Posting{Amount: amount, Lot: lot, Price: price}
+		if got := posting.Weight().String(); got != tc.weight {
+			t.Errorf("%q: expected weight %q, got %q", tc.input, tc.weight, got)
+		}
+	}
+
+	for _, input := range []string{"10 AAPL @", "10 AAPL @ abc", "10 AAPL {$148", "10 AAPL {} @ $1"} {
+		if _, _, _, err := ParseAnnotatedAmount(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestTransactionStringWritesCosts(t *testing.T) {
+	tx := Transaction{
+		Date:   time.Date(2025, time.May, 2, 0, 0, 0, 0, time.UTC),
+		Payee:  "Broker",
+		Status: Cleared,
+		Postings: []Posting{
+			{
+				Account: "Assets:Brokerage",
+				Amount:  NewAmount(decimal.RequireFromString("10"), "AAPL"),
+				Lot:     &Price{Amount: *NewAmount(decimal.RequireFromString("148.00"), "$")},
+				Price:   &Price{Amount: *NewAmount(decimal.RequireFromString("150.00"), "$")},
+			},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-1500.00"), "")},
+		},
+	}
+
+	lines := strings.Split(tx.String(), "\n")
+	if !strings.HasSuffix(lines[1], "10 AAPL {$148.00} @ $150.00") {
+		t.Fatalf("expected lot cost and price after the amount, got %q", lines[1])
+	}
+}
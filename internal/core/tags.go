@@ -0,0 +1,95 @@
+package core
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ParseTags extracts the tags and metadata written in comment lines. Tags are
+// words wrapped in colons, such as ":reimbursable:" or ":travel:work:", and a
+// comment of the form "Key: value" is a metadata field.
+func ParseTags(comments ...string) ([]string, map[string]string) {
+	var tags []string
+	var metadata map[string]string
+	for _, comment := range comments {
+		comment = strings.TrimSpace(comment)
+		if key, value, ok := parseMetadata(comment); ok {
+			if metadata == nil {
+				metadata = make(map[string]string)
+			}
+			metadata[key] = value
+			continue
+		}
+		for _, word := range strings.Fields(comment) {
+			for _, tag := range parseTagWord(word) {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+	}
+	return tags, metadata
+}
+
+// FormatTags formats tags as a comment, e.g. ":travel:reimbursable:".
+func FormatTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return ":" + strings.Join(tags, ":") + ":"
+}
+
+// parseMetadata recognizes a "Key: value" comment. The key is a single word.
+func parseMetadata(comment string) (string, string, bool) {
+	key, value, ok := strings.Cut(comment, ":")
+	if !ok || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	if value != "" && value[0] != ' ' && value[0] != '\t' {
+		return "", "", false
+	}
+	return key, strings.TrimSpace(value), true
+}
+
+// parseTagWord returns the tags in a word such as ":travel:work:".
+func parseTagWord(word string) []string {
+	if len(word) < 3 || !strings.HasPrefix(word, ":") || !strings.HasSuffix(word, ":") {
+		return nil
+	}
+	var tags []string
+	for _, tag := range strings.Split(word[1:len(word)-1], ":") {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// commentLines returns the comment lines written below a header or posting:
+// its notes, then any tags and metadata that aren't already in its comments.
+func commentLines(comment string, notes, tags []string, metadata map[string]string) []string {
+	lines := append([]string(nil), notes...)
+	written, writtenMetadata := ParseTags(append([]string{comment}, notes...)...)
+
+	var missing []string
+	for _, tag := range tags {
+		if !slices.Contains(written, tag) && !slices.Contains(missing, tag) {
+			missing = append(missing, tag)
+		}
+	}
+	if len(missing) > 0 {
+		lines = append(lines, FormatTags(missing))
+	}
+
+	keys := slices.Collect(maps.Keys(metadata))
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := writtenMetadata[key]; ok && value == metadata[key] {
+			continue
+		}
+		lines = append(lines, key+": "+metadata[key])
+	}
+	return lines
+}
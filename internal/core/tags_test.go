@@ -0,0 +1,50 @@
+package core
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, metadata := ParseTags("Invoice 123 :work:", ":travel:work:", "Receipt: scanned", "see http://example.com")
+	if expected := []string{"work", "travel"}; !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected tags %v, got %v", expected, tags)
+	}
+	if expected := map[string]string{"Receipt": "scanned"}; !reflect.DeepEqual(metadata, expected) {
+		t.Fatalf("expected metadata %v, got %v", expected, metadata)
+	}
+}
+
+func TestTransactionStringWritesNotesTagsAndMetadata(t *testing.T) {
+	tx := Transaction{
+		Date:     time.Date(2025, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Payee:    "Airline",
+		Comment:  "Conference :travel:",
+		Status:   Cleared,
+		Notes:    []string{"Booked online"},
+		Tags:     []string{"travel", "work"},
+		Metadata: map[string]string{"Trip": "Berlin"},
+		Postings: []Posting{
+			{Account: "Expenses:Travel", Amount: NewAmount(decimal.RequireFromString("300.00"), ""), Notes: []string{"Receipt: emailed"}, Metadata: map[string]string{"Receipt": "emailed"}},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-300.00"), "")},
+		},
+	}
+
+	lines := strings.Split(strings.TrimSuffix(tx.String(), "\n"), "\n")
+	expected := []string{
+		"\t; Booked online",
+		"\t; :work:",
+		"\t; Trip: Berlin",
+	}
+	if len(lines) != 7 || !reflect.DeepEqual(lines[1:4], expected) {
+		t.Fatalf("expected transaction comments %q, got %q", expected, lines)
+	}
+	// Metadata already written in a note isn't repeated
+	if lines[5] != "\t\t; Receipt: emailed" {
+		t.Fatalf("expected posting note below the posting, got %q", lines[5])
+	}
+}
@@ -1,25 +1,292 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// PostingKind distinguishes real postings from virtual ones.
+type PostingKind int
+
+const (
+	RealPosting            PostingKind = iota
+	VirtualPosting                     // written "(Account)"; doesn't have to balance
+	BalancedVirtualPosting             // written "[Account]"; balances like a real posting
+)
+
+// ParsePostingAccount reads a posting's account, which is wrapped in
+// parentheses or brackets for virtual postings.
+func ParsePostingAccount(s string) (string, PostingKind) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 {
+		switch {
+		case s[0] == '(' && s[len(s)-1] == ')':
+			return strings.TrimSpace(s[1 : len(s)-1]), VirtualPosting
+		case s[0] == '[' && s[len(s)-1] == ']':
+			return strings.TrimSpace(s[1 : len(s)-1]), BalancedVirtualPosting
+		}
+	}
+	return s, RealPosting
+}
+
+// FormatAccount writes an account for a posting of this kind, e.g. "[Budget:Groceries]".
+func (k PostingKind) FormatAccount(account string) string {
+	switch k {
+	case VirtualPosting:
+		return "(" + account + ")"
+	case BalancedVirtualPosting:
+		return "[" + account + "]"
+	}
+	return account
+}
+
 // Posting represents a single entry in a transaction.
 type Posting struct {
-	Account string // e.g., "Expenses:Food:Groceries"
-	Amount  string // e.g., "12.34" (stored as string for precision)
-	Comment string // optional inline comment written after the amount
+	Account string      // e.g., "Expenses:Food:Groceries"
+	Kind    PostingKind // real, or virtual when written "(Account)" or "[Account]"
+	Amount  *Amount     // e.g., $12.34; nil when the amount is elided
+	Lot     *Price      // optional lot cost, e.g. {$148.00}
+	Price   *Price      // optional price, e.g. @ $150.00
+	Comment string      // optional inline comment written after the amount
+
+	// Assertion is the account's balance after the posting, written "= $1,234.56".
+	// With an elided amount it's a balance assignment: the amount is whatever
+	// brings the account to that balance.
+	Assertion *Amount
+
+	Date    time.Time // date override from a "[DATE]" comment, zero when none
+	AuxDate time.Time // auxiliary date from a "[=DATE]" comment, zero when none
+
+	Notes    []string          // comment lines written below the posting
+	Tags     []string          // tags from the comments, e.g. "reimbursable" for ":reimbursable:"
+	Metadata map[string]string // "Key: value" fields from the comments
+}
+
+// Balances reports whether the posting takes part in the transaction's balance.
+// Only unbalanced virtual postings are left out.
+func (p Posting) Balances() bool {
+	return p.Kind != VirtualPosting
+}
+
+// Weight returns what the posting contributes to the transaction's balance:
+// its price or lot cost when it has one, otherwise its amount.
+// Returns nil when the amount is elided.
+func (p Posting) Weight() *Amount {
+	switch {
+	case p.Amount == nil:
+		return nil
+	case p.Price != nil:
+		cost := p.Price.Cost(p.Amount.Quantity)
+		return &cost
+	case p.Lot != nil:
+		cost := p.Lot.Cost(p.Amount.Quantity)
+		return &cost
+	}
+	return p.Amount
+}
+
+// IsAssignment reports whether the posting's amount is set by its balance assertion.
+func (p Posting) IsAssignment() bool {
+	return p.Amount == nil && p.Assertion != nil
+}
+
+// annotations formats the lot cost and price written after the amount.
+func (p Posting) annotations() string {
+	var annotations string
+	if p.Lot != nil {
+		annotations += " " + p.Lot.LotString()
+	}
+	if p.Price != nil {
+		annotations += " " + p.Price.String()
+	}
+	return annotations
+}
+
+// comment returns the inline comment with the posting's dates written in it,
+// unless a note already carries them.
+func (p Posting) comment() string {
+	if p.Date.IsZero() && p.AuxDate.IsZero() {
+		return p.Comment
+	}
+	if date, aux := ParseDateTag(p.Comment); date.Equal(p.Date) && aux.Equal(p.AuxDate) {
+		return p.Comment
+	}
+	if date, aux := ParseDateTag(p.Notes...); date.Equal(p.Date) && aux.Equal(p.AuxDate) {
+		return p.Comment
+	}
+	return withDateTag(p.Comment, p.Date, p.AuxDate)
+}
+
+// postingJSON is the serialized form of a Posting, with the amount, lot cost
+// and price in ledger style.
+type postingJSON struct {
+	Account   string
+	Amount    string
+	Assertion string `json:",omitempty"`
+	Comment   string
+	Date      time.Time         `json:",omitzero"`
+	AuxDate   time.Time         `json:",omitzero"`
+	Notes     []string          `json:",omitempty"`
+	Tags      []string          `json:",omitempty"`
+	Metadata  map[string]string `json:",omitempty"`
+}
+
+// MarshalJSON writes the account and amount as ledger text so sessions stay readable.
+func (p Posting) MarshalJSON() ([]byte, error) {
+	encoded := postingJSON{
+		Account:  p.Kind.FormatAccount(p.Account),
+		Comment:  p.Comment,
+		Date:     p.Date,
+		AuxDate:  p.AuxDate,
+		Notes:    p.Notes,
+		Tags:     p.Tags,
+		Metadata: p.Metadata,
+	}
+	if p.Amount != nil {
+		encoded.Amount = p.Amount.String() + p.annotations()
+	}
+	if p.Assertion != nil {
+		encoded.Assertion = p.Assertion.String()
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON reads a posting written by MarshalJSON.
+func (p *Posting) UnmarshalJSON(data []byte) error {
+	var decoded postingJSON
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	account, kind := ParsePostingAccount(decoded.Account)
+	*p = Posting{
+		Kind:     kind,
+		Account:  account,
+		Comment:  decoded.Comment,
+		Date:     decoded.Date,
+		AuxDate:  decoded.AuxDate,
+		Notes:    decoded.Notes,
+		Tags:     decoded.Tags,
+		Metadata: decoded.Metadata,
+	}
+	if strings.TrimSpace(decoded.Amount) != "" {
+		amount, lot, price, err := ParseAnnotatedAmount(decoded.Amount)
+		if err != nil {
+			return err
+		}
+		p.Amount, p.Lot, p.Price = amount, lot, price
+	}
+	if strings.TrimSpace(decoded.Assertion) != "" {
+		assertion, err := ParseAmount(decoded.Assertion)
+		if err != nil {
+			return err
+		}
+		p.Assertion = assertion
+	}
+	return nil
+}
+
+// Equal reports whether two postings have the same account, amount, costs, assertion, dates and comments.
+func (p Posting) Equal(other Posting) bool {
+	if p.Kind != other.Kind || p.Account != other.Account || p.Comment != other.Comment {
+		return false
+	}
+	if !p.Date.Equal(other.Date) || !p.AuxDate.Equal(other.AuxDate) {
+		return false
+	}
+	if !slices.Equal(p.Notes, other.Notes) || !slices.Equal(p.Tags, other.Tags) || !maps.Equal(p.Metadata, other.Metadata) {
+		return false
+	}
+	if (p.Lot == nil) != (other.Lot == nil) || (p.Lot != nil && !p.Lot.Equal(*other.Lot)) {
+		return false
+	}
+	if (p.Price == nil) != (other.Price == nil) || (p.Price != nil && !p.Price.Equal(*other.Price)) {
+		return false
+	}
+	if (p.Assertion == nil) != (other.Assertion == nil) || (p.Assertion != nil && !p.Assertion.Equal(*other.Assertion)) {
+		return false
+	}
+	if p.Amount == nil || other.Amount == nil {
+		return p.Amount == nil && other.Amount == nil
+	}
+	return p.Amount.Equal(*other.Amount)
+}
+
+// Status is the clearing state of a transaction.
+type Status int
+
+const (
+	Uncleared Status = iota
+	Pending          // marked "!"
+	Cleared          // marked "*"
+)
+
+// Marker returns the header marker for the status: "*", "!" or "" when uncleared.
+func (s Status) Marker() string {
+	switch s {
+	case Pending:
+		return "!"
+	case Cleared:
+		return "*"
+	}
+	return ""
+}
+
+// Next returns the status after s, cycling uncleared → pending → cleared.
+func (s Status) Next() Status {
+	return (s + 1) % 3
+}
+
+// String returns the name of the status.
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Cleared:
+		return "cleared"
+	}
+	return "uncleared"
+}
+
+// MarshalText encodes the status by name.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name.
+func (s *Status) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "uncleared", "":
+		*s = Uncleared
+	case "pending":
+		*s = Pending
+	case "cleared":
+		*s = Cleared
+	default:
+		return fmt.Errorf("unknown status %q", text)
+	}
+	return nil
 }
 
 // Transaction represents a complete financial event.
 type Transaction struct {
-	Date     time.Time
-	Payee    string // e.g., "Super Grocery Store"
-	Comment  string // optional comment appended to the payee line
-	Cleared  bool   // true when the transaction is cleared ("*")
-	Postings []Posting
+	Date        time.Time
+	AuxDate     time.Time         `json:",omitzero"` // auxiliary (effective) date written "DATE=AUX", zero when none
+	Payee       string            // e.g., "Super Grocery Store"
+	Comment     string            // optional comment appended to the payee line
+	Status      Status            // uncleared, pending ("!") or cleared ("*")
+	Code        string            // optional code such as a check number, written "(1042)"
+	Notes       []string          `json:",omitempty"` // comment lines written below the payee line
+	Tags        []string          `json:",omitempty"` // tags from the comments, e.g. "reimbursable" for ":reimbursable:"
+	Metadata    map[string]string `json:",omitempty"` // "Key: value" fields from the comments
+	Postings    []Posting
+	Bucket      string `json:",omitempty"` // account from a bucket directive that takes whatever doesn't balance
+	Draft       bool   // true for imported entries that still need categorizing
+	Description string // raw statement description for imported entries
 }
 
 // String formats the transaction in ledger-cli format with tab-based alignment.
@@ -27,35 +294,52 @@ func (t *Transaction) String() string {
 	var builder strings.Builder
 
 	// Date and payee line
-	dateStr := t.Date.Format("2006/01/02")
-	line := ""
-	if t.Cleared {
-		line = fmt.Sprintf("%s * %s", dateStr, t.Payee)
-	} else {
-		line = fmt.Sprintf("%s   %s", dateStr, t.Payee)
+	dateStr := FormatDate(t.Date)
+	if !t.AuxDate.IsZero() {
+		dateStr += "=" + FormatDate(t.AuxDate)
 	}
+	marker := t.Status.Marker()
+	if marker == "" {
+		marker = " "
+	}
+	line := fmt.Sprintf("%s %s ", dateStr, marker)
+	if code := strings.TrimSpace(t.Code); code != "" {
+		line += "(" + code + ") "
+	}
+	line += t.Payee
 
 	// Add comment if present (aligned to tab 12 = column 48)
 	if strings.TrimSpace(t.Comment) != "" {
 		line = addTabsToColumn(line, 44) + fmt.Sprintf("; %s", strings.TrimSpace(t.Comment))
 	}
 	builder.WriteString(line + "\n")
+	for _, note := range commentLines(t.Comment, t.Notes, t.Tags, t.Metadata) {
+		builder.WriteString("\t; " + note + "\n")
+	}
 
 	// Format amounts with decimal alignment
 	formattedAmounts := formatAmounts(t.Postings)
 
 	// Postings with proper indentation
 	for i, posting := range t.Postings {
-		line = "\t" + posting.Account
+		line = "\t" + posting.Kind.FormatAccount(posting.Account)
 
-		if posting.Amount != "" {
-			line = addTabsToColumn(line, 44) + formattedAmounts[i]
+		if posting.Amount != nil {
+			line = addTabsToColumn(line, 44) + formattedAmounts[i] + posting.annotations()
+			if posting.Assertion != nil {
+				line += " = " + posting.Assertion.String()
+			}
+		} else if posting.Assertion != nil {
+			line = addTabsToColumn(line, 44) + "= " + posting.Assertion.String()
 		}
 
-		if strings.TrimSpace(posting.Comment) != "" {
-			line = addTabsToColumn(line, 56) + fmt.Sprintf("; %s", strings.TrimSpace(posting.Comment))
+		if comment := strings.TrimSpace(posting.comment()); comment != "" {
+			line = addTabsToColumn(line, 56) + fmt.Sprintf("; %s", comment)
 		}
 		builder.WriteString(line + "\n")
+		for _, note := range commentLines(posting.Comment, posting.Notes, posting.Tags, posting.Metadata) {
+			builder.WriteString("\t\t; " + note + "\n")
+		}
 	}
 
 	return builder.String()
@@ -91,86 +375,57 @@ func calculateColumnPosition(s string) int {
 	return col
 }
 
-// formatAmounts formats all posting amounts with aligned decimal points.
-// The format ensures '$' has at least one space after it, '-' comes immediately
-// before the first digit, and all decimal points align vertically.
+// formatAmounts formats all posting amounts with aligned decimal marks.
+// Commodities written in front (bare numbers get DefaultCommodity) have at least
+// one space after them, '-' comes immediately before the first digit, and all
+// decimal marks align vertically. Commodities written after the quantity follow it.
 func formatAmounts(postings []Posting) []string {
-	// Parse all amounts to find max widths
-	type parsedAmount struct {
-		negative bool
-		intPart  string
-		decPart  string
+	type formattedAmount struct {
+		prefix string // commodity and space written before the number
+		number string // signed integer part
+		rest   string // decimal part and any suffix commodity
 	}
 
-	parsed := make([]parsedAmount, len(postings))
-	maxIntWidth := 0
-
+	formatted := make([]formattedAmount, len(postings))
+	maxLead := 0
 	for i, posting := range postings {
-		if posting.Amount == "" {
+		if posting.Amount == nil {
 			continue
 		}
+		amount := *posting.Amount
 
-		amount := posting.Amount
-		negative := false
-		if strings.HasPrefix(amount, "-") {
-			negative = true
-			amount = amount[1:]
+		var f formattedAmount
+		mark := string(amount.Style.mark())
+		intPart, decPart, hasDecimal := strings.Cut(amount.Number(), mark)
+		f.number = intPart
+		if hasDecimal {
+			f.rest = mark + decPart
 		}
-
-		parts := strings.Split(amount, ".")
-		intPart := parts[0]
-		decPart := ""
-		if len(parts) > 1 {
-			decPart = parts[1]
-		}
-
-		parsed[i] = parsedAmount{
-			negative: negative,
-			intPart:  intPart,
-			decPart:  decPart,
+		if amount.Suffix {
+			space := ""
+			if amount.Spaced {
+				space = " "
+			}
+			f.rest += space + quoteCommodity(amount.Commodity)
+		} else {
+			f.prefix = quoteCommodity(amount.Unit()) + " "
 		}
+		formatted[i] = f
 
-		// Account for the "-" taking up space
-		width := len(intPart)
-		if negative {
-			width++
-		}
-		if width > maxIntWidth {
-			maxIntWidth = width
+		lead := utf8.RuneCountInString(f.prefix) + len(f.number)
+		if lead > maxLead {
+			maxLead = lead
 		}
 	}
 
-	// Format each amount
+	// Pad between the commodity and the number so the decimal points line up
 	result := make([]string, len(postings))
-	for i, p := range parsed {
-		if postings[i].Amount == "" {
-			result[i] = ""
+	for i, f := range formatted {
+		if postings[i].Amount == nil {
 			continue
 		}
-
-		var amountBuilder strings.Builder
-		amountBuilder.WriteString("$ ")
-
-		// Calculate padding needed
-		currentWidth := len(p.intPart)
-		if p.negative {
-			currentWidth++
-		}
-		padding := maxIntWidth - currentWidth
-
-		// Add padding and sign
-		amountBuilder.WriteString(strings.Repeat(" ", padding))
-		if p.negative {
-			amountBuilder.WriteString("-")
-		}
-
-		amountBuilder.WriteString(p.intPart)
-		if p.decPart != "" {
-			amountBuilder.WriteString(".")
-			amountBuilder.WriteString(p.decPart)
-		}
-
-		result[i] = amountBuilder.String()
+		padding := maxLead - utf8.RuneCountInString(f.prefix) - len(f.number)
+		result[i] = f.prefix + strings.Repeat(" ", padding) + f.number + f.rest
 	}
 
 	return result
@@ -1,9 +1,12 @@
 package core
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 func TestTransactionStringIncludesCommentsAndCleared(t *testing.T) {
@@ -11,10 +14,10 @@ func TestTransactionStringIncludesCommentsAndCleared(t *testing.T) {
 		Date:    time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC),
 		Payee:   "Acme Co",
 		Comment: "Invoice 123",
-		Cleared: true,
+		Status:  Cleared,
 		Postings: []Posting{
-			{Account: "Expenses:Office", Amount: "100.00", Comment: "Supplies"},
-			{Account: "Assets:Checking", Amount: "-100.00"},
+			{Account: "Expenses:Office", Amount: NewAmount(decimal.RequireFromString("100.00"), ""), Comment: "Supplies"},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-100.00"), "")},
 		},
 	}
 
@@ -47,8 +50,8 @@ func TestTransactionStringNotClearedSpacing(t *testing.T) {
 		Date:  time.Date(2025, time.March, 5, 0, 0, 0, 0, time.UTC),
 		Payee: "Pending Payee",
 		Postings: []Posting{
-			{Account: "Assets:Checking", Amount: "-50.00"},
-			{Account: "Expenses:Misc", Amount: "50.00"},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-50.00"), "")},
+			{Account: "Expenses:Misc", Amount: NewAmount(decimal.RequireFromString("50.00"), "")},
 		},
 	}
 
@@ -64,11 +67,11 @@ func TestTransactionStringExactFormat(t *testing.T) {
 		Date:    time.Date(2025, time.January, 15, 0, 0, 0, 0, time.UTC),
 		Payee:   "Test Store",
 		Comment: "monthly supplies",
-		Cleared: true,
+		Status:  Cleared,
 		Postings: []Posting{
-			{Account: "Expenses:Office", Amount: "100.99", Comment: "pens"},
-			{Account: "Expenses:Food", Amount: "1.00"},
-			{Account: "Assets:Checking", Amount: "-101.99"},
+			{Account: "Expenses:Office", Amount: NewAmount(decimal.RequireFromString("100.99"), ""), Comment: "pens"},
+			{Account: "Expenses:Food", Amount: NewAmount(decimal.RequireFromString("1.00"), "")},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-101.99"), "")},
 		},
 	}
 
@@ -85,14 +88,14 @@ func TestTransactionStringExactFormat(t *testing.T) {
 
 func TestTransactionStringAmountAlignment(t *testing.T) {
 	tx := Transaction{
-		Date:    time.Date(2025, time.April, 15, 0, 0, 0, 0, time.UTC),
-		Payee:   "Test Payee",
-		Cleared: true,
+		Date:   time.Date(2025, time.April, 15, 0, 0, 0, 0, time.UTC),
+		Payee:  "Test Payee",
+		Status: Cleared,
 		Postings: []Posting{
-			{Account: "Expenses:Food", Amount: "100.99"},
-			{Account: "Expenses:Travel", Amount: "1.00"},
-			{Account: "Assets:Savings", Amount: "-4999.00"},
-			{Account: "Assets:Checking", Amount: "4897.01"},
+			{Account: "Expenses:Food", Amount: NewAmount(decimal.RequireFromString("100.99"), "")},
+			{Account: "Expenses:Travel", Amount: NewAmount(decimal.RequireFromString("1.00"), "")},
+			{Account: "Assets:Savings", Amount: NewAmount(decimal.RequireFromString("-4999.00"), "")},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("4897.01"), "")},
 		},
 	}
 
@@ -163,3 +166,38 @@ func TestTransactionStringAmountAlignment(t *testing.T) {
 		}
 	}
 }
+
+func TestTransactionStringWritesStatusAndCode(t *testing.T) {
+	tx := Transaction{
+		Date:   time.Date(2025, time.January, 20, 0, 0, 0, 0, time.UTC),
+		Payee:  "Landlord",
+		Status: Pending,
+		Code:   "1042",
+		Postings: []Posting{
+			{Account: "Expenses:Rent", Amount: NewAmount(decimal.RequireFromString("1500.00"), "")},
+			{Account: "Assets:Checking", Amount: NewAmount(decimal.RequireFromString("-1500.00"), "")},
+		},
+	}
+	if header := strings.SplitN(tx.String(), "\n", 2)[0]; header != "2025/01/20 ! (1042) Landlord" {
+		t.Fatalf("unexpected header: %q", header)
+	}
+
+	tx.Status = Uncleared
+	if header := strings.SplitN(tx.String(), "\n", 2)[0]; header != "2025/01/20   (1042) Landlord" {
+		t.Fatalf("unexpected uncleared header: %q", header)
+	}
+}
+
+func TestStatusJSONUsesNames(t *testing.T) {
+	encoded, err := json.Marshal(Transaction{Status: Pending})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if !strings.Contains(string(encoded), `"Status":"pending"`) {
+		t.Fatalf("expected status by name, got %s", encoded)
+	}
+	var decoded Transaction
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded.Status != Pending {
+		t.Fatalf("expected pending status back, got %v (%v)", decoded.Status, err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package importer converts bank statement exports into draft transactions
+// that can be categorized in the TUI before being written to the ledger.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+// ParseCSV reads a bank CSV export and converts each row into a draft transaction
+// using the given profile. Rows that cannot be interpreted are reported as issues.
+func ParseCSV(r io.Reader, profile Profile) (core.ParseResult, error) {
+	if err := profile.Validate(); err != nil {
+		return core.ParseResult{}, err
+	}
+
+	var (
+		transactions []core.Transaction
+		issues       []core.ParseIssue
+		reader       = csv.NewReader(r)
+		row          = 0
+	)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return core.ParseResult{}, fmt.Errorf("error reading csv: %w", err)
+		}
+
+		row++
+		if row <= profile.SkipRows || isBlankRecord(record) {
+			continue
+		}
+
+		lineNumber, _ := reader.FieldPos(0)
+		tx, err := profile.draft(record)
+		if err != nil {
+			issues = append(issues, core.ParseIssue{
+				Line:    lineNumber,
+				Message: err.Error(),
+			})
+			continue
+		}
+		transactions = append(transactions, *tx)
+	}
+
+	return core.ParseResult{Transactions: transactions, Issues: issues}, nil
+}
+
+// draft converts a single CSV record into a draft transaction.
+func (p Profile) draft(record []string) (*core.Transaction, error) {
+	rawDate, err := field(record, p.DateColumn)
+	if err != nil {
+		return nil, err
+	}
+	date, err := time.Parse(p.dateLayout(), rawDate)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized date '%s' (expected layout %s)", rawDate, p.dateLayout())
+	}
+
+	description, err := field(record, p.PayeeColumn)
+	if err != nil {
+		return nil, err
+	}
+	description = strings.Join(strings.Fields(description), " ")
+	if description == "" {
+		return nil, fmt.Errorf("row is missing a description")
+	}
+
+	amount, err := p.amount(record)
+	if err != nil {
+		return nil, err
+	}
+	if amount.IsZero() {
+		return nil, fmt.Errorf("row for '%s' has no amount", description)
+	}
+
+	return &core.Transaction{
+		Date:   date,
+		Payee:  description,
+		Status: core.Cleared,
+		Postings: []core.Posting{
+			{Account: p.Account, Amount: core.NewAmount(amount.Round(2), p.commodity())},
+		},
+		Draft:       true,
+		Description: description,
+	}, nil
+}
+
+// amount returns the signed change to the funding account for a record.
+// Negative values mean money left the account.
+func (p Profile) amount(record []string) (decimal.Decimal, error) {
+	var amount decimal.Decimal
+	if p.AmountColumn > 0 {
+		raw, err := field(record, p.AmountColumn)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if amount, err = parseAmount(raw); err != nil {
+			return decimal.Zero, err
+		}
+	} else {
+		rawDebit, err := field(record, p.DebitColumn)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		rawCredit, err := field(record, p.CreditColumn)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		debit, err := parseAmount(rawDebit)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		credit, err := parseAmount(rawCredit)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		amount = credit.Abs().Sub(debit.Abs())
+	}
+
+	if p.Invert {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// field returns the trimmed value of a 1-based column.
+func field(record []string, column int) (string, error) {
+	if column <= 0 || column > len(record) {
+		return "", fmt.Errorf("row has %d columns, expected at least %d", len(record), column)
+	}
+	return strings.TrimSpace(record[column-1]), nil
+}
+
+// parseAmount reads a bank-formatted amount such as "$1,234.56", "-12.00" or "(12.00)".
+// An empty value is treated as zero.
+func parseAmount(s string) (decimal.Decimal, error) {
+	clean := strings.TrimSpace(s)
+	if clean == "" {
+		return decimal.Zero, nil
+	}
+
+	negative := false
+	if strings.HasPrefix(clean, "(") && strings.HasSuffix(clean, ")") {
+		negative = true
+		clean = clean[1 : len(clean)-1]
+	}
+	clean = strings.ReplaceAll(clean, "$", "")
+	clean = strings.ReplaceAll(clean, ",", "")
+	clean = strings.ReplaceAll(clean, " ", "")
+
+	amount, err := decimal.NewFromString(clean)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("invalid amount '%s'", s)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// isBlankRecord reports whether every field in a record is empty.
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSVSingleAmountColumn(t *testing.T) {
+	data := "Date,Description,Amount\n" +
+		"01/15/2025,SQ *BLUE BOTTLE   8831 OAKLAND CA,-4.50\n" +
+		"01/16/2025,PAYROLL DEPOSIT,\"$1,250.00\"\n"
+
+	profile := Profile{
+		Account:      "Assets:Checking",
+		DateColumn:   1,
+		PayeeColumn:  2,
+		AmountColumn: 3,
+		SkipRows:     1,
+	}
+
+	result, err := ParseCSV(strings.NewReader(data), profile)
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+	if len(result.Issues) != 0 {
+		t.Fatalf("expected no issues, got %+v", result.Issues)
+	}
+	if len(result.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(result.Transactions))
+	}
+
+	tx := result.Transactions[0]
+	expectedDate := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	if !tx.Date.Equal(expectedDate) {
+		t.Errorf("expected date %v, got %v", expectedDate, tx.Date)
+	}
+	if tx.Payee != "SQ *BLUE BOTTLE 8831 OAKLAND CA" {
+		t.Errorf("unexpected payee: %q", tx.Payee)
+	}
+	if tx.Description != tx.Payee {
+		t.Errorf("expected description to match raw payee, got %q", tx.Description)
+	}
+	if !tx.Draft {
+		t.Errorf("expected imported transaction to be a draft")
+	}
+	if len(tx.Postings) != 1 || tx.Postings[0].Account != "Assets:Checking" || tx.Postings[0].Amount.String() != "$-4.50" {
+		t.Errorf("unexpected postings: %+v", tx.Postings)
+	}
+
+	if amount := result.Transactions[1].Postings[0].Amount.String(); amount != "$1250.00" {
+		t.Errorf("expected deposit amount $1250.00, got %q", amount)
+	}
+}
+
+func TestParseCSVSplitColumnsAndInvert(t *testing.T) {
+	data := "2025-02-01,Coffee,3.25,\n" +
+		"2025-02-02,Refund,,10.00\n"
+
+	profile := Profile{
+		Account:      "Liabilities:Credit Card",
+		DateColumn:   1,
+		DateFormat:   "2006-01-02",
+		PayeeColumn:  2,
+		DebitColumn:  3,
+		CreditColumn: 4,
+		Commodity:    "EUR",
+	}
+
+	result, err := ParseCSV(strings.NewReader(data), profile)
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+	if len(result.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d (issues: %+v)", len(result.Transactions), result.Issues)
+	}
+	if amount := result.Transactions[0].Postings[0].Amount.String(); amount != "-3.25 EUR" {
+		t.Errorf("expected debit to be negative, got %q", amount)
+	}
+	if amount := result.Transactions[1].Postings[0].Amount.String(); amount != "10.00 EUR" {
+		t.Errorf("expected credit to be positive, got %q", amount)
+	}
+
+	profile.Invert = true
+	result, err = ParseCSV(strings.NewReader(data), profile)
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+	if amount := result.Transactions[0].Postings[0].Amount.String(); amount != "3.25 EUR" {
+		t.Errorf("expected inverted debit to be positive, got %q", amount)
+	}
+}
+
+func TestParseCSVReportsBadRows(t *testing.T) {
+	data := "01/15/2025,Good Row,-1.00\n" +
+		"not a date,Bad Date,-2.00\n" +
+		"01/17/2025,Bad Amount,abc\n" +
+		"01/18/2025,Short Row\n"
+
+	profile := Profile{
+		Account:      "Assets:Checking",
+		DateColumn:   1,
+		PayeeColumn:  2,
+		AmountColumn: 3,
+	}
+
+	result, err := ParseCSV(strings.NewReader(data), profile)
+	if err != nil {
+		t.Fatalf("ParseCSV returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(result.Transactions))
+	}
+	if len(result.Issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d: %+v", len(result.Issues), result.Issues)
+	}
+	if result.Issues[0].Line != 2 {
+		t.Errorf("expected first issue on line 2, got %d", result.Issues[0].Line)
+	}
+}
+
+func TestParseCSVRejectsInvalidProfile(t *testing.T) {
+	profile := Profile{DateColumn: 1, PayeeColumn: 2, AmountColumn: 3}
+	if _, err := ParseCSV(strings.NewReader(""), profile); err == nil {
+		t.Fatalf("expected error for profile without account")
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]string{
+		"":          "0",
+		"12.34":     "12.34",
+		"-12.34":    "-12.34",
+		"$1,234.56": "1234.56",
+		"(45.00)":   "-45",
+		"$ -3.10":   "-3.1",
+	}
+	for input, expected := range tests {
+		amount, err := parseAmount(input)
+		if err != nil {
+			t.Errorf("parseAmount(%q) returned error: %v", input, err)
+			continue
+		}
+		if amount.String() != expected {
+			t.Errorf("parseAmount(%q) = %s, expected %s", input, amount.String(), expected)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+const fitidKey = "FITID"
+
+// ParseOFX reads an OFX/QFX statement in either the 1.x SGML or the 2.x XML
+// flavour and converts each STMTTRN record into a draft transaction posting
+// to the given funding account. The record's FITID is kept in the funding
+// posting's comment so later imports can skip it.
+func ParseOFX(r io.Reader, account string) (core.ParseResult, error) {
+	if strings.TrimSpace(account) == "" {
+		return core.ParseResult{}, fmt.Errorf("a funding account is required")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return core.ParseResult{}, fmt.Errorf("error reading ofx: %w", err)
+	}
+	content := string(data)
+	if !strings.Contains(strings.ToUpper(content), "<OFX>") {
+		return core.ParseResult{}, fmt.Errorf("file does not contain an <OFX> element")
+	}
+
+	var (
+		transactions []core.Transaction
+		issues       []core.ParseIssue
+		record       map[string]string
+		recordLine   int
+	)
+
+	for _, element := range scanOFX(content) {
+		switch element.tag {
+		case "STMTTRN":
+			record = make(map[string]string)
+			recordLine = element.line
+		case "/STMTTRN":
+			if record == nil {
+				continue
+			}
+			tx, err := ofxDraft(record, account)
+			if err != nil {
+				issues = append(issues, core.ParseIssue{
+					Line:    recordLine,
+					Message: err.Error(),
+				})
+			} else {
+				transactions = append(transactions, *tx)
+			}
+			record = nil
+		default:
+			if record == nil || strings.HasPrefix(element.tag, "/") || element.value == "" {
+				continue
+			}
+			if _, exists := record[element.tag]; !exists {
+				record[element.tag] = element.value
+			}
+		}
+	}
+
+	return core.ParseResult{Transactions: transactions, Issues: issues}, nil
+}
+
+// ofxElement is a single tag found while scanning an OFX document, along with
+// the text that immediately follows it.
+type ofxElement struct {
+	tag   string
+	value string
+	line  int
+}
+
+// scanOFX splits an OFX document into tags. SGML leaf elements have no closing
+// tag, so the value of every element is simply the text up to the next tag.
+func scanOFX(content string) []ofxElement {
+	var elements []ofxElement
+	line := 1
+	i := 0
+	for i < len(content) {
+		start := strings.IndexByte(content[i:], '<')
+		if start == -1 {
+			break
+		}
+		line += strings.Count(content[i:i+start], "\n")
+		i += start
+
+		end := strings.IndexByte(content[i:], '>')
+		if end == -1 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(content[i+1 : i+end]))
+		i += end + 1
+
+		next := strings.IndexByte(content[i:], '<')
+		if next == -1 {
+			next = len(content) - i
+		}
+		value := strings.TrimSpace(content[i : i+next])
+
+		// Skip processing instructions and self-describing headers in OFX 2.x
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+		if idx := strings.IndexAny(tag, " \t\r\n"); idx != -1 {
+			tag = tag[:idx]
+		}
+		elements = append(elements, ofxElement{tag: tag, value: unescapeOFX(value), line: line})
+	}
+	return elements
+}
+
+// ofxDraft converts the leaf values of a STMTTRN record into a draft transaction.
+func ofxDraft(record map[string]string, account string) (*core.Transaction, error) {
+	fitid := record["FITID"]
+	if fitid == "" {
+		return nil, fmt.Errorf("transaction is missing a FITID")
+	}
+
+	date, err := parseOFXDate(record["DTPOSTED"])
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: %w", fitid, err)
+	}
+
+	amount, err := decimal.NewFromString(record["TRNAMT"])
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s has invalid amount '%s'", fitid, record["TRNAMT"])
+	}
+
+	description := record["NAME"]
+	if description == "" {
+		description = record["MEMO"]
+	}
+	description = strings.Join(strings.Fields(description), " ")
+	if description == "" {
+		return nil, fmt.Errorf("transaction %s is missing a name", fitid)
+	}
+
+	return &core.Transaction{
+		Date:   date,
+		Payee:  description,
+		Status: core.Cleared,
+		Postings: []core.Posting{
+			{Account: account, Amount: core.NewAmount(amount.Round(2), ""), Comment: FITIDComment(fitid)},
+		},
+		Draft:       true,
+		Description: description,
+	}, nil
+}
+
+// parseOFXDate reads the date portion of an OFX datetime such as "20250115120000.000[-5:EST]".
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s'", s)
+	}
+	return date, nil
+}
+
+// unescapeOFX replaces the XML entities that may appear in OFX values.
+func unescapeOFX(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	replacer := strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", "\"", "&apos;", "'", "&nbsp;", " ")
+	return replacer.Replace(s)
+}
+
+// FITIDComment formats a posting comment recording a statement transaction ID.
+func FITIDComment(fitid string) string {
+	return fitidKey + ": " + fitid
+}
+
+// ExtractFITID returns the statement transaction ID recorded in a comment, if any.
+func ExtractFITID(comment string) (string, bool) {
+	idx := strings.Index(comment, fitidKey+":")
+	if idx == -1 {
+		return "", false
+	}
+	fields := strings.Fields(comment[idx+len(fitidKey)+1:])
+	if len(fields) == 0 {
+		return "", false
+	}
+	return fields[0], true
+}
+
+// KnownFITIDs collects every FITID recorded in the comments or metadata of the given transactions.
+func KnownFITIDs(transactions []core.Transaction) map[string]bool {
+	known := make(map[string]bool)
+	for _, tx := range transactions {
+		if fitid, ok := ExtractFITID(tx.Comment); ok {
+			known[fitid] = true
+		}
+		if fitid := tx.Metadata[fitidKey]; fitid != "" {
+			known[fitid] = true
+		}
+		for _, posting := range tx.Postings {
+			if fitid, ok := ExtractFITID(posting.Comment); ok {
+				known[fitid] = true
+			}
+			if fitid := posting.Metadata[fitidKey]; fitid != "" {
+				known[fitid] = true
+			}
+		}
+	}
+	return known
+}
+
+// SkipKnownFITIDs removes drafts whose FITID is already known and returns the
+// remaining drafts along with the number that were skipped. Kept FITIDs are
+// added to known so repeats within the same statement are skipped too.
+func SkipKnownFITIDs(transactions []core.Transaction, known map[string]bool) ([]core.Transaction, int) {
+	kept := make([]core.Transaction, 0, len(transactions))
+	skipped := 0
+	for _, tx := range transactions {
+		duplicate := false
+		for _, posting := range tx.Postings {
+			if fitid, ok := ExtractFITID(posting.Comment); ok && known[fitid] {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			skipped++
+			continue
+		}
+		for _, posting := range tx.Postings {
+			if fitid, ok := ExtractFITID(posting.Comment); ok {
+				known[fitid] = true
+			}
+		}
+		kept = append(kept, tx)
+	}
+	return kept, skipped
+}
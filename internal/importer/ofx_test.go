@@ -0,0 +1,152 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250115120000.000[-5:EST]
+<TRNAMT>-4.50
+<FITID>2025011501
+<NAME>SQ *BLUE BOTTLE
+<MEMO>OAKLAND CA
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20250116
+<TRNAMT>1250.00
+<FITID>2025011602
+<MEMO>PAYROLL &amp; BONUS
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20250117
+<TRNAMT>-1.00
+<NAME>NO FITID
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20250201</DTPOSTED>
+            <TRNAMT>-32.10</TRNAMT>
+            <FITID>CC-1</FITID>
+            <NAME>FUEL STATION</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFXSGML(t *testing.T) {
+	result, err := ParseOFX(strings.NewReader(sgmlStatement), "Assets:Checking")
+	if err != nil {
+		t.Fatalf("ParseOFX returned error: %v", err)
+	}
+	if len(result.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(result.Transactions))
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Line != 25 {
+		t.Fatalf("expected one issue for the record on line 25, got %+v", result.Issues)
+	}
+
+	tx := result.Transactions[0]
+	if !tx.Date.Equal(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date: %v", tx.Date)
+	}
+	if tx.Payee != "SQ *BLUE BOTTLE" || !tx.Draft {
+		t.Errorf("unexpected draft: %+v", tx)
+	}
+	posting := tx.Postings[0]
+	if posting.Account != "Assets:Checking" || posting.Amount.String() != "-4.50" || posting.Comment != "FITID: 2025011501" {
+		t.Errorf("unexpected posting: %+v", posting)
+	}
+
+	if payee := result.Transactions[1].Payee; payee != "PAYROLL & BONUS" {
+		t.Errorf("expected memo fallback with entities decoded, got %q", payee)
+	}
+}
+
+func TestParseOFXXML(t *testing.T) {
+	result, err := ParseOFX(strings.NewReader(xmlStatement), "Liabilities:Credit Card")
+	if err != nil {
+		t.Fatalf("ParseOFX returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 || len(result.Issues) != 0 {
+		t.Fatalf("expected 1 transaction and no issues, got %d / %+v", len(result.Transactions), result.Issues)
+	}
+	posting := result.Transactions[0].Postings[0]
+	if posting.Amount.String() != "-32.10" || posting.Comment != "FITID: CC-1" {
+		t.Errorf("unexpected posting: %+v", posting)
+	}
+}
+
+func TestParseOFXRejectsNonOFX(t *testing.T) {
+	if _, err := ParseOFX(strings.NewReader("Date,Amount\n"), "Assets:Checking"); err == nil {
+		t.Fatalf("expected error for non-OFX content")
+	}
+}
+
+func TestSkipKnownFITIDs(t *testing.T) {
+	ledger := []core.Transaction{
+		{
+			Payee: "Blue Bottle",
+			Postings: []core.Posting{
+				{Account: "Expenses:Food:Coffee", Amount: core.NewAmount(decimal.RequireFromString("4.50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-4.50"), ""), Comment: "FITID: 2025011501"},
+			},
+		},
+	}
+
+	result, err := ParseOFX(strings.NewReader(sgmlStatement), "Assets:Checking")
+	if err != nil {
+		t.Fatalf("ParseOFX returned error: %v", err)
+	}
+	drafts := append(result.Transactions, result.Transactions[1])
+
+	kept, skipped := SkipKnownFITIDs(drafts, KnownFITIDs(ledger))
+	if skipped != 2 {
+		t.Fatalf("expected ledger match and in-file repeat to be skipped, got %d", skipped)
+	}
+	if len(kept) != 1 || kept[0].Postings[0].Comment != "FITID: 2025011602" {
+		t.Fatalf("unexpected kept drafts: %+v", kept)
+	}
+}
+
+func TestExtractFITID(t *testing.T) {
+	if fitid, ok := ExtractFITID("Rewards card FITID: ABC-123 extra"); !ok || fitid != "ABC-123" {
+		t.Fatalf("expected ABC-123, got %q (ok=%v)", fitid, ok)
+	}
+	if _, ok := ExtractFITID("no id here"); ok {
+		t.Fatalf("expected no FITID")
+	}
+}
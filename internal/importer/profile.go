@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+)
+
+const (
+	profilesFileName  = "import-profiles.json"
+	defaultDateFormat = "01/02/2006"
+)
+
+// Profile describes how the columns of a bank's CSV export map onto a transaction.
+// Column numbers are 1-based; zero means the column is not used.
+type Profile struct {
+	Account      string `json:"account"`                 // funding account each row posts to
+	DateColumn   int    `json:"date_column"`             // column holding the transaction date
+	DateFormat   string `json:"date_format,omitempty"`   // Go time layout, e.g. "01/02/2006"
+	PayeeColumn  int    `json:"payee_column"`            // column holding the bank description
+	AmountColumn int    `json:"amount_column,omitempty"` // single signed amount column
+	DebitColumn  int    `json:"debit_column,omitempty"`  // money leaving the account
+	CreditColumn int    `json:"credit_column,omitempty"` // money entering the account
+	Invert       bool   `json:"invert,omitempty"`        // flip signs (e.g. credit card exports)
+	SkipRows     int    `json:"skip_rows,omitempty"`     // header rows to ignore
+	Commodity    string `json:"commodity,omitempty"`     // commodity of the amounts, e.g. "$" or "EUR"
+}
+
+// Validate reports whether the profile has enough information to read a statement.
+func (p Profile) Validate() error {
+	if p.Account == "" {
+		return fmt.Errorf("profile is missing a funding account")
+	}
+	if p.DateColumn <= 0 {
+		return fmt.Errorf("profile is missing a date column")
+	}
+	if p.PayeeColumn <= 0 {
+		return fmt.Errorf("profile is missing a payee column")
+	}
+	if p.AmountColumn <= 0 && (p.DebitColumn <= 0 || p.CreditColumn <= 0) {
+		return fmt.Errorf("profile needs an amount column or both debit and credit columns")
+	}
+	if p.AmountColumn > 0 && (p.DebitColumn > 0 || p.CreditColumn > 0) {
+		return fmt.Errorf("profile cannot use an amount column together with debit/credit columns")
+	}
+	if p.SkipRows < 0 {
+		return fmt.Errorf("profile has a negative skip-rows value")
+	}
+	return nil
+}
+
+// dateLayout returns the configured date layout or the default US bank format.
+func (p Profile) dateLayout() string {
+	if p.DateFormat == "" {
+		return defaultDateFormat
+	}
+	return p.DateFormat
+}
+
+// commodity returns the configured commodity or the ledger's default.
+func (p Profile) commodity() string {
+	if p.Commodity == "" {
+		return core.DefaultCommodity
+	}
+	return p.Commodity
+}
+
+// DefaultProfilesPath returns the location of the saved profiles in the user's config directory.
+func DefaultProfilesPath() (string, error) {
+	return configPath(profilesFileName)
+}
+
+// configPath returns the path of a file inside teller's config directory.
+func configPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "teller", name), nil
+}
+
+// LoadProfiles reads the saved profiles keyed by name.
+// A missing file yields an empty set.
+func LoadProfiles(path string) (map[string]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]Profile), nil
+		}
+		return nil, fmt.Errorf("failed to read profiles file: %w", err)
+	}
+
+	profiles := make(map[string]Profile)
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// SaveProfiles writes the profiles to disk, creating the parent directory if needed.
+func SaveProfiles(path string, profiles map[string]Profile) error {
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal profiles: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write profiles file: %w", err)
+	}
+	return nil
+}
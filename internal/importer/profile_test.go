@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestProfilesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "profiles.json")
+
+	profiles, err := LoadProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadProfiles on missing file returned error: %v", err)
+	}
+	if len(profiles) != 0 {
+		t.Fatalf("expected no profiles, got %d", len(profiles))
+	}
+
+	profiles["chase"] = Profile{
+		Account:      "Assets:Checking",
+		DateColumn:   1,
+		PayeeColumn:  3,
+		AmountColumn: 4,
+		SkipRows:     1,
+	}
+	if err := SaveProfiles(path, profiles); err != nil {
+		t.Fatalf("SaveProfiles returned error: %v", err)
+	}
+
+	loaded, err := LoadProfiles(path)
+	if err != nil {
+		t.Fatalf("LoadProfiles returned error: %v", err)
+	}
+	if loaded["chase"] != profiles["chase"] {
+		t.Fatalf("expected %+v, got %+v", profiles["chase"], loaded["chase"])
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	valid := Profile{Account: "Assets:Checking", DateColumn: 1, PayeeColumn: 2, AmountColumn: 3}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid profile, got %v", err)
+	}
+
+	split := Profile{Account: "Assets:Checking", DateColumn: 1, PayeeColumn: 2, DebitColumn: 3}
+	if err := split.Validate(); err == nil {
+		t.Fatalf("expected error when credit column is missing")
+	}
+
+	mixed := Profile{Account: "Assets:Checking", DateColumn: 1, PayeeColumn: 2, AmountColumn: 3, DebitColumn: 4, CreditColumn: 5}
+	if err := mixed.Validate(); err == nil {
+		t.Fatalf("expected error when mixing amount and debit/credit columns")
+	}
+}
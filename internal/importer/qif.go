@@ -0,0 +1,310 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+const categoriesFileName = "qif-categories.json"
+
+// QIFOptions controls how QIF records are converted into transactions.
+type QIFOptions struct {
+	Account    string            // funding account for every record
+	Categories map[string]string // QIF category (or "[Transfer]") -> ledger account
+	DayFirst   bool              // dates are written DD/MM/YY instead of MM/DD/YY
+}
+
+// qifSplit is a single S/E/$ group within a QIF record.
+type qifSplit struct {
+	category string
+	memo     string
+	amount   string
+}
+
+// qifRecord collects the fields of a QIF record up to its "^" terminator.
+type qifRecord struct {
+	line     int
+	date     string
+	amount   string
+	payee    string
+	memo     string
+	category string
+	cleared  string
+	splits   []qifSplit
+}
+
+// ParseQIF reads a Quicken Interchange Format export and converts the records
+// of !Type:Bank and !Type:CCard sections into transactions. Records whose
+// categories map onto ledger accounts are fully categorized, including split
+// records; records without a category, or with one missing from the mapping,
+// are left as drafts.
+func ParseQIF(r io.Reader, options QIFOptions) (core.ParseResult, error) {
+	if strings.TrimSpace(options.Account) == "" {
+		return core.ParseResult{}, fmt.Errorf("a funding account is required")
+	}
+
+	var (
+		transactions []core.Transaction
+		issues       []core.ParseIssue
+		scanner      = bufio.NewScanner(r)
+		lineNumber   = 0
+		supported    = false
+		record       = &qifRecord{}
+	)
+
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			header := strings.ToLower(strings.TrimSpace(line))
+			switch {
+			case header == "!type:bank" || header == "!type:ccard":
+				supported = true
+			case strings.HasPrefix(header, "!type:"):
+				supported = false
+				issues = append(issues, core.ParseIssue{
+					Line:    lineNumber,
+					Message: fmt.Sprintf("skipping unsupported section '%s'", strings.TrimSpace(line)),
+				})
+			default:
+				// Options and account lists don't change the section type
+			}
+			record = &qifRecord{}
+			continue
+		}
+
+		if !supported {
+			continue
+		}
+		if record.line == 0 {
+			record.line = lineNumber
+		}
+
+		code, value := line[0], strings.TrimSpace(line[1:])
+		switch code {
+		case 'D':
+			record.date = value
+		case 'T', 'U':
+			record.amount = value
+		case 'P':
+			record.payee = value
+		case 'M':
+			record.memo = value
+		case 'L':
+			record.category = value
+		case 'C':
+			record.cleared = value
+		case 'S':
+			record.splits = append(record.splits, qifSplit{category: value})
+		case 'E':
+			if len(record.splits) > 0 {
+				record.splits[len(record.splits)-1].memo = value
+			}
+		case '$':
+			if len(record.splits) > 0 {
+				record.splits[len(record.splits)-1].amount = value
+			}
+		case '^':
+			tx, err := record.transaction(options)
+			if err != nil {
+				issues = append(issues, core.ParseIssue{
+					Line:    record.line,
+					Message: err.Error(),
+				})
+			} else {
+				transactions = append(transactions, *tx)
+			}
+			record = &qifRecord{}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return core.ParseResult{}, fmt.Errorf("error reading qif: %w", err)
+	}
+
+	return core.ParseResult{Transactions: transactions, Issues: issues}, nil
+}
+
+// transaction converts a complete QIF record into a transaction.
+func (r *qifRecord) transaction(options QIFOptions) (*core.Transaction, error) {
+	date, err := parseQIFDate(r.date, options.DayFirst)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := parseAmount(r.amount)
+	if err != nil {
+		return nil, err
+	}
+
+	description := strings.Join(strings.Fields(r.payee), " ")
+	if description == "" {
+		description = strings.Join(strings.Fields(r.memo), " ")
+	}
+	if description == "" {
+		return nil, fmt.Errorf("record is missing a payee")
+	}
+
+	status := core.Uncleared
+	if strings.ContainsAny(strings.ToLower(r.cleared), "*cxr") {
+		status = core.Cleared
+	}
+	funding := core.Posting{Account: options.Account, Amount: core.NewAmount(total.Round(2), "")}
+	tx := &core.Transaction{
+		Date:        date,
+		Payee:       description,
+		Comment:     r.memo,
+		Status:      status,
+		Draft:       true,
+		Description: description,
+	}
+	if description == r.memo {
+		tx.Comment = ""
+	}
+
+	// Split records carry their own categories and amounts
+	if len(r.splits) > 0 {
+		var postings []core.Posting
+		allocated := decimal.Zero
+		mapped := true
+		for _, split := range r.splits {
+			amount, err := parseAmount(split.amount)
+			if err != nil {
+				return nil, err
+			}
+			if split.category == "" {
+				return nil, fmt.Errorf("split for '%s' is missing a category", description)
+			}
+			account, ok := options.account(split.category)
+			mapped = mapped && ok
+			allocated = allocated.Add(amount)
+			postings = append(postings, core.Posting{
+				Account: account,
+				Amount:  core.NewAmount(amount.Neg().Round(2), ""),
+				Comment: split.memo,
+			})
+		}
+		if !allocated.Equal(total) {
+			return nil, fmt.Errorf("splits for '%s' total %s but the record total is %s", description, allocated.StringFixed(2), total.StringFixed(2))
+		}
+		if !mapped {
+			tx.Postings = []core.Posting{funding}
+			return tx, nil
+		}
+		tx.Postings = append(postings, funding)
+		tx.Draft = false
+		return tx, nil
+	}
+
+	account, ok := options.account(r.category)
+	if !ok || total.IsZero() {
+		tx.Postings = []core.Posting{funding}
+		return tx, nil
+	}
+
+	tx.Postings = []core.Posting{
+		{Account: account, Amount: core.NewAmount(total.Neg().Round(2), "")},
+		funding,
+	}
+	tx.Draft = false
+	return tx, nil
+}
+
+// account maps a QIF category, or a transfer such as "[Savings]", onto a
+// ledger account through the category mapping. Any "/class" suffix is
+// ignored. It reports false for a missing or unmapped category.
+func (o QIFOptions) account(category string) (string, bool) {
+	category = strings.TrimSpace(category)
+	if mapped, ok := o.Categories[category]; ok {
+		return mapped, true
+	}
+	if idx := strings.Index(category, "/"); idx != -1 {
+		category = strings.TrimSpace(category[:idx])
+		if mapped, ok := o.Categories[category]; ok {
+			return mapped, true
+		}
+	}
+	return "", false
+}
+
+// parseQIFDate reads the date styles Quicken and GnuCash write, such as
+// "1/15/2025", "01/15/25", "1/15'25" and "2025-01-15".
+func parseQIFDate(s string, dayFirst bool) (time.Time, error) {
+	clean := strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if clean == "" {
+		return time.Time{}, fmt.Errorf("record is missing a date")
+	}
+	if date, err := time.Parse("2006-01-02", clean); err == nil {
+		return date, nil
+	}
+
+	parts := strings.FieldsFunc(clean, func(r rune) bool {
+		return r == '/' || r == '\'' || r == '-' || r == '.'
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("unrecognized date '%s'", s)
+	}
+
+	values := make([]int, 3)
+	for i, part := range parts {
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unrecognized date '%s'", s)
+		}
+		values[i] = value
+	}
+
+	month, day, year := values[0], values[1], values[2]
+	if dayFirst {
+		month, day = day, month
+	}
+	if year < 100 {
+		if year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, fmt.Errorf("unrecognized date '%s'", s)
+	}
+	return date, nil
+}
+
+// DefaultCategoriesPath returns the location of the QIF category mapping in the user's config directory.
+func DefaultCategoriesPath() (string, error) {
+	return configPath(categoriesFileName)
+}
+
+// LoadCategories reads a JSON object mapping QIF categories to ledger accounts.
+// A missing file yields an empty mapping.
+func LoadCategories(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make(map[string]string), nil
+		}
+		return nil, fmt.Errorf("failed to read categories file: %w", err)
+	}
+
+	categories := make(map[string]string)
+	if err := json.Unmarshal(data, &categories); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal categories: %w", err)
+	}
+	return categories, nil
+}
@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+const qifExport = `!Type:Bank
+D1/15'25
+T-4.50
+CX
+PBlue Bottle
+LDining
+^
+D01/16/2025
+T-100.00
+PCostco
+MMonthly run
+SGroceries
+EFood
+$-70.00
+SHousehold
+$-30.00
+^
+D1/17/25
+T1,250.00
+PACME Payroll
+LSalary
+^
+D1/18/25
+T-12.00
+PMystery
+^
+!Type:Invst
+D1/19/25
+T-1.00
+^
+!Type:CCard
+D2025-01-20
+T-25.00
+PFuel Station
+L[Checking]
+^
+D1/21/25
+T-10.00
+PBad Split
+SDining
+$-4.00
+^
+`
+
+func TestParseQIF(t *testing.T) {
+	result, err := ParseQIF(strings.NewReader(qifExport), QIFOptions{
+		Account: "Assets:Checking",
+		Categories: map[string]string{
+			"Dining":     "Expenses:Food:Dining",
+			"Groceries":  "Expenses:Food:Groceries",
+			"Household":  "Expenses:Household",
+			"[Checking]": "Assets:Checking",
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseQIF returned error: %v", err)
+	}
+	if len(result.Transactions) != 5 {
+		t.Fatalf("expected 5 transactions, got %d", len(result.Transactions))
+	}
+	if len(result.Issues) != 2 || result.Issues[0].Line != 27 || result.Issues[1].Line != 37 {
+		t.Fatalf("expected issues for the Invst section and the bad split, got %+v", result.Issues)
+	}
+
+	coffee := result.Transactions[0]
+	if !coffee.Date.Equal(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)) || coffee.Status != core.Cleared || coffee.Draft {
+		t.Errorf("unexpected transaction: %+v", coffee)
+	}
+	assertPostings(t, coffee.Postings, []core.Posting{
+		{Account: "Expenses:Food:Dining", Amount: core.NewAmount(decimal.RequireFromString("4.50"), "")},
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-4.50"), "")},
+	})
+
+	costco := result.Transactions[1]
+	if costco.Comment != "Monthly run" || costco.Status == core.Cleared {
+		t.Errorf("unexpected transaction: %+v", costco)
+	}
+	assertPostings(t, costco.Postings, []core.Posting{
+		{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("70.00"), ""), Comment: "Food"},
+		{Account: "Expenses:Household", Amount: core.NewAmount(decimal.RequireFromString("30.00"), "")},
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-100.00"), "")},
+	})
+
+	salary := result.Transactions[2]
+	if !salary.Draft || len(salary.Postings) != 1 {
+		t.Errorf("expected unmapped category to stay a draft, got %+v", salary)
+	}
+
+	mystery := result.Transactions[3]
+	if !mystery.Draft || mystery.Description != "Mystery" || len(mystery.Postings) != 1 {
+		t.Errorf("expected uncategorized record to stay a draft, got %+v", mystery)
+	}
+
+	assertPostings(t, result.Transactions[4].Postings, []core.Posting{
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("25.00"), "")},
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-25.00"), "")},
+	})
+}
+
+func TestParseQIFLeavesUnmappedSplitsAsDrafts(t *testing.T) {
+	data := "!Type:Bank\nD1/16/25\nT-100.00\nPCostco\nSGroceries\n$-70.00\nSHousehold\n$-30.00\n^\n"
+	result, err := ParseQIF(strings.NewReader(data), QIFOptions{
+		Account:    "Assets:Checking",
+		Categories: map[string]string{"Groceries": "Expenses:Food:Groceries"},
+	})
+	if err != nil {
+		t.Fatalf("ParseQIF returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %+v", result.Issues)
+	}
+	costco := result.Transactions[0]
+	if !costco.Draft {
+		t.Errorf("expected a split with an unmapped category to stay a draft")
+	}
+	assertPostings(t, costco.Postings, []core.Posting{
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-100.00"), "")},
+	})
+}
+
+func TestParseQIFDayFirst(t *testing.T) {
+	data := "!Type:Bank\nD15/01/2025\nT-1.00\nPCafe\n^\n"
+	result, err := ParseQIF(strings.NewReader(data), QIFOptions{Account: "Assets:Checking", DayFirst: true})
+	if err != nil {
+		t.Fatalf("ParseQIF returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %+v", result.Issues)
+	}
+	if date := result.Transactions[0].Date; !date.Equal(time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date: %v", date)
+	}
+}
+
+func assertPostings(t *testing.T, got, expected []core.Posting) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("expected %d postings, got %+v", len(expected), got)
+	}
+	for i := range expected {
+		if !got[i].Equal(expected[i]) {
+			t.Errorf("posting %d: expected %+v, got %+v", i, expected[i], got[i])
+		}
+	}
+}
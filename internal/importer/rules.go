@@ -0,0 +1,237 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+const rulesFileName = "import-rules.json"
+
+// Rule categorizes imported drafts whose statement line matches all of its conditions.
+// Conditions that are left empty are ignored.
+type Rule struct {
+	Name      string           `json:"name,omitempty"`
+	Match     string           `json:"match,omitempty"`      // case-insensitive substring of the description
+	Pattern   string           `json:"pattern,omitempty"`    // regular expression matched against the description
+	MinAmount *decimal.Decimal `json:"min_amount,omitempty"` // inclusive lower bound on the absolute amount
+	MaxAmount *decimal.Decimal `json:"max_amount,omitempty"` // inclusive upper bound on the absolute amount
+	Account   string           `json:"account,omitempty"`    // funding account the draft was imported into
+
+	Payee   string       `json:"payee,omitempty"`   // replacement payee name
+	Targets []RuleTarget `json:"targets,omitempty"` // accounts receiving the balancing amount
+	Cleared *bool        `json:"cleared,omitempty"` // overrides the cleared flag
+	Comment string       `json:"comment,omitempty"` // transaction comment to add
+
+	pattern *regexp.Regexp
+}
+
+// RuleTarget is an account that receives part of a matched draft's balancing amount.
+type RuleTarget struct {
+	Account string           `json:"account"`
+	Percent *decimal.Decimal `json:"percent,omitempty"` // share of the amount; omitted takes the remainder
+}
+
+// Label returns the rule's name, or its position in the rules file when unnamed.
+func (r Rule) Label(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule #%d", index+1)
+}
+
+// compile validates the rule and prepares its regular expression.
+func (r *Rule) compile() error {
+	if r.Match == "" && r.Pattern == "" && r.MinAmount == nil && r.MaxAmount == nil && r.Account == "" {
+		return fmt.Errorf("rule has no conditions")
+	}
+	if r.Pattern != "" {
+		pattern, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		r.pattern = pattern
+	}
+	remainders := 0
+	total := decimal.Zero
+	for _, target := range r.Targets {
+		if strings.TrimSpace(target.Account) == "" {
+			return fmt.Errorf("rule target is missing an account")
+		}
+		if target.Percent == nil {
+			remainders++
+			continue
+		}
+		if target.Percent.IsNegative() {
+			return fmt.Errorf("rule target '%s' has a negative percent", target.Account)
+		}
+		total = total.Add(*target.Percent)
+	}
+	hundred := decimal.NewFromInt(100)
+	switch {
+	case remainders > 1:
+		return fmt.Errorf("only one rule target may omit its percent")
+	case total.GreaterThan(hundred):
+		return fmt.Errorf("rule target percents add up to %s, more than 100", total)
+	case remainders == 0 && len(r.Targets) > 0 && !total.Equal(hundred):
+		return fmt.Errorf("rule target percents add up to %s, not 100", total)
+	}
+	return nil
+}
+
+// Matches reports whether a draft satisfies every condition of the rule.
+func (r Rule) Matches(tx core.Transaction) bool {
+	description := tx.Description
+	if description == "" {
+		description = tx.Payee
+	}
+	if r.Match != "" && !strings.Contains(strings.ToLower(description), strings.ToLower(r.Match)) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(description) {
+		return false
+	}
+
+	funding, amount, ok := fundingPosting(tx)
+	if r.Account != "" && (!ok || funding.Account != r.Account) {
+		return false
+	}
+	if r.MinAmount != nil && (!ok || amount.Abs().LessThan(*r.MinAmount)) {
+		return false
+	}
+	if r.MaxAmount != nil && (!ok || amount.Abs().GreaterThan(*r.MaxAmount)) {
+		return false
+	}
+	return true
+}
+
+// Apply returns the draft with the rule's changes. When the rule has targets,
+// the balancing postings are added and the draft is marked categorized.
+func (r Rule) Apply(tx core.Transaction) core.Transaction {
+	if r.Payee != "" {
+		tx.Payee = r.Payee
+	}
+	if r.Cleared != nil {
+		tx.Status = core.Uncleared
+		if *r.Cleared {
+			tx.Status = core.Cleared
+		}
+	}
+	if r.Comment != "" {
+		tx.Comment = r.Comment
+	}
+
+	funding, amount, ok := fundingPosting(tx)
+	if len(r.Targets) == 0 || !ok {
+		return tx
+	}
+
+	// Split the balancing amount across targets at the funding amount's
+	// precision; the remainder target absorbs rounding
+	balancing := amount.Neg()
+	places := max(-amount.Exponent(), 0)
+	allocated := decimal.Zero
+	remainderIndex := len(r.Targets) - 1
+	shares := make([]decimal.Decimal, len(r.Targets))
+	for i, target := range r.Targets {
+		if target.Percent == nil {
+			remainderIndex = i
+			continue
+		}
+		if i == remainderIndex {
+			continue
+		}
+		shares[i] = balancing.Mul(*target.Percent).Div(decimal.NewFromInt(100)).Round(places)
+		allocated = allocated.Add(shares[i])
+	}
+	shares[remainderIndex] = balancing.Sub(allocated)
+
+	postings := make([]core.Posting, 0, len(r.Targets)+1)
+	if amount.Sign() > 0 {
+		postings = append(postings, funding)
+	}
+	for i, target := range r.Targets {
+		postings = append(postings, core.Posting{Account: target.Account, Amount: funding.Amount.WithQuantity(shares[i])})
+	}
+	if amount.Sign() <= 0 {
+		postings = append(postings, funding)
+	}
+	tx.Postings = postings
+	tx.Draft = false
+	return tx
+}
+
+// MatchRule returns the index of the first rule matching the draft.
+func MatchRule(rules []Rule, tx core.Transaction) (int, bool) {
+	for i, rule := range rules {
+		if rule.Matches(tx) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// ApplyRules applies the first matching rule to each draft in place
+// and returns the number of drafts that matched a rule.
+func ApplyRules(rules []Rule, transactions []core.Transaction) int {
+	applied := 0
+	for i, tx := range transactions {
+		if !tx.Draft {
+			continue
+		}
+		if index, ok := MatchRule(rules, tx); ok {
+			transactions[i] = rules[index].Apply(tx)
+			applied++
+		}
+	}
+	return applied
+}
+
+// ParseRules decodes and validates a JSON list of rules.
+func ParseRules(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rules: %w", err)
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("%s: %w", rules[i].Label(i), err)
+		}
+	}
+	return rules, nil
+}
+
+// LoadRules reads the rules file at the given path.
+// A missing file yields no rules.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+	return ParseRules(data)
+}
+
+// DefaultRulesPath returns the location of the rules file in the user's config directory.
+func DefaultRulesPath() (string, error) {
+	return configPath(rulesFileName)
+}
+
+// fundingPosting returns the single funding posting of an imported draft and its amount.
+func fundingPosting(tx core.Transaction) (core.Posting, decimal.Decimal, bool) {
+	if len(tx.Postings) != 1 {
+		return core.Posting{}, decimal.Zero, false
+	}
+	posting := tx.Postings[0]
+	if posting.Amount == nil {
+		return posting, decimal.Zero, false
+	}
+	return posting, posting.Amount.Quantity, true
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func draftTransaction(description, account, quantity, commodity string) core.Transaction {
+	return core.Transaction{
+		Payee:       description,
+		Status:      core.Cleared,
+		Draft:       true,
+		Description: description,
+		Postings:    []core.Posting{{Account: account, Amount: core.NewAmount(decimal.RequireFromString(quantity), commodity)}},
+	}
+}
+
+func TestParseRulesAndMatch(t *testing.T) {
+	data := []byte(`[
+		{"name": "big coffee", "match": "blue bottle", "min_amount": "20", "payee": "Blue Bottle Catering"},
+		{"name": "coffee", "pattern": "^SQ \\*BLUE BOTTLE", "payee": "Blue Bottle Coffee",
+		 "targets": [{"account": "Expenses:Food:Coffee"}], "comment": "imported"},
+		{"account": "Liabilities:Credit Card", "max_amount": 5, "payee": "Small Charge"}
+	]`)
+
+	rules, err := ParseRules(data)
+	if err != nil {
+		t.Fatalf("ParseRules returned error: %v", err)
+	}
+
+	tx := draftTransaction("SQ *BLUE BOTTLE 8831 OAKLAND CA", "Assets:Checking", "-4.50", "")
+	index, ok := MatchRule(rules, tx)
+	if !ok || rules[index].Label(index) != "coffee" {
+		t.Fatalf("expected coffee rule to match, got index %d (ok=%v)", index, ok)
+	}
+
+	tx = draftTransaction("SQ *BLUE BOTTLE 8831 OAKLAND CA", "Assets:Checking", "-45.00", "")
+	if index, ok := MatchRule(rules, tx); !ok || index != 0 {
+		t.Fatalf("expected amount-bounded rule to match first, got index %d", index)
+	}
+
+	tx = draftTransaction("PARKING METER", "Liabilities:Credit Card", "-2.00", "")
+	if index, ok := MatchRule(rules, tx); !ok || rules[index].Label(index) != "rule #3" {
+		t.Fatalf("expected account rule to match, got index %d (ok=%v)", index, ok)
+	}
+
+	tx = draftTransaction("PARKING METER", "Assets:Checking", "-2.00", "")
+	if _, ok := MatchRule(rules, tx); ok {
+		t.Fatalf("expected no rule to match other account")
+	}
+}
+
+func TestParseRulesRejectsInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"no conditions":       `[{"payee": "Anything"}]`,
+		"bad pattern":         `[{"pattern": "(", "payee": "Broken"}]`,
+		"two remainders":      `[{"match": "x", "targets": [{"account": "A"}, {"account": "B"}]}]`,
+		"target sans account": `[{"match": "x", "targets": [{"percent": 50}]}]`,
+		"negative percent":    `[{"match": "x", "targets": [{"account": "A", "percent": -10}, {"account": "B"}]}]`,
+		"over 100 percent":    `[{"match": "x", "targets": [{"account": "A", "percent": 80}, {"account": "B", "percent": 30}]}]`,
+		"under 100 percent":   `[{"match": "x", "targets": [{"account": "A", "percent": 50}, {"account": "B", "percent": 30}]}]`,
+	}
+	for name, data := range tests {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestApplyRulesCategorizesDrafts(t *testing.T) {
+	rules, err := ParseRules([]byte(`[
+		{"match": "costco", "payee": "Costco", "cleared": false,
+		 "targets": [{"account": "Expenses:Food:Groceries", "percent": 70}, {"account": "Expenses:Household"}]},
+		{"match": "unknown", "payee": "Renamed Only"}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseRules returned error: %v", err)
+	}
+
+	transactions := []core.Transaction{
+		draftTransaction("COSTCO WHSE #0123", "Assets:Checking", "-100.01", ""),
+		draftTransaction("UNKNOWN MERCHANT", "Assets:Checking", "-5.00", ""),
+		draftTransaction("NO MATCH", "Assets:Checking", "-1.00", ""),
+	}
+
+	if applied := ApplyRules(rules, transactions); applied != 2 {
+		t.Fatalf("expected 2 rules applied, got %d", applied)
+	}
+
+	costco := transactions[0]
+	if costco.Draft || costco.Status == core.Cleared || costco.Payee != "Costco" {
+		t.Fatalf("unexpected categorized draft: %+v", costco)
+	}
+	if costco.Description != "COSTCO WHSE #0123" {
+		t.Fatalf("expected raw description to be kept, got %q", costco.Description)
+	}
+	expected := []core.Posting{
+		{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("70.01"), "")},
+		{Account: "Expenses:Household", Amount: core.NewAmount(decimal.RequireFromString("30.00"), "")},
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-100.01"), "")},
+	}
+	if len(costco.Postings) != len(expected) {
+		t.Fatalf("expected %d postings, got %+v", len(expected), costco.Postings)
+	}
+	for i := range expected {
+		if !costco.Postings[i].Equal(expected[i]) {
+			t.Errorf("posting %d: expected %+v, got %+v", i, expected[i], costco.Postings[i])
+		}
+	}
+
+	renamed := transactions[1]
+	if !renamed.Draft || renamed.Payee != "Renamed Only" {
+		t.Fatalf("expected rule without targets to only rename payee, got %+v", renamed)
+	}
+
+	if transactions[2].Payee != "NO MATCH" || !transactions[2].Draft {
+		t.Fatalf("expected unmatched draft to be untouched, got %+v", transactions[2])
+	}
+}
+
+func TestApplyRulesSplitsAtFundingPrecision(t *testing.T) {
+	rules, err := ParseRules([]byte(`[
+		{"match": "dinner", "targets": [{"account": "Expenses:Food", "percent": 50}, {"account": "Expenses:Gifts", "percent": 50}]},
+		{"match": "shares", "targets": [{"account": "Assets:Brokerage", "percent": 33.3}, {"account": "Assets:Savings"}]}
+	]`))
+	if err != nil {
+		t.Fatalf("ParseRules returned error: %v", err)
+	}
+
+	transactions := []core.Transaction{
+		draftTransaction("DINNER", "Assets:Checking", "-1001", "JPY"),
+		draftTransaction("SHARES", "Assets:Checking", "-10.005", "AAPL"),
+	}
+	ApplyRules(rules, transactions)
+
+	expected := [][]string{
+		{"501 JPY", "500 JPY", "-1001 JPY"},
+		{"3.332 AAPL", "6.673 AAPL", "-10.005 AAPL"},
+	}
+	for i, amounts := range expected {
+		postings := transactions[i].Postings
+		if len(postings) != len(amounts) {
+			t.Fatalf("transaction %d: expected %d postings, got %+v", i, len(amounts), postings)
+		}
+		for j, amount := range amounts {
+			if got := postings[j].Amount.String(); got != amount {
+				t.Errorf("transaction %d posting %d: expected %s, got %s", i, j, amount, got)
+			}
+		}
+	}
+}
+
+func TestLoadRulesMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatalf("LoadRules on missing file returned error: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("expected no rules, got %d", len(rules))
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"match": "x"}]`), 0o600); err != nil {
+		t.Fatalf("failed to write rules file: %v", err)
+	}
+	rules, err = LoadRules(path)
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d (err=%v)", len(rules), err)
+	}
+}
@@ -0,0 +1,104 @@
+package intelligence
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+)
+
+const (
+	// AccountHalfLife is how long it takes an account's usage score to halve
+	AccountHalfLife = 180 * 24 * time.Hour
+)
+
+// AccountUsage records how often and how recently an account was used.
+type AccountUsage struct {
+	Count    int
+	LastUsed time.Time
+}
+
+// merged combines usage from two sources.
+func (u AccountUsage) merged(other AccountUsage) AccountUsage {
+	result := AccountUsage{Count: u.Count + other.Count, LastUsed: u.LastUsed}
+	if other.LastUsed.After(result.LastUsed) {
+		result.LastUsed = other.LastUsed
+	}
+	return result
+}
+
+// score decays the use count by the time between the last use and now.
+// Undated usage is counted without decay.
+func (u AccountUsage) score(now time.Time) float64 {
+	if u.LastUsed.IsZero() || !now.After(u.LastUsed) {
+		return float64(u.Count)
+	}
+	age := now.Sub(u.LastUsed)
+	return float64(u.Count) * math.Exp2(-float64(age)/float64(AccountHalfLife))
+}
+
+// AccountUsage returns the combined base and runtime usage of an account.
+func (db *IntelligenceDB) AccountUsage(account string) AccountUsage {
+	usage := db.Accounts.Usage(account)
+	if db.Runtime != nil {
+		usage = usage.merged(db.Runtime.Accounts.Usage(account))
+	}
+	return usage
+}
+
+// accountScores scores accounts by time-decayed usage. Decay is measured from
+// the most recent use among the accounts, so rankings don't drift with the clock.
+// Accounts the payee's templates use are boosted in proportion to how many of
+// the payee's transactions they appear in; an account used in all of them
+// outranks every account the payee never uses.
+func (db *IntelligenceDB) accountScores(accounts []string, payee string) map[string]float64 {
+	usage := make(map[string]AccountUsage, len(accounts))
+	now := time.Time{}
+	for _, account := range accounts {
+		usage[account] = db.AccountUsage(account)
+		if usage[account].LastUsed.After(now) {
+			now = usage[account].LastUsed
+		}
+	}
+
+	scores := make(map[string]float64, len(accounts))
+	best := 0.0
+	for _, account := range accounts {
+		scores[account] = usage[account].score(now)
+		best = max(best, scores[account])
+	}
+
+	if payee == "" {
+		return scores
+	}
+	paired := make(map[string]int)
+	total := 0
+	for _, template := range db.FindTemplates(payee) {
+		total += template.Frequency
+		for _, account := range append(append([]string(nil), template.DebitAccounts...), template.CreditAccounts...) {
+			account, _ = core.ParsePostingAccount(account)
+			paired[account] += template.Frequency
+		}
+	}
+	if total == 0 {
+		return scores
+	}
+	for _, account := range accounts {
+		share := min(float64(paired[account])/float64(total), 1)
+		scores[account] += share * (best + 1)
+	}
+	return scores
+}
+
+// rankAccounts sorts accounts by score (descending), then alphabetically.
+func (db *IntelligenceDB) rankAccounts(accounts []string, payee string) {
+	scores := db.accountScores(accounts, payee)
+	sort.Slice(accounts, func(i, j int) bool {
+		left, right := scores[accounts[i]], scores[accounts[j]]
+		if left == right {
+			return accounts[i] < accounts[j]
+		}
+		return left > right
+	})
+}
@@ -0,0 +1,84 @@
+package intelligence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func TestTrieRecordsUsage(t *testing.T) {
+	trie := NewTrie()
+	trie.Record("Expenses:Auto:Gas", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC))
+	trie.Record("Expenses:Auto:Gas", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+	trie.Record("Expenses:Auto:Gas", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	trie.Insert("Expenses:Auto")
+
+	usage := trie.Usage("Expenses:Auto:Gas")
+	if usage.Count != 3 {
+		t.Fatalf("expected 3 uses, got %d", usage.Count)
+	}
+	if !usage.LastUsed.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected last use on 2025-03-01, got %s", usage.LastUsed)
+	}
+	if got := trie.Usage("Expenses:Auto"); got.Count != 0 {
+		t.Fatalf("expected inserted word to have no usage, got %+v", got)
+	}
+	if got := trie.Usage("Expenses:Au"); got.Count != 0 {
+		t.Fatalf("expected prefix to have no usage, got %+v", got)
+	}
+}
+
+func TestFindAccountsRanksByDecayedUsage(t *testing.T) {
+	posting := func(date time.Time, payee, account string) core.Transaction {
+		return core.Transaction{Date: date, Payee: payee, Postings: []core.Posting{
+			{Account: account, Amount: core.NewAmount(decimal.RequireFromString("10.00"), "")},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-10.00"), "")},
+		}}
+	}
+	var transactions []core.Transaction
+	// Heavily used years ago
+	for i := range 12 {
+		transactions = append(transactions, posting(time.Date(2019, 1, 1+i, 0, 0, 0, 0, time.UTC), "Old Garage", "Expenses:Auto:Repairs"))
+	}
+	// Used a few times recently
+	for i := range 3 {
+		transactions = append(transactions, posting(time.Date(2025, 6, 1+i, 0, 0, 0, 0, time.UTC), "Fuel Station", "Expenses:Auto:Gas"))
+	}
+	transactions = append(transactions, posting(time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), "Car Wash", "Expenses:Auto:Wash"))
+
+	db, _, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+
+	results := db.FindAccounts("Expenses:Auto", "")
+	expected := []string{"Expenses:Auto:Gas", "Expenses:Auto:Wash", "Expenses:Auto:Repairs"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	// Accounts the payee is usually entered with come first
+	results = db.FindAccounts("Expenses:Auto", "Car Wash")
+	expected = []string{"Expenses:Auto:Wash", "Expenses:Auto:Gas", "Expenses:Auto:Repairs"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	// Runtime usage counts towards the score
+	var batch []core.Transaction
+	for i := range 4 {
+		batch = append(batch, posting(time.Date(2025, 6, 10+i, 0, 0, 0, 0, time.UTC), "Car Wash", "Expenses:Auto:Wash"))
+	}
+	db.Runtime.BuildFromBatch(batch)
+	results = db.FindAccounts("Expenses:Auto", "")
+	expected = []string{"Expenses:Auto:Wash", "Expenses:Auto:Gas", "Expenses:Auto:Repairs"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+	if usage := db.AccountUsage("Expenses:Auto:Wash"); usage.Count != 5 {
+		t.Fatalf("expected combined usage of 5, got %d", usage.Count)
+	}
+}
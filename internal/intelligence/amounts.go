@@ -0,0 +1,176 @@
+package intelligence
+
+import (
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AmountStats summarizes the amounts seen for a template so they can be predicted.
+// Ratios line up with the template's (sorted) DebitAccounts and CreditAccounts.
+// Totals are in Commodity; when a template moves to another commodity only the
+// amounts in the newest one are kept.
+type AmountStats struct {
+	Commodity    string            // commodity of the totals (see core.Amount.Unit)
+	Last         decimal.Decimal   // total of the most recent transaction
+	LastDate     time.Time         // date of the most recent transaction
+	Common       decimal.Decimal   // most frequently seen total
+	CommonCount  int               // number of transactions with the Common total
+	DebitRatios  []decimal.Decimal // share of the total carried by each debit account
+	CreditRatios []decimal.Decimal // share of the total carried by each credit account
+
+	counts     map[string]int // total -> occurrences
+	debitSums  []decimal.Decimal
+	creditSums []decimal.Decimal
+}
+
+// observe records the amounts of one transaction in a single commodity.
+// Debits are positive, credits negative.
+func (s *AmountStats) observe(date time.Time, commodity string, debits, credits []decimal.Decimal) {
+	total := decimal.Zero
+	for _, amount := range debits {
+		total = total.Add(amount)
+	}
+	if total.IsZero() {
+		return
+	}
+	if s.Commodity != "" && commodity != s.Commodity {
+		if date.Before(s.LastDate) {
+			return
+		}
+		*s = AmountStats{}
+	}
+	s.Commodity = commodity
+
+	if s.counts == nil {
+		s.counts = make(map[string]int)
+	}
+	s.counts[total.StringFixed(2)]++
+	if s.LastDate.IsZero() || !date.Before(s.LastDate) {
+		s.Last = total
+		s.LastDate = date
+	}
+
+	s.debitSums = addSums(s.debitSums, debits)
+	s.creditSums = addSums(s.creditSums, credits)
+}
+
+// merged combines two sets of statistics for the same template structure
+// without modifying either. Ties on the last date go to other.
+func (s AmountStats) merged(other AmountStats) AmountStats {
+	if s.Commodity != "" && other.Commodity != "" && s.Commodity != other.Commodity {
+		if other.LastDate.Before(s.LastDate) {
+			return s
+		}
+		return other
+	}
+	result := AmountStats{
+		Commodity:  s.Commodity,
+		Last:       s.Last,
+		LastDate:   s.LastDate,
+		counts:     make(map[string]int, len(s.counts)+len(other.counts)),
+		debitSums:  addSums(addSums(nil, s.debitSums), other.debitSums),
+		creditSums: addSums(addSums(nil, s.creditSums), other.creditSums),
+	}
+	for total, count := range s.counts {
+		result.counts[total] += count
+	}
+	for total, count := range other.counts {
+		result.counts[total] += count
+	}
+	if len(other.counts) > 0 && (len(s.counts) == 0 || !other.LastDate.Before(s.LastDate)) {
+		result.Last = other.Last
+		result.LastDate = other.LastDate
+	}
+	if result.Commodity == "" {
+		result.Commodity = other.Commodity
+	}
+	result.finalize()
+	return result
+}
+
+// finalize derives the most common total and split ratios from the raw sums.
+func (s *AmountStats) finalize() {
+	s.Common = decimal.Zero
+	s.CommonCount = 0
+
+	totals := make([]decimal.Decimal, 0, len(s.counts))
+	for raw := range s.counts {
+		if total, err := decimal.NewFromString(raw); err == nil {
+			totals = append(totals, total)
+		}
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].LessThan(totals[j]) })
+
+	// Ties go to the most recent total, then to the smallest
+	for _, total := range totals {
+		count := s.counts[total.StringFixed(2)]
+		if count > s.CommonCount || (count == s.CommonCount && total.Equal(s.Last)) {
+			s.Common = total
+			s.CommonCount = count
+		}
+	}
+	s.DebitRatios = ratios(s.debitSums)
+	s.CreditRatios = ratios(s.creditSums)
+}
+
+// Predicted returns the total to pre-fill for the template: the most common
+// total once it has repeated, otherwise the most recent one.
+func (s AmountStats) Predicted() (decimal.Decimal, bool) {
+	if s.CommonCount > 1 {
+		return s.Common, true
+	}
+	if !s.Last.IsZero() {
+		return s.Last, true
+	}
+	return decimal.Zero, false
+}
+
+// Split divides a total across lines using the given ratios, rounded to cents.
+// Any rounding remainder goes to the line with the largest share so the parts
+// always add up to the total.
+func Split(total decimal.Decimal, ratios []decimal.Decimal) []decimal.Decimal {
+	if len(ratios) == 0 {
+		return nil
+	}
+	parts := make([]decimal.Decimal, len(ratios))
+	allocated := decimal.Zero
+	largest := 0
+	for i, ratio := range ratios {
+		parts[i] = total.Mul(ratio).Round(2)
+		allocated = allocated.Add(parts[i])
+		if ratio.GreaterThan(ratios[largest]) {
+			largest = i
+		}
+	}
+	parts[largest] = parts[largest].Add(total.Sub(allocated))
+	return parts
+}
+
+// addSums adds the absolute values of amounts to sums position by position.
+func addSums(sums, amounts []decimal.Decimal) []decimal.Decimal {
+	for len(sums) < len(amounts) {
+		sums = append(sums, decimal.Zero)
+	}
+	for i, amount := range amounts {
+		sums[i] = sums[i].Add(amount.Abs())
+	}
+	return sums
+}
+
+// ratios converts per-line sums into shares of their combined total.
+func ratios(sums []decimal.Decimal) []decimal.Decimal {
+	total := decimal.Zero
+	for _, sum := range sums {
+		total = total.Add(sum)
+	}
+	if total.IsZero() {
+		return nil
+	}
+	result := make([]decimal.Decimal, len(sums))
+	for i, sum := range sums {
+		result[i] = sum.Div(total)
+	}
+	return result
+}
@@ -0,0 +1,79 @@
+package intelligence
+
+import (
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func TestTemplateAmountStats(t *testing.T) {
+	month := func(m time.Month) time.Time {
+		return time.Date(2025, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	rent := func(date time.Time, total, utilities string) core.Transaction {
+		return core.Transaction{
+			Date:  date,
+			Payee: "Landlord",
+			Postings: []core.Posting{
+				{Account: "Expenses:Utilities", Amount: core.NewAmount(decimal.RequireFromString(utilities), "")},
+				{Account: "Expenses:Rent"},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString(total).Neg(), "")},
+			},
+		}
+	}
+
+	db, _, err := NewIntelligenceDB(core.ParseResult{Transactions: []core.Transaction{
+		rent(month(3), "1500.00", "300.00"),
+		rent(month(1), "1500.00", "300.00"),
+		rent(month(2), "1600.00", "320.00"),
+	}})
+	if err != nil {
+		t.Fatalf("NewIntelligenceDB returned error: %v", err)
+	}
+
+	templates := db.FindTemplates("Landlord")
+	if len(templates) != 1 {
+		t.Fatalf("expected 1 template, got %d", len(templates))
+	}
+	stats := templates[0].Amounts
+	if !stats.Last.Equal(decimal.RequireFromString("1500")) || !stats.LastDate.Equal(month(3)) {
+		t.Errorf("unexpected last amount %s on %v", stats.Last, stats.LastDate)
+	}
+	if !stats.Common.Equal(decimal.RequireFromString("1500")) || stats.CommonCount != 2 {
+		t.Errorf("unexpected common amount %s (%d)", stats.Common, stats.CommonCount)
+	}
+
+	// Debit accounts are sorted, so rent comes before utilities
+	total, ok := stats.Predicted()
+	if !ok {
+		t.Fatalf("expected a predicted amount")
+	}
+	parts := Split(total, stats.DebitRatios)
+	if len(parts) != 2 || parts[0].StringFixed(2) != "1200.00" || parts[1].StringFixed(2) != "300.00" {
+		t.Errorf("unexpected debit split: %v", parts)
+	}
+
+	// A newer runtime amount becomes the last amount when merged
+	db.Runtime.BuildFromBatch([]core.Transaction{rent(month(4), "1650.00", "330.00")})
+	merged := db.FindTemplates("Landlord")[0].Amounts
+	if merged.Last.StringFixed(2) != "1650.00" || merged.CommonCount != 2 {
+		t.Errorf("unexpected merged stats: last %s, common %s (%d)", merged.Last, merged.Common, merged.CommonCount)
+	}
+	if db.Templates["Landlord"][0].Amounts.Last.StringFixed(2) != "1500.00" {
+		t.Errorf("merging should not modify the base template")
+	}
+}
+
+func TestSplitAssignsRemainderToLargestShare(t *testing.T) {
+	third := decimal.NewFromInt(1).Div(decimal.NewFromInt(3))
+	parts := Split(decimal.RequireFromString("100"), []decimal.Decimal{third, third.Add(decimal.RequireFromString("0.0001")), third})
+	sum := decimal.Zero
+	for _, part := range parts {
+		sum = sum.Add(part)
+	}
+	if !sum.Equal(decimal.NewFromInt(100)) || parts[1].StringFixed(2) != "33.34" {
+		t.Fatalf("unexpected split: %v", parts)
+	}
+}
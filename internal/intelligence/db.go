@@ -13,13 +13,21 @@ type templateBucket struct {
 	debit     []string
 	credit    []string
 	frequency int
+	amounts   AmountStats
 }
 
-// TemplateRecord stores a transaction structure and its frequency.
+type templatePosting struct {
+	account   string
+	amount    decimal.Decimal
+	hasAmount bool
+}
+
+// TemplateRecord stores a transaction structure, its frequency and the amounts seen with it.
 type TemplateRecord struct {
 	DebitAccounts  []string
 	CreditAccounts []string
 	Frequency      int
+	Amounts        AmountStats
 }
 
 // BuildReport captures metrics and issues encountered while constructing the intelligence DB.
@@ -37,27 +45,38 @@ func (r BuildReport) HasIssues() bool {
 
 // IntelligenceDB is the in-memory data store for all suggestion features.
 type IntelligenceDB struct {
-	Payees    map[string]int
-	Accounts  *Trie
-	Templates map[string][]TemplateRecord
-	Runtime   *RuntimeIntelligence
+	Payees       map[string]int
+	Tags         map[string]int
+	Accounts     *Trie
+	Templates    map[string][]TemplateRecord
+	Transactions []core.Transaction
+	Recurrences  []Recurrence
+	Automated    []core.AutomatedTransaction
+	Declared     map[string]bool       // accounts declared with account directives
+	Formats      core.CommodityFormats // styles of commodities declared with a format
+	Prices       *PriceDB
+	Runtime      *RuntimeIntelligence
 }
 
 // NewIntelligenceDB creates a new intelligence database from parsed transactions.
 func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, error) {
 	db := &IntelligenceDB{
 		Payees:    make(map[string]int),
+		Tags:      make(map[string]int),
 		Accounts:  NewTrie(),
 		Templates: make(map[string][]TemplateRecord),
+		Prices:    NewPriceDB(result.Prices),
+		Automated: result.Automated,
+		Declared:  make(map[string]bool),
+		Formats:   core.NewCommodityFormats(result.Commodities, result.DecimalComma),
 		Runtime:   NewRuntimeIntelligence(),
 	}
 
 	transactions := result.Transactions
+	db.Transactions = transactions
 
 	// Track payee usage frequencies
 	payeeFreq := make(map[string]int)
-	// Extract unique accounts
-	accountSet := make(map[string]bool)
 	// Capture non-fatal issues encountered during analysis.
 	var issues []string
 
@@ -65,26 +84,39 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 		if tx.Payee != "" {
 			payeeFreq[tx.Payee]++
 		}
+		recordTags(db.Tags, tx)
 
-		// Process all postings to extract account names
+		// Process all postings to record account usage
 		for _, posting := range tx.Postings {
 			if posting.Account != "" {
-				accountSet[posting.Account] = true
+				db.Accounts.Record(posting.Account, tx.Date)
 			}
 		}
+		if tx.Bucket != "" {
+			db.Accounts.Insert(tx.Bucket)
+		}
 	}
 
-	db.Payees = payeeFreq
-
-	// Insert all accounts into the Trie
-	for account := range accountSet {
+	// Declared accounts and payees are suggested even before they're used
+	for _, account := range result.Accounts {
 		db.Accounts.Insert(account)
+		db.Declared[account] = true
+	}
+	for _, payee := range result.Payees {
+		if _, ok := payeeFreq[payee]; !ok {
+			payeeFreq[payee] = 0
+		}
 	}
 
+	db.Payees = payeeFreq
+
 	// Analyze transaction templates
 	templateFreq := make(map[string]map[string]templateBucket) // payee -> key -> bucket
+	// Balance assignments get their amounts from the running balance of their account
+	balances := core.RunningBalances{}
 
 	for _, tx := range transactions {
+		resolved, _ := balances.Apply(tx)
 		if tx.Payee == "" || len(tx.Postings) == 0 {
 			if tx.Payee == "" {
 				issues = append(issues, fmt.Sprintf("transaction on %s missing payee", tx.Date.Format("2006-01-02")))
@@ -95,55 +127,60 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 			continue
 		}
 
-		var debitAccounts []string
-		var creditAccounts []string
-
-		type templatePosting struct {
-			account   string
-			amount    decimal.Decimal
-			hasAmount bool
-		}
+		var debitEntries []templatePosting
+		var creditEntries []templatePosting
 
 		var postings []templatePosting
-		var balance decimal.Decimal
+		balance := core.Balance{}
+		priced := false
 		var missing []int
 
-		for _, posting := range tx.Postings {
+		for i, posting := range tx.Postings {
 			account := strings.TrimSpace(posting.Account)
 			if account == "" {
 				issues = append(issues, fmt.Sprintf("payee %q has posting with missing account", tx.Payee))
 				continue
 			}
-
-			rawAmount := strings.TrimSpace(posting.Amount)
-			entry := templatePosting{account: account}
-			if rawAmount == "" {
-				missing = append(missing, len(postings))
-				postings = append(postings, entry)
-				continue
+			if posting.IsAssignment() {
+				posting.Amount = resolved.Postings[i].Amount
 			}
 
-			amount, err := decimal.NewFromString(rawAmount)
-			if err != nil {
-				issues = append(issues, fmt.Sprintf("payee %q account %q has invalid amount %q", tx.Payee, account, rawAmount))
-				postings = append(postings, entry)
+			// Templates keep virtual postings in their ledger form so they're entered the same way
+			entry := templatePosting{account: posting.Kind.FormatAccount(account)}
+			if posting.Amount == nil {
+				if posting.Balances() {
+					missing = append(missing, len(postings))
+					postings = append(postings, entry)
+				}
 				continue
 			}
 
-			entry.amount = amount
+			entry.amount = posting.Amount.Quantity
 			entry.hasAmount = true
 			postings = append(postings, entry)
-			balance = balance.Add(amount)
+			// Priced postings balance at their cost; unbalanced virtual postings don't count
+			if posting.Balances() {
+				balance.Add(*posting.Weight())
+			}
+			priced = priced || posting.Price != nil || posting.Lot != nil
 		}
 
 		if len(postings) == 0 {
 			continue
 		}
 
+		// Transactions that don't balance on their own are balanced by the bucket account
+		if len(missing) == 0 && tx.Bucket != "" && !balance.IsZero() {
+			missing = append(missing, len(postings))
+			postings = append(postings, templatePosting{account: tx.Bucket})
+		}
 		if len(missing) == 1 {
-			remainder := balance.Neg()
-			postings[missing[0]].amount = remainder
-			postings[missing[0]].hasAmount = true
+			if remainder, ok := elidedRemainder(balance); ok {
+				postings[missing[0]].amount = remainder
+				postings[missing[0]].hasAmount = true
+			} else {
+				issues = append(issues, fmt.Sprintf("payee %q transaction on %s can't infer its elided amount with several commodities unbalanced", tx.Payee, tx.Date.Format("2006-01-02")))
+			}
 		} else if len(missing) > 1 {
 			issues = append(issues, fmt.Sprintf("payee %q transaction on %s has %d postings without amounts", tx.Payee, tx.Date.Format("2006-01-02"), len(missing)))
 		}
@@ -154,21 +191,19 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 				continue
 			}
 			if entry.amount.Sign() >= 0 {
-				debitAccounts = append(debitAccounts, entry.account)
+				debitEntries = append(debitEntries, entry)
 			} else {
-				creditAccounts = append(creditAccounts, entry.account)
+				creditEntries = append(creditEntries, entry)
 			}
 		}
 
-		if len(debitAccounts) == 0 && len(creditAccounts) == 0 {
+		if len(debitEntries) == 0 && len(creditEntries) == 0 {
 			issues = append(issues, fmt.Sprintf("payee %q transaction on %s produced empty template", tx.Payee, tx.Date.Format("2006-01-02")))
 			continue
 		}
 
-		sortedDebit := append([]string(nil), debitAccounts...)
-		sortedCredit := append([]string(nil), creditAccounts...)
-		sort.Strings(sortedDebit)
-		sort.Strings(sortedCredit)
+		sortedDebit, debitAmounts := sortTemplatePostings(debitEntries)
+		sortedCredit, creditAmounts := sortTemplatePostings(creditEntries)
 		templateKey := strings.Join(sortedDebit, "|") + "->" + strings.Join(sortedCredit, "|")
 
 		if templateFreq[tx.Payee] == nil {
@@ -179,6 +214,11 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 		bucket.frequency++
 		bucket.debit = sortedDebit
 		bucket.credit = sortedCredit
+		// Amounts of priced postings aren't in the commodity they balance in,
+		// so they can't be predicted from it
+		if commodity, ok := singleCommodity(balance); ok && !priced {
+			bucket.amounts.observe(tx.Date, commodity, debitAmounts, creditAmounts)
+		}
 		templateFreq[tx.Payee][templateKey] = bucket
 	}
 
@@ -187,10 +227,12 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 	for payee, templates := range templateFreq {
 		var records []TemplateRecord
 		for _, bucket := range templates {
+			bucket.amounts.finalize()
 			records = append(records, TemplateRecord{
 				DebitAccounts:  append([]string(nil), bucket.debit...),
 				CreditAccounts: append([]string(nil), bucket.credit...),
 				Frequency:      bucket.frequency,
+				Amounts:        bucket.amounts,
 			})
 		}
 
@@ -209,6 +251,12 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 		totalTemplates += len(records)
 	}
 
+	db.Recurrences = DetectRecurrences(transactions, db.Templates)
+	// Budgets from periodic transactions stand in for any schedule seen in the history
+	budgets, budgetIssues := BudgetRecurrences(result.Periodic, latestDate(transactions))
+	db.Recurrences = mergeRecurrences(db.Recurrences, budgets)
+	issues = append(issues, budgetIssues...)
+
 	report := BuildReport{
 		Transactions:    len(transactions),
 		UniquePayees:    len(db.Payees),
@@ -219,7 +267,7 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 	for _, issue := range result.Issues {
 		report.Issues = append(report.Issues, core.LoadIssue{
 			Stage:   "parser",
-			Message: fmt.Sprintf("line %d: %s", issue.Line, issue.Message),
+			Message: fmt.Sprintf("%s: %s", issue.Location(), issue.Message),
 		})
 	}
 
@@ -233,55 +281,66 @@ func NewIntelligenceDB(result core.ParseResult) (*IntelligenceDB, BuildReport, e
 	return db, report, nil
 }
 
-// FindPayees returns payees that start with the given prefix.
-// Results from both base and runtime intelligence are merged and returned
-// ranked by usage frequency (descending), with alphabetical tiebreaking.
-func (db *IntelligenceDB) FindPayees(prefix string) []string {
-	prefix = strings.ToLower(prefix)
-	seen := make(map[string]bool)
-	var matches []string
-
-	frequency := make(map[string]int)
-
-	// Collect all matching payees from runtime
-	if db.Runtime != nil {
-		for payee, count := range db.Runtime.Payees {
-			if strings.HasPrefix(strings.ToLower(payee), prefix) {
-				frequency[payee] += count
-			}
-		}
+// elidedRemainder returns the amount an elided posting takes to balance the
+// others. It can only be inferred when at most one commodity is unbalanced.
+func elidedRemainder(balance core.Balance) (decimal.Decimal, bool) {
+	unbalanced := balance.Commodities()
+	switch len(unbalanced) {
+	case 0:
+		return decimal.Zero, true
+	case 1:
+		return balance[unbalanced[0]].Neg(), true
 	}
+	return decimal.Zero, false
+}
 
-	// Collect all matching payees from base database (avoiding duplicates)
-	for payee, count := range db.Payees {
-		if strings.HasPrefix(strings.ToLower(payee), prefix) {
-			frequency[payee] += count
-		}
+// singleCommodity returns the commodity of a transaction's amounts when they all share one.
+func singleCommodity(balance core.Balance) (string, bool) {
+	if len(balance) != 1 {
+		return "", false
+	}
+	for commodity := range balance {
+		return commodity, true
 	}
+	return "", false
+}
 
-	for payee := range frequency {
-		if !seen[payee] {
-			matches = append(matches, payee)
-			seen[payee] = true
+// sortTemplatePostings orders postings by account (largest amount first within an
+// account) and returns the accounts and amounts in that order.
+func sortTemplatePostings(entries []templatePosting) ([]string, []decimal.Decimal) {
+	sorted := append([]templatePosting(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].account == sorted[j].account {
+			return sorted[i].amount.Abs().GreaterThan(sorted[j].amount.Abs())
 		}
+		return sorted[i].account < sorted[j].account
+	})
+	accounts := make([]string, len(sorted))
+	amounts := make([]decimal.Decimal, len(sorted))
+	for i, entry := range sorted {
+		accounts[i] = entry.account
+		amounts[i] = entry.amount
 	}
+	return accounts, amounts
+}
 
-	// Sort by usage frequency (descending), then alphabetically for stability
-	sort.Slice(matches, func(i, j int) bool {
-		left := frequency[matches[i]]
-		right := frequency[matches[j]]
-		if left == right {
-			return matches[i] < matches[j]
+// recordTags counts the tags used on a transaction and its postings.
+func recordTags(counts map[string]int, tx core.Transaction) {
+	for _, tag := range tx.Tags {
+		counts[tag]++
+	}
+	for _, posting := range tx.Postings {
+		for _, tag := range posting.Tags {
+			counts[tag]++
 		}
-		return left > right
-	})
-	return matches
+	}
 }
 
 // FindAccounts returns account names that start with the given prefix.
-// Results from both base and runtime intelligence are merged and returned
-// in alphabetical order for a unified, consistent experience.
-func (db *IntelligenceDB) FindAccounts(prefix string) []string {
+// Results from both base and runtime intelligence are merged and ranked by
+// time-decayed usage, with accounts the payee is usually entered with boosted.
+// Pass an empty payee to rank by usage alone.
+func (db *IntelligenceDB) FindAccounts(prefix, payee string) []string {
 	seen := make(map[string]bool)
 	var matches []string
 
@@ -303,8 +362,7 @@ func (db *IntelligenceDB) FindAccounts(prefix string) []string {
 		}
 	}
 
-	// Sort results alphabetically for consistent ordering
-	sort.Strings(matches)
+	db.rankAccounts(matches, payee)
 	return matches
 }
 
@@ -335,8 +393,9 @@ func (db *IntelligenceDB) FindTemplates(payee string) []TemplateRecord {
 			for _, rt := range runtimeTemplates {
 				key := templateKey(rt)
 				if existing, found := templateMap[key]; found {
-					// Same template structure exists in both - combine frequencies
+					// Same template structure exists in both - combine frequencies and amounts
 					existing.Frequency += rt.Frequency
+					existing.Amounts = existing.Amounts.merged(rt.Amounts)
 					templateMap[key] = existing
 				} else {
 					// New template structure from runtime
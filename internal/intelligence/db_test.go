@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
 )
 
 func TestNewIntelligenceDB(t *testing.T) {
@@ -15,32 +16,32 @@ func TestNewIntelligenceDB(t *testing.T) {
 			Date:  time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
 			Payee: "Super Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Groceries", Amount: "85.42"},
-				{Account: "Assets:Checking", Amount: "-85.42"},
+				{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("85.42"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-85.42"), "")},
 			},
 		},
 		{
 			Date:  time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC),
 			Payee: "Gas Station",
 			Postings: []core.Posting{
-				{Account: "Expenses:Auto:Gas", Amount: "45.00"},
-				{Account: "Assets:Credit Card", Amount: "-45.00"},
+				{Account: "Expenses:Auto:Gas", Amount: core.NewAmount(decimal.RequireFromString("45.00"), "")},
+				{Account: "Assets:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-45.00"), "")},
 			},
 		},
 		{
 			Date:  time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC),
 			Payee: "Super Grocery Store", // Duplicate payee
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Groceries", Amount: "125.67"},
-				{Account: "Assets:Checking", Amount: "-125.67"},
+				{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("125.67"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-125.67"), "")},
 			},
 		},
 		{
 			Date:  time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC),
 			Payee: "Coffee Shop",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Dining", Amount: "8.50"},
-				{Account: "Assets:Checking", Amount: "-8.50"},
+				{Account: "Expenses:Food:Dining", Amount: core.NewAmount(decimal.RequireFromString("8.50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-8.50"), "")},
 			},
 		},
 	}
@@ -80,16 +81,16 @@ func TestNewIntelligenceDB(t *testing.T) {
 
 }
 
-func TestFindPayees(t *testing.T) {
+func TestMatchPayees(t *testing.T) {
 	// Create mock transactions
 	transactions := []core.Transaction{
-		{Payee: "City Hardware", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-		{Payee: "City Hardware", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "2"}, {Account: "Income:Misc", Amount: "-2"}}},
-		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "2"}, {Account: "Income:Misc", Amount: "-2"}}},
-		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "3"}, {Account: "Income:Misc", Amount: "-3"}}},
-		{Payee: "Coffee Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-		{Payee: "Gas Station", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
+		{Payee: "City Hardware", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("1"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-1"), "")}}},
+		{Payee: "City Hardware", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("2"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-2"), "")}}},
+		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("1"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-1"), "")}}},
+		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("2"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-2"), "")}}},
+		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("3"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-3"), "")}}},
+		{Payee: "Coffee Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("1"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-1"), "")}}},
+		{Payee: "Gas Station", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("1"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-1"), "")}}},
 	}
 
 	result := core.ParseResult{Transactions: transactions}
@@ -101,7 +102,6 @@ func TestFindPayees(t *testing.T) {
 		t.Fatalf("expected no build issues, got %d: %v", len(report.Issues), report.Issues)
 	}
 
-	// Test prefix matching
 	tests := []struct {
 		prefix   string
 		expected []string
@@ -133,7 +133,7 @@ func TestFindPayees(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := db.FindPayees(test.prefix)
+		result := matchTexts(db.MatchPayees(test.prefix))
 		if len(result) != len(test.expected) {
 			t.Errorf("For prefix '%s': expected %d results, got %d", test.prefix, len(test.expected), len(result))
 			continue
@@ -146,13 +146,13 @@ func TestFindPayees(t *testing.T) {
 	}
 }
 
-func TestFindPayeesRanksByCombinedFrequency(t *testing.T) {
+func TestMatchPayeesRanksByCombinedFrequency(t *testing.T) {
 	base := []core.Transaction{
-		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "2"}, {Account: "Income:Misc", Amount: "-2"}}},
-		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "3"}, {Account: "Income:Misc", Amount: "-3"}}},
-		{Payee: "Beta Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "4"}, {Account: "Income:Misc", Amount: "-4"}}},
-		{Payee: "Beta Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "5"}, {Account: "Income:Misc", Amount: "-5"}}},
+		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("1"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-1"), "")}}},
+		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("2"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-2"), "")}}},
+		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("3"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-3"), "")}}},
+		{Payee: "Beta Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("4"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-4"), "")}}},
+		{Payee: "Beta Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("5"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-5"), "")}}},
 	}
 
 	db, report, err := NewIntelligenceDB(core.ParseResult{Transactions: base})
@@ -164,22 +164,22 @@ func TestFindPayeesRanksByCombinedFrequency(t *testing.T) {
 	}
 
 	runtimeBatch := []core.Transaction{
-		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "6"}, {Account: "Income:Misc", Amount: "-6"}}},
-		{Payee: "Charlie Cafe", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "7"}, {Account: "Income:Misc", Amount: "-7"}}},
-		{Payee: "Charlie Cafe", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "8"}, {Account: "Income:Misc", Amount: "-8"}}},
-		{Payee: "Charlie Cafe", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "9"}, {Account: "Income:Misc", Amount: "-9"}}},
+		{Payee: "Alpha Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("6"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-6"), "")}}},
+		{Payee: "Charlie Cafe", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("7"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-7"), "")}}},
+		{Payee: "Charlie Cafe", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("8"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-8"), "")}}},
+		{Payee: "Charlie Cafe", Postings: []core.Posting{{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("9"), "")}, {Account: "Income:Misc", Amount: core.NewAmount(decimal.RequireFromString("-9"), "")}}},
 	}
 	db.Runtime.BuildFromBatch(runtimeBatch)
 
-	results := db.FindPayees("")
+	results := matchTexts(db.MatchPayees(""))
 	expected := []string{"Alpha Shop", "Charlie Cafe", "Beta Shop"}
 	if !reflect.DeepEqual(results, expected) {
 		t.Fatalf("expected %v, got %v", expected, results)
 	}
 
-	alphaPrefix := db.FindPayees("aLp")
+	alphaPrefix := matchTexts(db.MatchPayees("aLp"))
 	if len(alphaPrefix) != 1 || alphaPrefix[0] != "Alpha Shop" {
-		t.Fatalf("expected case-insensitive prefix to return Alpha Shop, got %v", alphaPrefix)
+		t.Fatalf("expected case-insensitive query to return Alpha Shop, got %v", alphaPrefix)
 	}
 }
 
@@ -189,22 +189,22 @@ func TestFindAccounts(t *testing.T) {
 		{
 			Payee: "Super Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Groceries", Amount: "85.42"},
-				{Account: "Assets:Checking", Amount: "-85.42"},
+				{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("85.42"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-85.42"), "")},
 			},
 		},
 		{
 			Payee: "Gas Station",
 			Postings: []core.Posting{
-				{Account: "Expenses:Auto:Gas", Amount: "45.00"},
-				{Account: "Assets:Credit Card", Amount: "-45.00"},
+				{Account: "Expenses:Auto:Gas", Amount: core.NewAmount(decimal.RequireFromString("45.00"), "")},
+				{Account: "Assets:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-45.00"), "")},
 			},
 		},
 		{
 			Payee: "Coffee Shop",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Dining", Amount: "8.50"},
-				{Account: "Assets:Checking", Amount: "-8.50"},
+				{Account: "Expenses:Food:Dining", Amount: core.NewAmount(decimal.RequireFromString("8.50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-8.50"), "")},
 			},
 		},
 	}
@@ -242,7 +242,7 @@ func TestFindAccounts(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		result := db.FindAccounts(test.prefix)
+		result := db.FindAccounts(test.prefix, "")
 		if len(result) != len(test.expected) {
 			t.Errorf("For account prefix '%s': expected %d results, got %d", test.prefix, len(test.expected), len(result))
 			continue
@@ -268,9 +268,9 @@ func TestNewIntelligenceDBWithElidedPosting(t *testing.T) {
 			Date:  time.Date(2024, 8, 15, 0, 0, 0, 0, time.UTC),
 			Payee: "City Market",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Groceries", Amount: ""},
-				{Account: "Expenses:Food:Alcohol", Amount: "14.10"},
-				{Account: "Liabilities:Apple Card", Amount: "-91.41"},
+				{Account: "Expenses:Food:Groceries"},
+				{Account: "Expenses:Food:Alcohol", Amount: core.NewAmount(decimal.RequireFromString("14.10"), "")},
+				{Account: "Liabilities:Apple Card", Amount: core.NewAmount(decimal.RequireFromString("-91.41"), "")},
 			},
 		},
 	}
@@ -311,30 +311,30 @@ func TestFindTemplates(t *testing.T) {
 		{
 			Payee: "City Market",
 			Postings: []core.Posting{
-				{Account: "Assets:Checking", Amount: "-85.42"},
-				{Account: "Expenses:Groceries", Amount: "85.42"},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-85.42"), "")},
+				{Account: "Expenses:Groceries", Amount: core.NewAmount(decimal.RequireFromString("85.42"), "")},
 			},
 		},
 		{
 			Payee: "City Market",
 			Postings: []core.Posting{
-				{Account: "Assets:Checking", Amount: "-125.67"},
-				{Account: "Expenses:Groceries", Amount: "125.67"},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-125.67"), "")},
+				{Account: "Expenses:Groceries", Amount: core.NewAmount(decimal.RequireFromString("125.67"), "")},
 			},
 		},
 		{
 			Payee: "City Market",
 			Postings: []core.Posting{
-				{Account: "Assets:Credit Card", Amount: "-45.00"},
-				{Account: "Expenses:Alcohol", Amount: "25.00"},
-				{Account: "Expenses:Groceries", Amount: "20.00"},
+				{Account: "Assets:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-45.00"), "")},
+				{Account: "Expenses:Alcohol", Amount: core.NewAmount(decimal.RequireFromString("25.00"), "")},
+				{Account: "Expenses:Groceries", Amount: core.NewAmount(decimal.RequireFromString("20.00"), "")},
 			},
 		},
 		{
 			Payee: "Gas Station",
 			Postings: []core.Posting{
-				{Account: "Assets:Credit Card", Amount: "-40.00"},
-				{Account: "Expenses:Auto:Gas", Amount: "40.00"},
+				{Account: "Assets:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-40.00"), "")},
+				{Account: "Expenses:Auto:Gas", Amount: core.NewAmount(decimal.RequireFromString("40.00"), "")},
 			},
 		},
 	}
@@ -402,9 +402,9 @@ func TestTemplatesIncludeElidedAmounts(t *testing.T) {
 		{
 			Payee: "City Market",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Groceries", Amount: "125.67"},
-				{Account: "Expenses:Food:Alcohol", Amount: "19.99"},
-				{Account: "Assets:Checking", Amount: ""},
+				{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("125.67"), "")},
+				{Account: "Expenses:Food:Alcohol", Amount: core.NewAmount(decimal.RequireFromString("19.99"), "")},
+				{Account: "Assets:Checking"},
 			},
 		},
 	}
@@ -435,6 +435,42 @@ func TestTemplatesIncludeElidedAmounts(t *testing.T) {
 	}
 }
 
+func TestElidedAmountBalancesAtCost(t *testing.T) {
+	transactions := []core.Transaction{
+		{
+			Payee: "Broker",
+			Postings: []core.Posting{
+				{
+					Account: "Assets:Brokerage",
+					Amount:  core.NewAmount(decimal.RequireFromString("10"), "AAPL"),
+					Price:   &core.Price{Amount: *core.NewAmount(decimal.RequireFromString("150.00"), "$")},
+				},
+				{Account: "Expenses:Fees", Amount: core.NewAmount(decimal.RequireFromString("4.95"), "")},
+				{Account: "Assets:Checking"},
+			},
+		},
+	}
+
+	db, report, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no build issues, got %d: %v", len(report.Issues), report.Issues)
+	}
+
+	templates := db.FindTemplates("Broker")
+	if len(templates) != 1 {
+		t.Fatalf("Expected 1 template, got %d", len(templates))
+	}
+	if !equalSlices(templates[0].CreditAccounts, []string{"Assets:Checking"}) {
+		t.Fatalf("expected checking to take the cost, got %v", templates[0].CreditAccounts)
+	}
+	if _, ok := templates[0].Amounts.Predicted(); ok {
+		t.Fatalf("expected no amount prediction for priced postings")
+	}
+}
+
 func equalSlices(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -458,8 +494,8 @@ func TestMergedTemplateFrequency(t *testing.T) {
 			Date:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
 			Payee: "Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50"},
-				{Account: "Assets:Checking", Amount: "-50"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 	}
@@ -484,16 +520,16 @@ func TestMergedTemplateFrequency(t *testing.T) {
 			Date:  time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
 			Payee: "Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "75"},
-				{Account: "Assets:Checking", Amount: "-75"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("75"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-75"), "")},
 			},
 		},
 		{
 			Date:  time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC),
 			Payee: "Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "80"},
-				{Account: "Assets:Checking", Amount: "-80"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("80"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-80"), "")},
 			},
 		},
 	}
@@ -524,8 +560,8 @@ func TestMergedTemplatesWithNewPattern(t *testing.T) {
 			Date:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
 			Payee: "Test Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:A", Amount: "50"},
-				{Account: "Assets:Checking", Amount: "-50"},
+				{Account: "Expenses:A", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 	}
@@ -550,8 +586,8 @@ func TestMergedTemplatesWithNewPattern(t *testing.T) {
 			Date:  time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
 			Payee: "Test Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:A", Amount: "75"},
-				{Account: "Assets:CreditCard", Amount: "-75"},
+				{Account: "Expenses:A", Amount: core.NewAmount(decimal.RequireFromString("75"), "")},
+				{Account: "Assets:CreditCard", Amount: core.NewAmount(decimal.RequireFromString("-75"), "")},
 			},
 		},
 	}
@@ -576,3 +612,173 @@ func TestMergedTemplatesWithNewPattern(t *testing.T) {
 		t.Errorf("Expected second template frequency 1, got %d", templates[1].Frequency)
 	}
 }
+
+func TestBuildReportLocatesParseIssues(t *testing.T) {
+	result := core.ParseResult{Issues: []core.ParseIssue{
+		{File: "2024.ledger", Line: 12, Message: "unrecognized date format"},
+		{Line: 3, Message: "posting missing account name"},
+	}}
+	_, report, err := NewIntelligenceDB(result)
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+
+	expected := []string{
+		"2024.ledger line 12: unrecognized date format",
+		"line 3: posting missing account name",
+	}
+	for i, message := range expected {
+		if report.Issues[i].Message != message {
+			t.Errorf("expected issue %q, got %q", message, report.Issues[i].Message)
+		}
+	}
+}
+
+func TestVirtualPostingsDoNotAffectBalance(t *testing.T) {
+	transactions := []core.Transaction{
+		{
+			Payee: "Grocery Store",
+			Postings: []core.Posting{
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("40.00"), "")},
+				{Account: "Assets:Checking"},
+				{Account: "Budget:Food", Kind: core.VirtualPosting, Amount: core.NewAmount(decimal.RequireFromString("-40.00"), "")},
+				{Account: "Budget:Unallocated", Kind: core.VirtualPosting},
+			},
+		},
+	}
+
+	db, report, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no build issues, got %d: %v", len(report.Issues), report.Issues)
+	}
+	if accounts := db.Accounts.Find("Budget:"); !equalSlices(accounts, []string{"Budget:Food", "Budget:Unallocated"}) {
+		t.Fatalf("expected virtual accounts without brackets, got %v", accounts)
+	}
+
+	templates := db.FindTemplates("Grocery Store")
+	if len(templates) != 1 {
+		t.Fatalf("Expected 1 template, got %d", len(templates))
+	}
+	if !equalSlices(templates[0].CreditAccounts, []string{"(Budget:Food)", "Assets:Checking"}) {
+		t.Fatalf("expected checking to take the real balance, got %v", templates[0].CreditAccounts)
+	}
+}
+
+func TestBalanceAssignmentsUseRunningBalance(t *testing.T) {
+	transactions := []core.Transaction{
+		{
+			Payee: "Opening Balance",
+			Postings: []core.Posting{
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("60.00"), "$")},
+				{Account: "Equity:Opening"},
+			},
+		},
+		{
+			Payee: "Cash Count",
+			Postings: []core.Posting{
+				{Account: "Assets:Cash", Assertion: core.NewAmount(decimal.RequireFromString("45.00"), "$")},
+				{Account: "Expenses:Misc"},
+			},
+		},
+	}
+
+	db, report, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no build issues, got %d: %v", len(report.Issues), report.Issues)
+	}
+
+	templates := db.FindTemplates("Cash Count")
+	if len(templates) != 1 {
+		t.Fatalf("Expected 1 template, got %d", len(templates))
+	}
+	if !equalSlices(templates[0].DebitAccounts, []string{"Expenses:Misc"}) || !equalSlices(templates[0].CreditAccounts, []string{"Assets:Cash"}) {
+		t.Fatalf("expected the assignment to credit cash, got %v / %v", templates[0].DebitAccounts, templates[0].CreditAccounts)
+	}
+}
+
+func TestDeclaredAccountsAndPayeesAreSuggested(t *testing.T) {
+	result := core.ParseResult{
+		Transactions: []core.Transaction{
+			{
+				Payee: "Grocery Store",
+				Postings: []core.Posting{
+					{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("40.00"), "")},
+					{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-40.00"), "")},
+				},
+			},
+		},
+		Accounts: []string{"Expenses:Food", "Expenses:Furniture"},
+		Payees:   []string{"Costco", "Grocery Store"},
+	}
+
+	db, _, err := NewIntelligenceDB(result)
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if accounts := db.Accounts.Find("Expenses:F"); !equalSlices(accounts, []string{"Expenses:Food", "Expenses:Furniture"}) {
+		t.Fatalf("expected the declared account to be suggested, got %v", accounts)
+	}
+	if usage := db.Accounts.Usage("Expenses:Furniture"); usage.Count != 0 {
+		t.Fatalf("expected a declared account to have no uses, got %d", usage.Count)
+	}
+	if payees := matchTexts(db.MatchPayees("Costco")); !equalSlices(payees, []string{"Costco"}) {
+		t.Fatalf("expected the declared payee to be suggested, got %v", payees)
+	}
+	if db.Payees["Grocery Store"] != 1 {
+		t.Fatalf("expected declaring a used payee to keep its count, got %d", db.Payees["Grocery Store"])
+	}
+	if !db.Declared["Expenses:Furniture"] || db.Declared["Assets:Checking"] {
+		t.Fatalf("unexpected declared accounts: %v", db.Declared)
+	}
+}
+
+func TestBucketBalancesTransactionsWithoutElidedAmount(t *testing.T) {
+	transactions := []core.Transaction{
+		{
+			Payee:  "Coffee Shop",
+			Bucket: "Assets:Cash",
+			Postings: []core.Posting{
+				{Account: "Expenses:Coffee", Amount: core.NewAmount(decimal.RequireFromString("4.00"), "")},
+			},
+		},
+		{
+			Payee:  "Market",
+			Bucket: "Assets:Cash",
+			Postings: []core.Posting{
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("10.00"), "")},
+				{Account: "Assets:Checking"},
+			},
+		},
+	}
+
+	db, report, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if len(report.Issues) != 0 {
+		t.Fatalf("expected no build issues, got %d: %v", len(report.Issues), report.Issues)
+	}
+
+	templates := db.FindTemplates("Coffee Shop")
+	if len(templates) != 1 || !equalSlices(templates[0].CreditAccounts, []string{"Assets:Cash"}) {
+		t.Fatalf("expected the bucket to take the credit, got %+v", templates)
+	}
+	if amount, ok := templates[0].Amounts.Predicted(); !ok || !amount.Equal(core.NewAmount(decimal.RequireFromString("4.00"), "").Quantity) {
+		t.Fatalf("expected a predicted amount of 4.00, got %v", amount)
+	}
+
+	// The bucket only steps in when there's no elided amount to take the difference
+	templates = db.FindTemplates("Market")
+	if len(templates) != 1 || !equalSlices(templates[0].CreditAccounts, []string{"Assets:Checking"}) {
+		t.Fatalf("expected the elided posting to take the credit, got %+v", templates)
+	}
+	if len(db.Accounts.Find("Assets:Cash")) != 1 {
+		t.Fatalf("expected the bucket account to be suggested")
+	}
+}
@@ -0,0 +1,70 @@
+package intelligence
+
+import (
+	"strings"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+// DuplicateWindow is how far apart two entries may be dated and still be treated as the same transaction.
+const DuplicateWindow = 3 * 24 * time.Hour
+
+// FindDuplicate returns the first candidate that looks like the same transaction
+// as tx: the same payee (ignoring case), the same absolute amount, and a date
+// within DuplicateWindow.
+func FindDuplicate(tx core.Transaction, candidates []core.Transaction) (core.Transaction, bool) {
+	payee := strings.TrimSpace(tx.Payee)
+	amount := TransactionAmount(tx)
+	if payee == "" || amount.Quantity.IsZero() {
+		return core.Transaction{}, false
+	}
+
+	for _, candidate := range candidates {
+		if !strings.EqualFold(strings.TrimSpace(candidate.Payee), payee) {
+			continue
+		}
+		gap := tx.Date.Sub(candidate.Date)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap > DuplicateWindow {
+			continue
+		}
+		if TransactionAmount(candidate).Equal(amount) {
+			return candidate, true
+		}
+	}
+	return core.Transaction{}, false
+}
+
+// TransactionAmount returns the absolute size of a transaction: the sum of its
+// positive postings, or of its negative postings when it has no positive ones
+// (as with imported drafts that only carry the funding posting). Only real
+// postings in the commodity of the first amount are counted.
+func TransactionAmount(tx core.Transaction) core.Amount {
+	positive := decimal.Zero
+	negative := decimal.Zero
+	commodity := ""
+	for _, posting := range tx.Postings {
+		if posting.Amount == nil || posting.Kind != core.RealPosting {
+			continue
+		}
+		if commodity == "" {
+			commodity = posting.Amount.Unit()
+		}
+		if posting.Amount.Unit() != commodity {
+			continue
+		}
+		if amount := posting.Amount.Quantity; amount.IsPositive() {
+			positive = positive.Add(amount)
+		} else {
+			negative = negative.Add(amount)
+		}
+	}
+	if positive.IsZero() {
+		return *core.NewAmount(negative.Abs(), commodity)
+	}
+	return *core.NewAmount(positive, commodity)
+}
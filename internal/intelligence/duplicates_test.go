@@ -0,0 +1,76 @@
+package intelligence
+
+import (
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func TestFindDuplicate(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	existing := []core.Transaction{
+		{
+			Date:  day(10),
+			Payee: "Blue Bottle",
+			Postings: []core.Posting{
+				{Account: "Expenses:Food:Coffee", Amount: core.NewAmount(decimal.RequireFromString("4.50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-4.50"), "")},
+			},
+		},
+		{
+			// Imported drafts only carry the funding posting
+			Date:        day(20),
+			Payee:       "ACME PAYROLL",
+			Draft:       true,
+			Description: "ACME PAYROLL",
+			Postings:    []core.Posting{{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("1250.00"), "")}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		tx      core.Transaction
+		matches bool
+	}{
+		{
+			name: "same entry a few days later",
+			tx: core.Transaction{Date: day(13), Payee: "blue bottle", Postings: []core.Posting{
+				{Account: "Expenses:Food:Coffee", Amount: core.NewAmount(decimal.RequireFromString("4.50"), "")},
+				{Account: "Liabilities:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-4.50"), "")},
+			}},
+			matches: true,
+		},
+		{
+			name: "outside the window",
+			tx: core.Transaction{Date: day(14), Payee: "Blue Bottle", Postings: []core.Posting{
+				{Account: "Expenses:Food:Coffee", Amount: core.NewAmount(decimal.RequireFromString("4.50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-4.50"), "")},
+			}},
+		},
+		{
+			name: "different amount",
+			tx: core.Transaction{Date: day(10), Payee: "Blue Bottle", Postings: []core.Posting{
+				{Account: "Expenses:Food:Coffee", Amount: core.NewAmount(decimal.RequireFromString("5.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-5.00"), "")},
+			}},
+		},
+		{
+			name: "against a draft",
+			tx: core.Transaction{Date: day(19), Payee: "ACME Payroll", Postings: []core.Posting{
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("1250.00"), "")},
+				{Account: "Income:Salary", Amount: core.NewAmount(decimal.RequireFromString("-1250.00"), "")},
+			}},
+			matches: true,
+		},
+	}
+
+	for _, tc := range tests {
+		if _, ok := FindDuplicate(tc.tx, existing); ok != tc.matches {
+			t.Errorf("%s: expected match=%v, got %v", tc.name, tc.matches, ok)
+		}
+	}
+}
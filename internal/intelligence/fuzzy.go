@@ -0,0 +1,231 @@
+package intelligence
+
+import (
+	"math"
+	"sort"
+	"unicode"
+)
+
+// Scoring weights for fuzzy matching, loosely modelled on fzf.
+const (
+	scoreMatch       = 16 // every matched character
+	scoreConsecutive = 8  // matched character directly follows the previous match
+	scoreBoundary    = 10 // matched character starts a word or account segment
+	scoreStart       = 12 // match begins at the very first character
+	penaltyGap       = 1  // each skipped character between matches
+	maxGapPenalty    = 6  // cap on the penalty for a single gap
+
+	// frequencyWeight scales how much usage counts lift a match when ranking
+	frequencyWeight = 6.0
+)
+
+// Match is a search result along with the rune positions in Text that matched the query.
+type Match struct {
+	Text      string
+	Positions []int
+	Score     int
+}
+
+// FuzzyMatch reports whether every character of query appears in text in
+// order (ignoring case) and scores the best such alignment. Consecutive runs,
+// word boundaries and matches at the start score higher; gaps cost a little.
+// An empty query matches everything with a zero score.
+func FuzzyMatch(query, text string) (Match, bool) {
+	q := foldRunes(query)
+	t := []rune(text)
+	lower := foldRunes(text)
+	if len(q) == 0 {
+		return Match{Text: text}, true
+	}
+	if len(q) > len(t) {
+		return Match{Text: text}, false
+	}
+
+	// score[i][j] is the best score of matching q[:i+1] with q[i] at t[j], and
+	// from[i][j] is where q[i-1] is matched in that alignment. Gaps of
+	// farGap or more all cost maxGapPenalty, so only the best of them counts.
+	const none = math.MinInt
+	farGap := maxGapPenalty / penaltyGap
+	score := make([][]int, len(q))
+	from := make([][]int, len(q))
+	for i := range q {
+		score[i] = make([]int, len(t))
+		from[i] = make([]int, len(t))
+		far, farAt := none, -1
+		for j := range t {
+			score[i][j] = none
+			if k := j - 1 - farGap; i > 0 && k >= 0 && score[i-1][k] > far {
+				far, farAt = score[i-1][k], k
+			}
+			if lower[j] != q[i] {
+				continue
+			}
+			bonus := scoreMatch
+			if isBoundary(t, j) {
+				bonus += scoreBoundary
+			}
+			if i == 0 {
+				if j == 0 {
+					bonus += scoreStart
+				}
+				score[i][j] = bonus
+				continue
+			}
+			best, bestAt := none, -1
+			if farAt >= 0 {
+				best, bestAt = far-maxGapPenalty, farAt
+			}
+			for k := max(j-farGap, 0); k < j; k++ {
+				if score[i-1][k] == none {
+					continue
+				}
+				candidate := score[i-1][k] - (j-k-1)*penaltyGap
+				if k == j-1 {
+					candidate = score[i-1][k] + scoreConsecutive
+				}
+				if candidate > best {
+					best, bestAt = candidate, k
+				}
+			}
+			if bestAt >= 0 {
+				score[i][j] = best + bonus
+				from[i][j] = bestAt
+			}
+		}
+	}
+
+	last := len(q) - 1
+	end := -1
+	for j := range t {
+		if score[last][j] != none && (end < 0 || score[last][j] > score[last][end]) {
+			end = j
+		}
+	}
+	if end < 0 {
+		return Match{Text: text}, false
+	}
+	positions := make([]int, len(q))
+	positions[last] = end
+	for i := last; i > 0; i-- {
+		positions[i-1] = from[i][positions[i]]
+	}
+	return Match{Text: text, Positions: positions, Score: score[last][end]}, true
+}
+
+// foldRunes lowercases s rune by rune, so positions in the result are the
+// rune positions in s even where strings.ToLower would change the length.
+func foldRunes(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// isBoundary reports whether the rune at pos starts a word or account segment.
+func isBoundary(text []rune, pos int) bool {
+	if pos == 0 {
+		return true
+	}
+	prev, current := text[pos-1], text[pos]
+	switch prev {
+	case ' ', ':', '-', '_', '/', '.', '&', '*', '#':
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(current)
+}
+
+// MatchPayees fuzzy-matches payees from both base and runtime intelligence.
+// Results are ranked by match score blended with usage frequency.
+func (db *IntelligenceDB) MatchPayees(query string) []Match {
+	frequency := make(map[string]int)
+	if db.Runtime != nil {
+		for payee, count := range db.Runtime.Payees {
+			frequency[payee] += count
+		}
+	}
+	for payee, count := range db.Payees {
+		frequency[payee] += count
+	}
+
+	var matches []Match
+	for payee := range frequency {
+		if match, ok := FuzzyMatch(query, payee); ok {
+			matches = append(matches, match)
+		}
+	}
+	rankMatches(matches, func(text string) float64 {
+		return frequencyWeight * math.Log2(1+float64(frequency[text]))
+	})
+	return matches
+}
+
+// MatchTags fuzzy-matches tags from both base and runtime intelligence.
+// Results are ranked by match score blended with usage frequency.
+func (db *IntelligenceDB) MatchTags(query string) []Match {
+	frequency := make(map[string]int)
+	if db.Runtime != nil {
+		for tag, count := range db.Runtime.Tags {
+			frequency[tag] += count
+		}
+	}
+	for tag, count := range db.Tags {
+		frequency[tag] += count
+	}
+
+	var matches []Match
+	for tag := range frequency {
+		if match, ok := FuzzyMatch(query, tag); ok {
+			matches = append(matches, match)
+		}
+	}
+	rankMatches(matches, func(text string) float64 {
+		return frequencyWeight * math.Log2(1+float64(frequency[text]))
+	})
+	return matches
+}
+
+// MatchAccounts fuzzy-matches full account paths from both base and runtime intelligence.
+// Results are ranked by match score blended with the account's usage score for payee.
+func (db *IntelligenceDB) MatchAccounts(query, payee string) []Match {
+	seen := make(map[string]bool)
+	var accounts []string
+	var matches []Match
+	collect := func(candidates []string) {
+		for _, account := range candidates {
+			if seen[account] {
+				continue
+			}
+			seen[account] = true
+			if match, ok := FuzzyMatch(query, account); ok {
+				accounts = append(accounts, account)
+				matches = append(matches, match)
+			}
+		}
+	}
+	if db.Runtime != nil {
+		collect(db.Runtime.Accounts.Find(""))
+	}
+	collect(db.Accounts.Find(""))
+
+	scores := db.accountScores(accounts, payee)
+	rankMatches(matches, func(text string) float64 {
+		return frequencyWeight * math.Log2(1+scores[text])
+	})
+	return matches
+}
+
+// rankMatches sorts matches by score plus a usage boost (descending), then alphabetically.
+func rankMatches(matches []Match, boost func(text string) float64) {
+	rank := make(map[string]float64, len(matches))
+	for _, match := range matches {
+		rank[match.Text] = float64(match.Score) + boost(match.Text)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		left, right := rank[matches[i].Text], rank[matches[j].Text]
+		if left == right {
+			return matches[i].Text < matches[j].Text
+		}
+		return left > right
+	})
+}
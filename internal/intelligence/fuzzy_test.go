@@ -0,0 +1,157 @@
+package intelligence
+
+import (
+	"reflect"
+	"testing"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		query     string
+		text      string
+		matches   bool
+		positions []int
+	}{
+		{query: "grocery", text: "Super Grocery Store", matches: true, positions: []int{6, 7, 8, 9, 10, 11, 12}},
+		{query: "SGS", text: "Super Grocery Store", matches: true, positions: []int{0, 6, 14}},
+		{query: "groc", text: "Expenses:Food:Groceries", matches: true, positions: []int{14, 15, 16, 17}},
+		{query: "efg", text: "Expenses:Food:Groceries", matches: true, positions: []int{0, 9, 14}},
+		{query: "xyz", text: "Super Grocery Store", matches: false},
+		{query: "stores", text: "Super Grocery Store", matches: false},
+		// The consecutive match at the end beats the greedy one after the first b
+		{query: "abc", text: "ab_xc_bc", matches: true, positions: []int{0, 6, 7}},
+		// Lowercasing İ to i shortens the string by a byte; positions stay in runes
+		{query: "kebab", text: "İstanbul Kebab", matches: true, positions: []int{9, 10, 11, 12, 13}},
+		{query: "ist", text: "İstanbul Kebab", matches: true, positions: []int{0, 1, 2}},
+	}
+
+	for _, tc := range tests {
+		match, ok := FuzzyMatch(tc.query, tc.text)
+		if ok != tc.matches {
+			t.Errorf("%q in %q: expected match=%v, got %v", tc.query, tc.text, tc.matches, ok)
+			continue
+		}
+		if ok && !reflect.DeepEqual(match.Positions, tc.positions) {
+			t.Errorf("%q in %q: expected positions %v, got %v", tc.query, tc.text, tc.positions, match.Positions)
+		}
+	}
+}
+
+func TestFuzzyMatchPrefersBoundaries(t *testing.T) {
+	boundary, _ := FuzzyMatch("fg", "Expenses:Food:Groceries")
+	inner, _ := FuzzyMatch("fg", "Offgrid Supplies")
+	if boundary.Score <= inner.Score {
+		t.Fatalf("expected segment starts to score higher: %d <= %d", boundary.Score, inner.Score)
+	}
+}
+
+func TestMatchPayeesBlendsFrequency(t *testing.T) {
+	tx := func(payee string) core.Transaction {
+		return core.Transaction{Payee: payee, Postings: []core.Posting{
+			{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("1"), "")},
+			{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-1"), "")},
+		}}
+	}
+	base := []core.Transaction{
+		tx("Super Grocery Store"),
+		tx("Grocery Outlet"),
+		tx("Corner Store"), tx("Corner Store"), tx("Corner Store"), tx("Corner Store"),
+	}
+	db, _, err := NewIntelligenceDB(core.ParseResult{Transactions: base})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+
+	results := matchTexts(db.MatchPayees("grocery"))
+	expected := []string{"Grocery Outlet", "Super Grocery Store"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	// Equally good matches are ordered by how often the payee is used
+	results = matchTexts(db.MatchPayees("st"))
+	expected = []string{"Corner Store", "Super Grocery Store"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	batch := []core.Transaction{}
+	for range 6 {
+		batch = append(batch, tx("Super Grocery Store"))
+	}
+	db.Runtime.BuildFromBatch(batch)
+	results = matchTexts(db.MatchPayees("st"))
+	expected = []string{"Super Grocery Store", "Corner Store"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected runtime usage to lift Super Grocery Store, got %v", results)
+	}
+}
+
+func TestMatchTagsCountsTransactionAndPostingTags(t *testing.T) {
+	base := []core.Transaction{
+		{Payee: "Airline", Tags: []string{"travel", "work"}, Postings: []core.Posting{
+			{Account: "Expenses:Travel", Amount: core.NewAmount(decimal.RequireFromString("300"), ""), Tags: []string{"reimbursable"}},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-300"), "")},
+		}},
+		{Payee: "Hotel", Tags: []string{"travel"}, Postings: []core.Posting{
+			{Account: "Expenses:Travel", Amount: core.NewAmount(decimal.RequireFromString("120"), "")},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-120"), "")},
+		}},
+	}
+	db, _, err := NewIntelligenceDB(core.ParseResult{Transactions: base})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if db.Tags["travel"] != 2 || db.Tags["reimbursable"] != 1 {
+		t.Fatalf("unexpected tag counts: %v", db.Tags)
+	}
+
+	results := matchTexts(db.MatchTags(""))
+	expected := []string{"travel", "reimbursable", "work"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	results = matchTexts(db.MatchTags("reim"))
+	expected = []string{"reimbursable"}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+}
+
+func TestMatchAccountsFullPaths(t *testing.T) {
+	base := []core.Transaction{
+		{Payee: "Market", Postings: []core.Posting{
+			{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("10"), "")},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-10"), "")},
+		}},
+	}
+	db, _, err := NewIntelligenceDB(core.ParseResult{Transactions: base})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	db.Runtime.BuildFromBatch([]core.Transaction{{Payee: "Shop", Postings: []core.Posting{
+		{Account: "Expenses:Household", Amount: core.NewAmount(decimal.RequireFromString("5"), "")},
+		{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-5"), "")},
+	}}})
+
+	results := matchTexts(db.MatchAccounts("groc", ""))
+	if !reflect.DeepEqual(results, []string{"Expenses:Food:Groceries"}) {
+		t.Fatalf("expected groceries account, got %v", results)
+	}
+	results = matchTexts(db.MatchAccounts("ehous", ""))
+	if !reflect.DeepEqual(results, []string{"Expenses:Household"}) {
+		t.Fatalf("expected runtime account, got %v", results)
+	}
+}
+
+func matchTexts(matches []Match) []string {
+	texts := make([]string, 0, len(matches))
+	for _, match := range matches {
+		texts = append(texts, match.Text)
+	}
+	return texts
+}
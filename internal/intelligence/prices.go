@@ -0,0 +1,123 @@
+package intelligence
+
+import (
+	"sort"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+// pricePair identifies the price of one commodity in another.
+type pricePair struct {
+	commodity string
+	currency  string
+}
+
+// pricePoint is the rate of a commodity pair on one day.
+type pricePoint struct {
+	date time.Time
+	rate decimal.Decimal
+}
+
+// PriceDB is the price history of commodities, keyed by commodity pair and date.
+type PriceDB struct {
+	history map[pricePair][]pricePoint // sorted by date
+}
+
+// NewPriceDB creates a price database from price directives.
+func NewPriceDB(prices []core.MarketPrice) *PriceDB {
+	db := &PriceDB{history: make(map[pricePair][]pricePoint)}
+	for _, price := range prices {
+		db.Add(price)
+	}
+	return db
+}
+
+// Add records a price. A later price for the same pair and day replaces the earlier one.
+func (db *PriceDB) Add(price core.MarketPrice) {
+	pair := pricePair{commodity: price.Commodity, currency: price.Price.Unit()}
+	day := truncateDay(price.Date)
+	points := db.history[pair]
+	i := sort.Search(len(points), func(i int) bool { return !points[i].date.Before(day) })
+	if i < len(points) && points[i].date.Equal(day) {
+		points[i].rate = price.Price.Quantity
+		return
+	}
+	points = append(points, pricePoint{})
+	copy(points[i+1:], points[i:])
+	points[i] = pricePoint{date: day, rate: price.Price.Quantity}
+	db.history[pair] = points
+}
+
+// Rate returns the price of one unit of commodity in currency as of date:
+// the latest price recorded on or before that day. A price recorded the
+// other way round is inverted.
+func (db *PriceDB) Rate(commodity, currency string, date time.Time) (decimal.Decimal, bool) {
+	if commodity == currency {
+		return decimal.NewFromInt(1), true
+	}
+	direct, directOK := db.latest(pricePair{commodity: commodity, currency: currency}, date)
+	inverse, inverseOK := db.latest(pricePair{commodity: currency, currency: commodity}, date)
+	switch {
+	case directOK && (!inverseOK || !inverse.date.After(direct.date)):
+		return direct.rate, true
+	case inverseOK && !inverse.rate.IsZero():
+		return decimal.NewFromInt(1).DivRound(inverse.rate, 8), true
+	}
+	return decimal.Zero, false
+}
+
+// Convert values an amount in currency as of date.
+// Returns false when there is no price for the amount's commodity.
+func (db *PriceDB) Convert(amount core.Amount, currency string, date time.Time) (core.Amount, bool) {
+	if amount.Unit() == currency {
+		return amount, true
+	}
+	rate, ok := db.Rate(amount.Unit(), currency, date)
+	if !ok {
+		return core.Amount{}, false
+	}
+	return *core.NewAmount(amount.Quantity.Mul(rate).Round(2), currency), true
+}
+
+// ConvertPosting values a posting's amount in currency as of date, usually
+// the date of its transaction. Returns false for elided amounts.
+func (db *PriceDB) ConvertPosting(posting core.Posting, currency string, date time.Time) (core.Amount, bool) {
+	if posting.Amount == nil {
+		return core.Amount{}, false
+	}
+	return db.Convert(*posting.Amount, currency, date)
+}
+
+// ConvertBalance values every commodity of a balance in currency as of date and sums them.
+// Returns the commodities that have no price when it can't.
+func (db *PriceDB) ConvertBalance(balance core.Balance, currency string, date time.Time) (core.Amount, []string) {
+	total := decimal.Zero
+	var missing []string
+	for _, commodity := range balance.Commodities() {
+		converted, ok := db.Convert(*core.NewAmount(balance[commodity], commodity), currency, date)
+		if !ok {
+			missing = append(missing, commodity)
+			continue
+		}
+		total = total.Add(converted.Quantity)
+	}
+	return *core.NewAmount(total, currency), missing
+}
+
+// latest returns the last price of a pair on or before date.
+func (db *PriceDB) latest(pair pricePair, date time.Time) (pricePoint, bool) {
+	points := db.history[pair]
+	day := truncateDay(date)
+	i := sort.Search(len(points), func(i int) bool { return points[i].date.After(day) })
+	if i == 0 {
+		return pricePoint{}, false
+	}
+	return points[i-1], true
+}
+
+// truncateDay drops the time of day so prices are compared by date.
+func truncateDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,91 @@
+package intelligence
+
+import (
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func marketPrice(date time.Time, commodity, price, currency string) core.MarketPrice {
+	return core.MarketPrice{Date: date, Commodity: commodity, Price: *core.NewAmount(decimal.RequireFromString(price), currency)}
+}
+
+func TestPriceDBRateAsOfDate(t *testing.T) {
+	jan := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2025, 2, 15, 0, 0, 0, 0, time.UTC)
+	db := NewPriceDB([]core.MarketPrice{
+		marketPrice(feb, "AAPL", "190.00", "$"),
+		marketPrice(jan, "AAPL", "182.31", "$"),
+		marketPrice(jan, "$", "0.80", "EUR"),
+	})
+
+	tests := []struct {
+		commodity string
+		currency  string
+		date      time.Time
+		rate      string
+		ok        bool
+	}{
+		{commodity: "AAPL", currency: "$", date: jan.AddDate(0, 0, -1)},
+		{commodity: "AAPL", currency: "$", date: jan, rate: "182.31", ok: true},
+		{commodity: "AAPL", currency: "$", date: feb.AddDate(0, 0, -1), rate: "182.31", ok: true},
+		{commodity: "AAPL", currency: "$", date: feb.Add(15 * time.Hour), rate: "190", ok: true},
+		{commodity: "EUR", currency: "$", date: feb, rate: "1.25", ok: true},
+		{commodity: "AAPL", currency: "EUR", date: feb},
+		{commodity: "$", currency: "$", date: jan, rate: "1", ok: true},
+	}
+	for _, tc := range tests {
+		rate, ok := db.Rate(tc.commodity, tc.currency, tc.date)
+		if ok != tc.ok {
+			t.Errorf("%s in %s on %s: expected ok=%v, got %v", tc.commodity, tc.currency, tc.date.Format("2006-01-02"), tc.ok, ok)
+			continue
+		}
+		if ok && rate.String() != tc.rate {
+			t.Errorf("%s in %s on %s: expected rate %s, got %s", tc.commodity, tc.currency, tc.date.Format("2006-01-02"), tc.rate, rate)
+		}
+	}
+}
+
+func TestPriceDBConvertsPostingsAndBalances(t *testing.T) {
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	db := NewPriceDB([]core.MarketPrice{
+		marketPrice(date, "AAPL", "150.00", "$"),
+		marketPrice(date, "€", "1.10", "$"),
+	})
+
+	posting := core.Posting{Account: "Assets:Brokerage", Amount: core.NewAmount(decimal.RequireFromString("-4"), "AAPL")}
+	value, ok := db.ConvertPosting(posting, "$", date)
+	if !ok || value.String() != "$-600.00" {
+		t.Fatalf("expected posting worth $-600.00, got %s (%v)", value, ok)
+	}
+	if _, ok := db.ConvertPosting(core.Posting{Account: "Assets:Checking"}, "$", date); ok {
+		t.Fatalf("expected elided posting to have no value")
+	}
+
+	balance := core.Balance{}
+	balance.Add(*core.NewAmount(decimal.RequireFromString("10"), "AAPL"))
+	balance.Add(*core.NewAmount(decimal.RequireFromString("20.00"), "€"))
+	balance.Add(*core.NewAmount(decimal.RequireFromString("5.00"), ""))
+	total, missing := db.ConvertBalance(balance, "$", date)
+	if len(missing) != 0 || total.String() != "$1527.00" {
+		t.Fatalf("expected $1527.00 in total, got %s (missing %v)", total, missing)
+	}
+
+	balance.Add(*core.NewAmount(decimal.RequireFromString("1"), "MSFT"))
+	if _, missing := db.ConvertBalance(balance, "$", date); len(missing) != 1 || missing[0] != "MSFT" {
+		t.Fatalf("expected MSFT to be missing a price, got %v", missing)
+	}
+}
+
+func TestNewIntelligenceDBLoadsPrices(t *testing.T) {
+	date := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	db, _, err := NewIntelligenceDB(core.ParseResult{Prices: []core.MarketPrice{marketPrice(date, "AAPL", "182.31", "$")}})
+	if err != nil {
+		t.Fatalf("Failed to create IntelligenceDB: %v", err)
+	}
+	if rate, ok := db.Prices.Rate("AAPL", "$", date); !ok || rate.String() != "182.31" {
+		t.Fatalf("expected AAPL price from directives, got %s (%v)", rate, ok)
+	}
+}
@@ -0,0 +1,466 @@
+package intelligence
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+// Period is the interval between occurrences of a recurring transaction.
+type Period int
+
+const (
+	PeriodWeekly Period = iota + 1
+	PeriodBiweekly
+	PeriodMonthly
+	PeriodQuarterly
+	PeriodYearly
+)
+
+// periodSpec describes how a period is recognized and how it steps forward.
+type periodSpec struct {
+	period         Period
+	name           string
+	days           int // nominal length in days
+	tolerance      int // how far an interval may stray from days and still count
+	months         int // calendar months per step, 0 for fixed-day periods
+	minOccurrences int
+}
+
+var periodSpecs = []periodSpec{
+	{PeriodWeekly, "weekly", 7, 1, 0, 3},
+	{PeriodBiweekly, "biweekly", 14, 2, 0, 3},
+	{PeriodMonthly, "monthly", 30, 4, 1, 3},
+	{PeriodQuarterly, "quarterly", 91, 7, 3, 3},
+	{PeriodYearly, "yearly", 365, 10, 12, 2},
+}
+
+// String returns the lowercase name of the period.
+func (p Period) String() string {
+	return p.spec().name
+}
+
+func (p Period) spec() periodSpec {
+	for _, spec := range periodSpecs {
+		if spec.period == p {
+			return spec
+		}
+	}
+	return periodSpec{name: "unknown", days: 1}
+}
+
+// Recurrence describes a payee that repeats on a regular schedule.
+type Recurrence struct {
+	Payee       string
+	Period      Period
+	ExpectedDay int             // day of the month for monthly and longer periods, otherwise the weekday
+	Amount      decimal.Decimal // typical total
+	Commodity   string          // commodity of Amount
+	Template    TemplateRecord  // most frequent posting structure for the payee
+	Last        time.Time       // date of the most recent occurrence
+	Occurrences int
+	Budget      []core.Posting // postings of the periodic transaction it comes from; nil when detected from history
+}
+
+// DueTransaction is an expected occurrence of a recurring transaction.
+type DueTransaction struct {
+	Recurrence Recurrence
+	Date       time.Time
+}
+
+// DetectRecurrences finds payees whose transactions repeat on a weekly,
+// biweekly, monthly, quarterly or yearly schedule. Schedules that have
+// lapsed (no occurrence for more than two periods before the latest
+// transaction) are ignored.
+func DetectRecurrences(transactions []core.Transaction, templates map[string][]TemplateRecord) []Recurrence {
+	datesByPayee := make(map[string][]time.Time)
+	latest := time.Time{}
+	for _, tx := range transactions {
+		if tx.Draft || tx.Payee == "" || tx.Date.IsZero() {
+			continue
+		}
+		datesByPayee[tx.Payee] = append(datesByPayee[tx.Payee], tx.Date)
+		if tx.Date.After(latest) {
+			latest = tx.Date
+		}
+	}
+
+	var recurrences []Recurrence
+	for payee, dates := range datesByPayee {
+		records := templates[payee]
+		if len(records) == 0 {
+			continue
+		}
+		amount, ok := records[0].Amounts.Predicted()
+		if !ok {
+			continue
+		}
+
+		dates = uniqueSortedDates(dates)
+		spec, ok := detectPeriod(dates)
+		if !ok {
+			continue
+		}
+		last := dates[len(dates)-1]
+		if latest.Sub(last) > time.Duration(2*spec.days+spec.tolerance)*24*time.Hour {
+			continue
+		}
+
+		recurrences = append(recurrences, Recurrence{
+			Payee:       payee,
+			Period:      spec.period,
+			ExpectedDay: expectedDay(dates, spec),
+			Amount:      amount,
+			Commodity:   records[0].Amounts.Commodity,
+			Template:    records[0],
+			Last:        last,
+			Occurrences: len(dates),
+		})
+	}
+
+	sort.Slice(recurrences, func(i, j int) bool {
+		return recurrences[i].Payee < recurrences[j].Payee
+	})
+	return recurrences
+}
+
+// BudgetRecurrences turns periodic transactions into recurrences, scheduled up
+// to latest. A periodic transaction without a description is named after its
+// first posting's account. Periods that don't fit a recurrence are reported.
+func BudgetRecurrences(periodic []core.PeriodicTransaction, latest time.Time) ([]Recurrence, []string) {
+	var recurrences []Recurrence
+	var issues []string
+	for _, budget := range periodic {
+		period, ok := budgetPeriod(budget.Interval)
+		if !ok {
+			issues = append(issues, fmt.Sprintf("periodic transaction %q can't be used for recurring suggestions", budget.Period))
+			continue
+		}
+		payee := budget.Description
+		if payee == "" && len(budget.Postings) > 0 {
+			payee = budget.Postings[0].Account
+		}
+		amount, commodity := budgetAmount(budget.Postings)
+		if payee == "" || amount.IsZero() {
+			continue
+		}
+
+		recurrence := Recurrence{
+			Payee:     payee,
+			Period:    period,
+			Amount:    amount,
+			Commodity: commodity,
+			Budget:    budget.Postings,
+		}
+		recurrence.schedule(budgetStart(budget, period, latest), latest)
+		recurrences = append(recurrences, recurrence)
+	}
+	return recurrences, issues
+}
+
+// budgetPeriod returns the recurrence period for a periodic transaction's interval.
+func budgetPeriod(interval core.Interval) (Period, bool) {
+	for _, spec := range periodSpecs {
+		if spec.months > 0 && interval == (core.Interval{Months: spec.months}) {
+			return spec.period, true
+		}
+		if spec.months == 0 && interval == (core.Interval{Days: spec.days}) {
+			return spec.period, true
+		}
+	}
+	return 0, false
+}
+
+// budgetAmount totals the positive amounts of the balancing postings in the
+// commodity of the first one.
+func budgetAmount(postings []core.Posting) (decimal.Decimal, string) {
+	total := decimal.Zero
+	commodity := ""
+	for _, posting := range postings {
+		if posting.Amount == nil || !posting.Balances() || !posting.Amount.Quantity.IsPositive() {
+			continue
+		}
+		if commodity == "" {
+			commodity = posting.Amount.Unit()
+		}
+		if posting.Amount.Unit() == commodity {
+			total = total.Add(posting.Amount.Quantity)
+		}
+	}
+	return total, commodity
+}
+
+// budgetStart returns the first occurrence of a periodic transaction: its
+// "from" date, or else the start of latest's year (the first Monday of it for
+// weekly periods).
+func budgetStart(budget core.PeriodicTransaction, period Period, latest time.Time) time.Time {
+	if !budget.Start.IsZero() {
+		return budget.Start
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	start := time.Date(latest.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	if period.spec().months == 0 {
+		for start.Weekday() != time.Monday {
+			start = start.AddDate(0, 0, 1)
+		}
+	}
+	return start
+}
+
+// schedule sets the recurrence to occur first on start, with Last the latest
+// occurrence on or before latest (or the step before start when none is).
+func (r *Recurrence) schedule(start, latest time.Time) {
+	spec := r.Period.spec()
+	if spec.months == 0 {
+		r.ExpectedDay = int(start.Weekday())
+		r.Last = start.AddDate(0, 0, -spec.days)
+	} else {
+		r.ExpectedDay = start.Day()
+		r.Last = time.Date(start.Year(), start.Month()-time.Month(spec.months), 1, 0, 0, 0, 0, start.Location())
+	}
+	for k := 0; k < 10000; k++ {
+		next := r.occurrence(1)
+		if next.After(latest) {
+			break
+		}
+		r.Last = next
+		r.Occurrences++
+	}
+}
+
+// mergeRecurrences adds budgets to the detected recurrences, replacing any
+// detected for the same payee.
+func mergeRecurrences(detected, budgets []Recurrence) []Recurrence {
+	merged := append([]Recurrence(nil), budgets...)
+	for _, recurrence := range detected {
+		replaced := false
+		for _, budget := range budgets {
+			replaced = replaced || strings.EqualFold(budget.Payee, recurrence.Payee)
+		}
+		if !replaced {
+			merged = append(merged, recurrence)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Payee < merged[j].Payee
+	})
+	return merged
+}
+
+// latestDate returns the date of the most recent transaction, or zero when there are none.
+func latestDate(transactions []core.Transaction) time.Time {
+	latest := time.Time{}
+	for _, tx := range transactions {
+		if tx.Date.After(latest) {
+			latest = tx.Date
+		}
+	}
+	return latest
+}
+
+// detectPeriod matches the intervals between dates against the known periods.
+// The median interval picks the period and most intervals must agree with it.
+func detectPeriod(dates []time.Time) (periodSpec, bool) {
+	if len(dates) < 2 {
+		return periodSpec{}, false
+	}
+	intervals := make([]int, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		intervals = append(intervals, int(dates[i].Sub(dates[i-1]).Hours()/24))
+	}
+	sorted := append([]int(nil), intervals...)
+	sort.Ints(sorted)
+	median := sorted[len(sorted)/2]
+
+	for _, spec := range periodSpecs {
+		if abs(median-spec.days) > spec.tolerance || len(dates) < spec.minOccurrences {
+			continue
+		}
+		fitting := 0
+		for _, interval := range intervals {
+			if abs(interval-spec.days) <= spec.tolerance {
+				fitting++
+			}
+		}
+		if fitting*4 >= len(intervals)*3 {
+			return spec, true
+		}
+	}
+	return periodSpec{}, false
+}
+
+// expectedDay returns the most common day of the month for calendar periods
+// (favouring the latest on ties) or the weekday of the last occurrence.
+func expectedDay(dates []time.Time, spec periodSpec) int {
+	last := dates[len(dates)-1]
+	if spec.months == 0 {
+		return int(last.Weekday())
+	}
+	counts := make(map[int]int)
+	for _, date := range dates {
+		counts[date.Day()]++
+	}
+	days := make([]int, 0, len(counts))
+	for day := range counts {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+	best := last.Day()
+	for _, day := range days {
+		if counts[day] > counts[best] {
+			best = day
+		}
+	}
+	return best
+}
+
+// occurrence returns the date of the k-th expected occurrence after Last.
+func (r Recurrence) occurrence(k int) time.Time {
+	spec := r.Period.spec()
+	if spec.months == 0 {
+		return r.Last.AddDate(0, 0, k*spec.days)
+	}
+	first := time.Date(r.Last.Year(), r.Last.Month()+time.Month(k*spec.months), 1, 0, 0, 0, 0, r.Last.Location())
+	daysInMonth := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(r.ExpectedDay, daysInMonth)-1)
+}
+
+// DueBetween returns the expected dates after after, up to and including until.
+func (r Recurrence) DueBetween(after, until time.Time) []time.Time {
+	var dates []time.Time
+	for k := 1; k <= 1000; k++ {
+		date := r.occurrence(k)
+		if date.After(until) {
+			break
+		}
+		if date.After(after) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// ExpectedTransactions lists occurrences of recurring transactions that fall
+// after the last ledger date, up to and including until, and have no entry
+// for the same payee close to the expected date in entered.
+func (db *IntelligenceDB) ExpectedTransactions(until time.Time, entered []core.Transaction) []DueTransaction {
+	since := latestDate(db.Transactions)
+
+	var due []DueTransaction
+	for _, recurrence := range db.Recurrences {
+		window := time.Duration(max(recurrence.Period.spec().days/2, 3)) * 24 * time.Hour
+		for _, date := range recurrence.DueBetween(since, until) {
+			if !hasEntryNear(entered, recurrence.Payee, date, window) {
+				due = append(due, DueTransaction{Recurrence: recurrence, Date: date})
+			}
+		}
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].Date.Equal(due[j].Date) {
+			return due[i].Recurrence.Payee < due[j].Recurrence.Payee
+		}
+		return due[i].Date.Before(due[j].Date)
+	})
+	return due
+}
+
+// Transaction builds an uncleared transaction for the expected occurrence,
+// with the typical amount split across the template's accounts, or with the
+// budgeted postings when it comes from a periodic transaction.
+func (d DueTransaction) Transaction() core.Transaction {
+	if d.Recurrence.Budget != nil {
+		return core.Transaction{
+			Date:     d.Date,
+			Payee:    d.Recurrence.Payee,
+			Postings: budgetPostings(d.Recurrence.Budget),
+		}
+	}
+
+	template := d.Recurrence.Template
+	total := d.Recurrence.Amount
+	debits := Split(total, template.Amounts.DebitRatios)
+	credits := Split(total, template.Amounts.CreditRatios)
+
+	var postings []core.Posting
+	for i, account := range template.DebitAccounts {
+		posting := core.Posting{}
+		posting.Account, posting.Kind = core.ParsePostingAccount(account)
+		if i < len(debits) {
+			posting.Amount = core.NewAmount(debits[i].Round(2), d.Recurrence.Commodity)
+		}
+		postings = append(postings, posting)
+	}
+	for i, account := range template.CreditAccounts {
+		posting := core.Posting{}
+		posting.Account, posting.Kind = core.ParsePostingAccount(account)
+		if i < len(credits) {
+			posting.Amount = core.NewAmount(credits[i].Neg().Round(2), d.Recurrence.Commodity)
+		}
+		postings = append(postings, posting)
+	}
+
+	return core.Transaction{
+		Date:     d.Date,
+		Payee:    d.Recurrence.Payee,
+		Postings: postings,
+	}
+}
+
+// budgetPostings copies a periodic transaction's postings for a new transaction.
+func budgetPostings(budget []core.Posting) []core.Posting {
+	postings := make([]core.Posting, len(budget))
+	for i, posting := range budget {
+		postings[i] = core.Posting{
+			Account: posting.Account,
+			Kind:    posting.Kind,
+			Amount:  posting.Amount,
+			Comment: posting.Comment,
+		}
+	}
+	return postings
+}
+
+// hasEntryNear reports whether entered has a transaction for payee within window of date.
+func hasEntryNear(entered []core.Transaction, payee string, date time.Time, window time.Duration) bool {
+	for _, tx := range entered {
+		if !strings.EqualFold(strings.TrimSpace(tx.Payee), payee) {
+			continue
+		}
+		gap := tx.Date.Sub(date)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap <= window {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueSortedDates sorts dates and drops repeats of the same day.
+func uniqueSortedDates(dates []time.Time) []time.Time {
+	sorted := append([]time.Time(nil), dates...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+	unique := sorted[:0]
+	for _, date := range sorted {
+		if len(unique) > 0 && unique[len(unique)-1].Equal(date) {
+			continue
+		}
+		unique = append(unique, date)
+	}
+	return unique
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
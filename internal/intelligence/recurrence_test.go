@@ -0,0 +1,170 @@
+package intelligence
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
+)
+
+func recurringTransaction(payee string, date time.Time, amount string) core.Transaction {
+	quantity := decimal.RequireFromString(amount)
+	return core.Transaction{
+		Date:  date,
+		Payee: payee,
+		Postings: []core.Posting{
+			{Account: "Expenses:" + payee, Amount: core.NewAmount(quantity, "")},
+			{Account: "Assets:Checking", Amount: core.NewAmount(quantity.Neg(), "")},
+		},
+	}
+}
+
+func TestDetectRecurrences(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	transactions := []core.Transaction{
+		// Monthly on the 31st, clamped in shorter months
+		recurringTransaction("Rent", date(2024, 10, 31), "1500.00"),
+		recurringTransaction("Rent", date(2024, 11, 30), "1500.00"),
+		recurringTransaction("Rent", date(2024, 12, 31), "1500.00"),
+		// Biweekly payroll
+		recurringTransaction("Payroll", date(2024, 11, 15), "2000.00"),
+		recurringTransaction("Payroll", date(2024, 11, 29), "2000.00"),
+		recurringTransaction("Payroll", date(2024, 12, 13), "2100.00"),
+		recurringTransaction("Payroll", date(2024, 12, 27), "2000.00"),
+		// Yearly renewal needs only two occurrences
+		recurringTransaction("Domain", date(2023, 12, 20), "12.00"),
+		recurringTransaction("Domain", date(2024, 12, 20), "12.00"),
+		// Irregular spending is not a schedule
+		recurringTransaction("Cafe", date(2024, 12, 1), "4.00"),
+		recurringTransaction("Cafe", date(2024, 12, 3), "4.00"),
+		recurringTransaction("Cafe", date(2024, 12, 20), "4.00"),
+		// Lapsed subscription
+		recurringTransaction("Gym", date(2024, 5, 1), "40.00"),
+		recurringTransaction("Gym", date(2024, 6, 1), "40.00"),
+		recurringTransaction("Gym", date(2024, 7, 1), "40.00"),
+	}
+
+	db, _, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions})
+	if err != nil {
+		t.Fatalf("NewIntelligenceDB returned error: %v", err)
+	}
+
+	found := make(map[string]Recurrence)
+	for _, recurrence := range db.Recurrences {
+		found[recurrence.Payee] = recurrence
+	}
+	if len(found) != 3 {
+		t.Fatalf("expected Rent, Payroll and Domain to recur, got %+v", db.Recurrences)
+	}
+
+	rent := found["Rent"]
+	if rent.Period != PeriodMonthly || rent.ExpectedDay != 31 || rent.Amount.StringFixed(2) != "1500.00" {
+		t.Errorf("unexpected rent recurrence: %+v", rent)
+	}
+	if due := rent.DueBetween(date(2024, 12, 31), date(2025, 3, 1)); len(due) != 2 || !due[1].Equal(date(2025, 2, 28)) {
+		t.Errorf("expected rent due on Jan 31 and Feb 28, got %v", due)
+	}
+	if payroll := found["Payroll"]; payroll.Period != PeriodBiweekly || payroll.Amount.StringFixed(2) != "2000.00" {
+		t.Errorf("unexpected payroll recurrence: %+v", payroll)
+	}
+	if domain := found["Domain"]; domain.Period != PeriodYearly {
+		t.Errorf("unexpected domain recurrence: %+v", domain)
+	}
+
+	// Expected entries start after the last ledger date and skip ones already in the batch
+	entered := []core.Transaction{recurringTransaction("payroll", date(2025, 1, 11), "2000.00")}
+	expected := db.ExpectedTransactions(date(2025, 1, 31), entered)
+	var payees []string
+	for _, item := range expected {
+		payees = append(payees, item.Recurrence.Payee+" "+item.Date.Format("2006-01-02"))
+	}
+	want := []string{"Payroll 2025-01-24", "Rent 2025-01-31"}
+	if len(payees) != len(want) || payees[0] != want[0] || payees[1] != want[1] {
+		t.Fatalf("expected %v, got %v", want, payees)
+	}
+
+	tx := expected[1].Transaction()
+	if tx.Payee != "Rent" || tx.Status == core.Cleared || len(tx.Postings) != 2 || tx.Postings[0].Amount.String() != "$1500.00" || tx.Postings[1].Amount.String() != "$-1500.00" {
+		t.Fatalf("unexpected prefilled transaction: %+v", tx)
+	}
+}
+
+func TestPeriodicTransactionsAreBudgetRecurrences(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	transactions := []core.Transaction{
+		recurringTransaction("Rent", date(2025, 1, 31), "1400.00"),
+		recurringTransaction("Rent", date(2025, 2, 28), "1400.00"),
+		recurringTransaction("Rent", date(2025, 3, 31), "1400.00"),
+		recurringTransaction("Cafe", date(2025, 4, 2), "4.00"),
+	}
+	periodic := []core.PeriodicTransaction{
+		{
+			Period:      "Monthly from 2025/01/01",
+			Interval:    core.Interval{Months: 1},
+			Start:       date(2025, 1, 1),
+			Description: "Rent",
+			Postings: []core.Posting{
+				{Account: "Expenses:Rent", Amount: core.NewAmount(decimal.RequireFromString("1500.00"), "$")},
+				{Account: "Assets:Checking"},
+			},
+		},
+		{
+			Period:   "Weekly",
+			Interval: core.Interval{Days: 7},
+			Postings: []core.Posting{
+				{Account: "Expenses:Groceries", Amount: core.NewAmount(decimal.RequireFromString("100.00"), "$")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-100.00"), "$")},
+			},
+		},
+		{
+			Period:   "Daily",
+			Interval: core.Interval{Days: 1},
+			Postings: []core.Posting{{Account: "Expenses:Coffee", Amount: core.NewAmount(decimal.RequireFromString("3.00"), "$")}},
+		},
+	}
+
+	db, report, err := NewIntelligenceDB(core.ParseResult{Transactions: transactions, Periodic: periodic})
+	if err != nil {
+		t.Fatalf("NewIntelligenceDB returned error: %v", err)
+	}
+	if len(report.Issues) != 1 || !strings.Contains(report.Issues[0].Message, `"Daily"`) {
+		t.Fatalf("expected the daily budget to be reported, got %+v", report.Issues)
+	}
+
+	found := make(map[string]Recurrence)
+	for _, recurrence := range db.Recurrences {
+		found[recurrence.Payee] = recurrence
+	}
+	rent, ok := found["Rent"]
+	if !ok || rent.Budget == nil || rent.ExpectedDay != 1 || !rent.Amount.Equal(decimal.RequireFromString("1500")) {
+		t.Fatalf("expected the rent budget to replace the detected schedule, got %+v", rent)
+	}
+	if got := rent.Last.Format("2006-01-02"); got != "2025-04-01" {
+		t.Fatalf("expected the last budgeted rent on 2025-04-01, got %s", got)
+	}
+	groceries, ok := found["Expenses:Groceries"]
+	if !ok || groceries.Period != PeriodWeekly || groceries.ExpectedDay != int(time.Monday) {
+		t.Fatalf("expected a weekly groceries budget on Mondays, got %+v", groceries)
+	}
+
+	due := db.ExpectedTransactions(date(2025, 5, 1), nil)
+	var rentDue []DueTransaction
+	for _, item := range due {
+		if item.Recurrence.Payee == "Rent" {
+			rentDue = append(rentDue, item)
+		}
+	}
+	if len(rentDue) != 1 || rentDue[0].Date.Format("2006-01-02") != "2025-05-01" {
+		t.Fatalf("expected rent due on 2025-05-01, got %+v", rentDue)
+	}
+	tx := rentDue[0].Transaction()
+	if len(tx.Postings) != 2 || tx.Postings[0].Amount.String() != "$1500.00" || tx.Postings[1].Amount != nil {
+		t.Fatalf("expected the budgeted postings, got %+v", tx.Postings)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"strings"
 
 	"git.sr.ht/~jakintosh/teller/internal/core"
-	"github.com/shopspring/decimal"
 )
 
 // RuntimeIntelligence stores intelligence extracted from transactions in the current batch.
@@ -13,6 +12,7 @@ import (
 // ledger-based and runtime-added data.
 type RuntimeIntelligence struct {
 	Payees    map[string]int
+	Tags      map[string]int
 	Accounts  *Trie
 	Templates map[string][]TemplateRecord
 }
@@ -21,6 +21,7 @@ type RuntimeIntelligence struct {
 func NewRuntimeIntelligence() *RuntimeIntelligence {
 	return &RuntimeIntelligence{
 		Payees:    make(map[string]int),
+		Tags:      make(map[string]int),
 		Accounts:  NewTrie(),
 		Templates: make(map[string][]TemplateRecord),
 	}
@@ -33,54 +34,48 @@ func (r *RuntimeIntelligence) BuildFromBatch(transactions []core.Transaction) {
 	// Create a fresh instance
 	*r = RuntimeIntelligence{
 		Payees:    make(map[string]int),
+		Tags:      make(map[string]int),
 		Accounts:  NewTrie(),
 		Templates: make(map[string][]TemplateRecord),
 	}
 
 	// Track payee usage frequencies
 	payeeFreq := make(map[string]int)
-	// Extract unique accounts
-	accountSet := make(map[string]bool)
 
 	for _, tx := range transactions {
+		// Imported drafts carry raw bank descriptions that shouldn't be learned
+		if tx.Draft {
+			continue
+		}
 		if tx.Payee != "" {
 			payeeFreq[tx.Payee]++
 		}
+		recordTags(r.Tags, tx)
 
-		// Process all postings to extract account names
+		// Process all postings to record account usage
 		for _, posting := range tx.Postings {
 			if posting.Account != "" {
-				accountSet[posting.Account] = true
+				r.Accounts.Record(posting.Account, tx.Date)
 			}
 		}
 	}
 
 	r.Payees = payeeFreq
 
-	// Insert all accounts into the Trie
-	for account := range accountSet {
-		r.Accounts.Insert(account)
-	}
-
 	// Analyze transaction templates (same logic as in NewIntelligenceDB)
 	templateFreq := make(map[string]map[string]templateBucket) // payee -> key -> bucket
 
 	for _, tx := range transactions {
-		if tx.Payee == "" || len(tx.Postings) == 0 {
+		if tx.Draft || tx.Payee == "" || len(tx.Postings) == 0 {
 			continue
 		}
 
-		var debitAccounts []string
-		var creditAccounts []string
-
-		type templatePosting struct {
-			account   string
-			amount    decimal.Decimal
-			hasAmount bool
-		}
+		var debitEntries []templatePosting
+		var creditEntries []templatePosting
 
 		var postings []templatePosting
-		var balance decimal.Decimal
+		balance := core.Balance{}
+		priced := false
 		var missing []int
 
 		for _, posting := range tx.Postings {
@@ -89,34 +84,40 @@ func (r *RuntimeIntelligence) BuildFromBatch(transactions []core.Transaction) {
 				continue
 			}
 
-			rawAmount := strings.TrimSpace(posting.Amount)
-			entry := templatePosting{account: account}
-			if rawAmount == "" {
-				missing = append(missing, len(postings))
-				postings = append(postings, entry)
-				continue
-			}
-
-			amount, err := decimal.NewFromString(rawAmount)
-			if err != nil {
-				postings = append(postings, entry)
+			// Templates keep virtual postings in their ledger form so they're entered the same way
+			entry := templatePosting{account: posting.Kind.FormatAccount(account)}
+			if posting.Amount == nil {
+				if posting.Balances() {
+					missing = append(missing, len(postings))
+					postings = append(postings, entry)
+				}
 				continue
 			}
 
-			entry.amount = amount
+			entry.amount = posting.Amount.Quantity
 			entry.hasAmount = true
 			postings = append(postings, entry)
-			balance = balance.Add(amount)
+			// Priced postings balance at their cost; unbalanced virtual postings don't count
+			if posting.Balances() {
+				balance.Add(*posting.Weight())
+			}
+			priced = priced || posting.Price != nil || posting.Lot != nil
 		}
 
 		if len(postings) == 0 {
 			continue
 		}
 
+		// Transactions that don't balance on their own are balanced by the bucket account
+		if len(missing) == 0 && tx.Bucket != "" && !balance.IsZero() {
+			missing = append(missing, len(postings))
+			postings = append(postings, templatePosting{account: tx.Bucket})
+		}
 		if len(missing) == 1 {
-			remainder := balance.Neg()
-			postings[missing[0]].amount = remainder
-			postings[missing[0]].hasAmount = true
+			if remainder, ok := elidedRemainder(balance); ok {
+				postings[missing[0]].amount = remainder
+				postings[missing[0]].hasAmount = true
+			}
 		} else if len(missing) > 1 {
 			continue
 		}
@@ -126,20 +127,18 @@ func (r *RuntimeIntelligence) BuildFromBatch(transactions []core.Transaction) {
 				continue
 			}
 			if entry.amount.Sign() >= 0 {
-				debitAccounts = append(debitAccounts, entry.account)
+				debitEntries = append(debitEntries, entry)
 			} else {
-				creditAccounts = append(creditAccounts, entry.account)
+				creditEntries = append(creditEntries, entry)
 			}
 		}
 
-		if len(debitAccounts) == 0 && len(creditAccounts) == 0 {
+		if len(debitEntries) == 0 && len(creditEntries) == 0 {
 			continue
 		}
 
-		sortedDebit := append([]string(nil), debitAccounts...)
-		sortedCredit := append([]string(nil), creditAccounts...)
-		sort.Strings(sortedDebit)
-		sort.Strings(sortedCredit)
+		sortedDebit, debitAmounts := sortTemplatePostings(debitEntries)
+		sortedCredit, creditAmounts := sortTemplatePostings(creditEntries)
 		templateKey := strings.Join(sortedDebit, "|") + "->" + strings.Join(sortedCredit, "|")
 
 		if templateFreq[tx.Payee] == nil {
@@ -150,6 +149,11 @@ func (r *RuntimeIntelligence) BuildFromBatch(transactions []core.Transaction) {
 		bucket.frequency++
 		bucket.debit = sortedDebit
 		bucket.credit = sortedCredit
+		// Amounts of priced postings aren't in the commodity they balance in,
+		// so they can't be predicted from it
+		if commodity, ok := singleCommodity(balance); ok && !priced {
+			bucket.amounts.observe(tx.Date, commodity, debitAmounts, creditAmounts)
+		}
 		templateFreq[tx.Payee][templateKey] = bucket
 	}
 
@@ -157,10 +161,12 @@ func (r *RuntimeIntelligence) BuildFromBatch(transactions []core.Transaction) {
 	for payee, templates := range templateFreq {
 		var records []TemplateRecord
 		for _, bucket := range templates {
+			bucket.amounts.finalize()
 			records = append(records, TemplateRecord{
 				DebitAccounts:  append([]string(nil), bucket.debit...),
 				CreditAccounts: append([]string(nil), bucket.credit...),
 				Frequency:      bucket.frequency,
+				Amounts:        bucket.amounts,
 			})
 		}
 
@@ -179,21 +185,6 @@ func (r *RuntimeIntelligence) BuildFromBatch(transactions []core.Transaction) {
 	}
 }
 
-// FindPayees returns payees that start with the given prefix.
-func (r *RuntimeIntelligence) FindPayees(prefix string) []string {
-	prefix = strings.ToLower(prefix)
-	var matches []string
-
-	for payee := range r.Payees {
-		if strings.HasPrefix(strings.ToLower(payee), prefix) {
-			matches = append(matches, payee)
-		}
-	}
-
-	sort.Strings(matches)
-	return matches
-}
-
 // FindAccounts returns account names that start with the given prefix.
 func (r *RuntimeIntelligence) FindAccounts(prefix string) []string {
 	return r.Accounts.Find(prefix)
@@ -1,10 +1,13 @@
 package intelligence
 
 import (
+	"maps"
+	"slices"
 	"testing"
 	"time"
 
 	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
 )
 
 func TestRuntimeBuildFromBatch(t *testing.T) {
@@ -14,24 +17,24 @@ func TestRuntimeBuildFromBatch(t *testing.T) {
 			Date:  time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
 			Payee: "Brand New Payee",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50.00"},
-				{Account: "Assets:Checking", Amount: "-50.00"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-50.00"), "")},
 			},
 		},
 		{
 			Date:  time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC),
 			Payee: "Brand New Payee", // Same payee, same template
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "75.00"},
-				{Account: "Assets:Checking", Amount: "-75.00"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("75.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-75.00"), "")},
 			},
 		},
 		{
 			Date:  time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC),
 			Payee: "Another Payee", // Different payee
 			Postings: []core.Posting{
-				{Account: "Expenses:Gas", Amount: "45.00"},
-				{Account: "Assets:Checking", Amount: "-45.00"},
+				{Account: "Expenses:Gas", Amount: core.NewAmount(decimal.RequireFromString("45.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-45.00"), "")},
 			},
 		},
 	}
@@ -66,6 +69,25 @@ func TestRuntimeBuildFromBatch(t *testing.T) {
 	}
 }
 
+func TestRuntimeBuildSkipsDrafts(t *testing.T) {
+	runtime := NewRuntimeIntelligence()
+	runtime.BuildFromBatch([]core.Transaction{
+		{
+			Payee:       "POS PURCHASE 0042",
+			Draft:       true,
+			Description: "POS PURCHASE 0042",
+			Postings:    []core.Posting{{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-12.00"), "")}},
+		},
+	})
+
+	if len(runtime.Payees) != 0 {
+		t.Errorf("Expected drafts to be ignored for payees, got %v", runtime.Payees)
+	}
+	if len(runtime.Templates) != 0 {
+		t.Errorf("Expected drafts to be ignored for templates, got %d", len(runtime.Templates))
+	}
+}
+
 func TestRuntimeBuildFromEmptyBatch(t *testing.T) {
 	runtime := NewRuntimeIntelligence()
 	runtime.Payees = map[string]int{"Old Payee": 1}
@@ -94,52 +116,15 @@ func TestRuntimeBuildFromNilBatch(t *testing.T) {
 	}
 }
 
-func TestRuntimeFindPayees(t *testing.T) {
-	transactions := []core.Transaction{
-		{Payee: "City Hardware", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-		{Payee: "City Market", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-		{Payee: "Coffee Shop", Postings: []core.Posting{{Account: "Assets:Cash", Amount: "1"}, {Account: "Income:Misc", Amount: "-1"}}},
-	}
-
-	runtime := NewRuntimeIntelligence()
-	runtime.BuildFromBatch(transactions)
-
-	// Test prefix matching
-	tests := []struct {
-		prefix   string
-		expected []string
-	}{
-		{"City", []string{"City Hardware", "City Market"}},
-		{"city", []string{"City Hardware", "City Market"}}, // Case insensitive
-		{"Coffee", []string{"Coffee Shop"}},
-		{"Nonexistent", []string{}},
-		{"", []string{"City Hardware", "City Market", "Coffee Shop"}}, // Empty prefix matches all
-	}
-
-	for _, test := range tests {
-		results := runtime.FindPayees(test.prefix)
-		if len(results) != len(test.expected) {
-			t.Errorf("FindPayees(%q): expected %d results, got %d", test.prefix, len(test.expected), len(results))
-			continue
-		}
-		for i, expected := range test.expected {
-			if i >= len(results) || results[i] != expected {
-				t.Errorf("FindPayees(%q): expected %v, got %v", test.prefix, test.expected, results)
-				break
-			}
-		}
-	}
-}
-
 func TestRuntimeFindAccounts(t *testing.T) {
 	transactions := []core.Transaction{
 		{Payee: "Test1", Postings: []core.Posting{
-			{Account: "Assets:Checking", Amount: "100"},
-			{Account: "Income:Salary", Amount: "-100"},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("100"), "")},
+			{Account: "Income:Salary", Amount: core.NewAmount(decimal.RequireFromString("-100"), "")},
 		}},
 		{Payee: "Test2", Postings: []core.Posting{
-			{Account: "Assets:Savings", Amount: "50"},
-			{Account: "Income:Interest", Amount: "-50"},
+			{Account: "Assets:Savings", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+			{Account: "Income:Interest", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 		}},
 	}
 
@@ -170,15 +155,15 @@ func TestRuntimeFindTemplates(t *testing.T) {
 		{
 			Payee: "Test Payee",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50"},
-				{Account: "Assets:Cash", Amount: "-50"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 		{
 			Payee: "Test Payee",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "30"},
-				{Account: "Assets:Credit", Amount: "-30"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("30"), "")},
+				{Account: "Assets:Credit", Amount: core.NewAmount(decimal.RequireFromString("-30"), "")},
 			},
 		},
 	}
@@ -206,14 +191,14 @@ func TestRuntimeMutabilityScenario(t *testing.T) {
 		{
 			Payee: "Brand New Payyee", // Typo
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50"},
-				{Account: "Assets:Cash", Amount: "-50"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 	}
 	runtime.BuildFromBatch(batch1)
 
-	payees := runtime.FindPayees("Brand New")
+	payees := slices.Sorted(maps.Keys(runtime.Payees))
 	if !contains(payees, "Brand New Payyee") {
 		t.Error("Typo payee should be in suggestions after first transaction")
 	}
@@ -223,22 +208,22 @@ func TestRuntimeMutabilityScenario(t *testing.T) {
 		{
 			Payee: "Brand New Payee", // Fixed
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50"},
-				{Account: "Assets:Cash", Amount: "-50"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 		{
 			Payee: "Brand New Payyee", // Still has typo
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "75"},
-				{Account: "Assets:Cash", Amount: "-75"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("75"), "")},
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-75"), "")},
 			},
 		},
 	}
 	runtime.BuildFromBatch(batch2)
 
 	// Both payees should exist (one is used, one is typo)
-	payees = runtime.FindPayees("Brand New")
+	payees = slices.Sorted(maps.Keys(runtime.Payees))
 	if len(payees) != 2 {
 		t.Errorf("Expected 2 payees, got %d: %v", len(payees), payees)
 	}
@@ -248,22 +233,22 @@ func TestRuntimeMutabilityScenario(t *testing.T) {
 		{
 			Payee: "Brand New Payee",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50"},
-				{Account: "Assets:Cash", Amount: "-50"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 		{
 			Payee: "Brand New Payee", // Fixed
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "75"},
-				{Account: "Assets:Cash", Amount: "-75"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("75"), "")},
+				{Account: "Assets:Cash", Amount: core.NewAmount(decimal.RequireFromString("-75"), "")},
 			},
 		},
 	}
 	runtime.BuildFromBatch(batch3)
 
 	// Now only the correct payee should exist
-	payees = runtime.FindPayees("Brand New")
+	payees = slices.Sorted(maps.Keys(runtime.Payees))
 	if len(payees) != 1 {
 		t.Errorf("Expected 1 payee after fix, got %d: %v", len(payees), payees)
 	}
@@ -294,22 +279,22 @@ func TestRuntimeTemplateFrequency(t *testing.T) {
 		{
 			Payee: "Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "50"},
-				{Account: "Assets:Checking", Amount: "-50"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("50"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-50"), "")},
 			},
 		},
 		{
 			Payee: "Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "75"},
-				{Account: "Assets:Checking", Amount: "-75"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("75"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-75"), "")},
 			},
 		},
 		{
 			Payee: "Grocery Store",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food", Amount: "60"},
-				{Account: "Assets:CreditCard", Amount: "-60"},
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("60"), "")},
+				{Account: "Assets:CreditCard", Amount: core.NewAmount(decimal.RequireFromString("-60"), "")},
 			},
 		},
 	}
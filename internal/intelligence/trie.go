@@ -1,12 +1,16 @@
 package intelligence
 
-import "sort"
+import (
+	"sort"
+	"time"
+)
 
 // TrieNode represents a node in the Trie data structure.
 type TrieNode struct {
 	children map[rune]*TrieNode
 	isEnd    bool
-	word     string // Store the complete word at terminal nodes
+	word     string       // Store the complete word at terminal nodes
+	usage    AccountUsage // How often and how recently the word was recorded
 }
 
 // Trie is a prefix tree for efficient prefix-based string searching.
@@ -38,6 +42,36 @@ func (t *Trie) Insert(word string) {
 	current.word = word
 }
 
+// Record adds a word to the Trie and counts one use of it on the given date.
+func (t *Trie) Record(word string, date time.Time) {
+	t.Insert(word)
+	node := t.node(word)
+	node.usage.Count++
+	if date.After(node.usage.LastUsed) {
+		node.usage.LastUsed = date
+	}
+}
+
+// Usage returns the recorded use count and last-used date of a word.
+func (t *Trie) Usage(word string) AccountUsage {
+	if node := t.node(word); node != nil && node.isEnd {
+		return node.usage
+	}
+	return AccountUsage{}
+}
+
+// node returns the node reached by following word from the root, or nil.
+func (t *Trie) node(word string) *TrieNode {
+	current := t.root
+	for _, char := range word {
+		if current.children[char] == nil {
+			return nil
+		}
+		current = current.children[char]
+	}
+	return current
+}
+
 // Find returns all words in the Trie that start with the given prefix.
 func (t *Trie) Find(prefix string) []string {
 	current := t.root
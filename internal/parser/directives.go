@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+)
+
+// directive is an account, payee or commodity declaration. The indented
+// lines below it are its sub-directives rather than postings.
+type directive struct {
+	keyword string
+	name    string
+}
+
+// parseDirectiveLine recognizes a directive starting in the first column and
+// splits it into its keyword and argument, e.g. "account" and "Expenses:Food".
+// The year directive may be written "Y2019", without a space.
+func parseDirectiveLine(line string) (string, string, bool) {
+	if len(line) == 0 || unicode.IsSpace(rune(line[0])) {
+		return "", "", false
+	}
+	text, _ := extractComment(line)
+	keyword, arg := text, ""
+	if idx := strings.IndexFunc(text, unicode.IsSpace); idx != -1 {
+		keyword, arg = text[:idx], strings.TrimSpace(text[idx:])
+	}
+	if len(keyword) > 1 && keyword[0] == 'Y' && arg == "" && strings.Trim(keyword[1:], "0123456789") == "" {
+		keyword, arg = "Y", keyword[1:]
+	}
+	switch keyword {
+	case "account", "payee", "commodity", "alias", "end", "year", "Y", "apply", "bucket", "A":
+		return keyword, arg, true
+	}
+	return "", "", false
+}
+
+// applyDirective records a declaration, or changes the aliases, year,
+// applied accounts or bucket that the lines after it are read with.
+// Returns the declaration whose sub-directives follow, if any.
+func (s *parseState) applyDirective(keyword, arg string) (*directive, error) {
+	switch keyword {
+	case "account":
+		if arg == "" {
+			return nil, fmt.Errorf("account directive missing an account name")
+		}
+		arg = s.applyAccount(arg)
+		if !slices.Contains(s.result.Accounts, arg) {
+			s.result.Accounts = append(s.result.Accounts, arg)
+		}
+	case "payee":
+		if arg == "" {
+			return nil, fmt.Errorf("payee directive missing a payee")
+		}
+		if !slices.Contains(s.result.Payees, arg) {
+			s.result.Payees = append(s.result.Payees, arg)
+		}
+	case "commodity":
+		if arg == "" {
+			return nil, fmt.Errorf("commodity directive missing a commodity")
+		}
+		commodity := parseCommodityDeclaration(arg, s.formats.DecimalComma)
+		s.result.Commodities = append(s.result.Commodities, commodity)
+		s.formats.Declare(commodity)
+	case "alias":
+		name, account, ok := strings.Cut(arg, "=")
+		name, account = strings.TrimSpace(name), strings.TrimSpace(account)
+		if !ok || name == "" || account == "" {
+			return nil, fmt.Errorf("alias directive must be written alias NAME=ACCOUNT")
+		}
+		s.aliases[name] = account
+		return nil, nil
+	case "year", "Y":
+		year, err := strconv.Atoi(arg)
+		if err != nil || year <= 0 {
+			return nil, fmt.Errorf("year directive has invalid year %q", arg)
+		}
+		s.year = year
+		return nil, nil
+	case "apply":
+		account, ok := strings.CutPrefix(arg, "account")
+		account = strings.TrimSpace(account)
+		if !ok || account == "" {
+			return nil, fmt.Errorf("unsupported directive %q", "apply "+arg)
+		}
+		s.prefixes = append(s.prefixes, account)
+		return nil, nil
+	case "bucket", "A":
+		if arg == "" {
+			return nil, fmt.Errorf("bucket directive missing an account name")
+		}
+		s.bucket = s.applyAccount(expandAlias(s.aliases, arg))
+		return nil, nil
+	case "end":
+		switch arg {
+		case "aliases":
+			clear(s.aliases)
+		case "apply", "apply account":
+			if len(s.prefixes) == 0 {
+				return nil, fmt.Errorf("%q without apply account", "end "+arg)
+			}
+			s.prefixes = s.prefixes[:len(s.prefixes)-1]
+		default:
+			return nil, fmt.Errorf("unknown directive %q", "end "+arg)
+		}
+		return nil, nil
+	}
+	return &directive{keyword: keyword, name: arg}, nil
+}
+
+// applySubdirective handles an indented line below a declaration. Accounts
+// take "alias NAME" and commodities "format AMOUNT"; other sub-directives,
+// such as notes, are ignored.
+func (s *parseState) applySubdirective(decl directive, line string) {
+	text, _ := extractComment(line)
+	keyword, arg := text, ""
+	if idx := strings.IndexFunc(text, unicode.IsSpace); idx != -1 {
+		keyword, arg = text[:idx], strings.TrimSpace(text[idx:])
+	}
+	switch {
+	case decl.keyword == "account" && keyword == "alias" && arg != "":
+		s.aliases[arg] = decl.name
+	case decl.keyword == "commodity" && keyword == "format" && arg != "":
+		commodity := &s.result.Commodities[len(s.result.Commodities)-1]
+		commodity.Format = arg
+		s.formats.Declare(*commodity)
+	}
+}
+
+// parseCommodityDeclaration reads the argument of a commodity directive: a
+// commodity such as "$" or "AAPL", or a sample amount like "$1,000.00" that
+// also gives its format. decimalComma is how an ambiguous sample is read.
+func parseCommodityDeclaration(arg string, decimalComma bool) core.Commodity {
+	if amount, err := (core.CommodityFormats{DecimalComma: decimalComma}).ParseAmount(arg); err == nil && amount.Commodity != "" {
+		return core.Commodity{Symbol: amount.Commodity, Format: arg}
+	}
+	return core.Commodity{Symbol: strings.Trim(arg, `"`)}
+}
+
+// applyAccount prefixes an account with those of the open apply account blocks.
+func (s *parseState) applyAccount(account string) string {
+	if len(s.prefixes) == 0 {
+		return account
+	}
+	return strings.Join(s.prefixes, ":") + ":" + account
+}
+
+// expandAlias replaces an account named by an alias, or whose first segment
+// is one, with the aliased account.
+func expandAlias(aliases map[string]string, account string) string {
+	if target, ok := aliases[account]; ok {
+		return target
+	}
+	if first, rest, ok := strings.Cut(account, ":"); ok {
+		if target, ok := aliases[first]; ok {
+			return target + ":" + rest
+		}
+	}
+	return account
+}
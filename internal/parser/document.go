@@ -0,0 +1,301 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Document is a lossless syntax tree of one ledger file. Its nodes are the
+// file's entries in order, with every line kept along with its whitespace and
+// line ending, so writing an unmodified document reproduces the file byte for
+// byte. Included files aren't followed; each has its own document.
+type Document struct {
+	File  string
+	Nodes []Node
+}
+
+// NodeKind is the kind of entry, or part of an entry, a node holds.
+type NodeKind int
+
+const (
+	UnknownNode      NodeKind = iota // lines the parser doesn't recognize
+	BlankNode                        // one or more blank lines
+	CommentNode                      // a comment that isn't a note on a header or posting
+	TransactionNode                  // a transaction, with its header and postings as children
+	AutomatedNode                    // an automated transaction, "= PREDICATE"
+	PeriodicNode                     // a periodic transaction, "~ PERIOD"
+	PriceNode                        // a price directive, "P DATE COMMODITY PRICE"
+	IncludeNode                      // an include directive
+	DirectiveNode                    // any other directive; declarations have their sub-directives as children
+	HeaderNode                       // the first line of an entry, with the notes below it
+	PostingNode                      // a posting, with the notes below it
+	SubdirectiveNode                 // an indented line below a declaration
+)
+
+var nodeKindNames = []string{
+	"unknown", "blank", "comment", "transaction", "automated", "periodic", "price", "include", "directive",
+	"header", "posting", "subdirective",
+}
+
+// String returns the lowercase name of the kind.
+func (k NodeKind) String() string {
+	if int(k) < len(nodeKindNames) {
+		return nodeKindNames[k]
+	}
+	return "unknown"
+}
+
+// Span locates a run of bytes in a file. Lines count from 1; offsets from 0,
+// with End just past the last byte.
+type Span struct {
+	File      string
+	StartLine int
+	EndLine   int
+	Start     int
+	End       int
+}
+
+// String describes where the span starts, e.g. "2024.ledger:12".
+func (s Span) String() string {
+	return fmt.Sprintf("%s:%d", s.File, s.StartLine)
+}
+
+// Line is one line of a document. Text excludes the line ending, which is
+// "\n", "\r\n", or empty for a last line without one.
+type Line struct {
+	Text   string
+	Ending string
+	Span   Span
+}
+
+// Node is an entry of a document or a part of one. Transactions, automated
+// and periodic transactions and declarations hold their header, postings or
+// sub-directives, comments and blank lines as Children; other nodes hold
+// their Lines directly.
+type Node struct {
+	Kind     NodeKind
+	Lines    []Line // empty when the node has children
+	Children []Node
+	Span     Span
+}
+
+// Text returns the node's lines as they appear in the file.
+func (n Node) Text() string {
+	var b strings.Builder
+	for _, child := range n.Children {
+		b.WriteString(child.Text())
+	}
+	for _, line := range n.Lines {
+		b.WriteString(line.Text + line.Ending)
+	}
+	return b.String()
+}
+
+// ParseDocument reads a ledger file losslessly.
+func ParseDocument(filePath string) (*Document, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return ReadDocument(filePath, data), nil
+}
+
+// ReadDocument parses ledger text losslessly. name is recorded as the file
+// of every span. Entries are grouped as ParseFile reads them: blank lines,
+// comments and unrecognized lines in the first column don't end an entry,
+// so indented lines after them still belong to it.
+func ReadDocument(name string, data []byte) *Document {
+	doc := &Document{File: name}
+	for _, line := range splitLines(name, data) {
+		doc.add(line)
+	}
+	for i := range doc.Nodes {
+		if node := &doc.Nodes[i]; isEntry(*node) {
+			node.Children = entryChildren(node.Kind, node.Lines)
+			node.Lines = nil
+		}
+	}
+	return doc
+}
+
+// Bytes returns the document's text, which is the original file's unless
+// its nodes have been changed.
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	for _, node := range d.Nodes {
+		b.WriteString(node.Text())
+	}
+	return b.Bytes()
+}
+
+// WriteTo writes the document's text to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(d.Bytes())
+	return int64(n), err
+}
+
+// NodeAt returns the entry containing a line, e.g. to show the transaction a
+// parse issue refers to.
+func (d *Document) NodeAt(line int) (Node, bool) {
+	for _, node := range d.Nodes {
+		if node.Span.StartLine <= line && line <= node.Span.EndLine {
+			return node, true
+		}
+	}
+	return Node{}, false
+}
+
+// add places a line in the node it belongs to. Indented lines continue the
+// open entry, taking in any blank lines, comments and unrecognized lines
+// since it; other lines start a node of their own.
+func (d *Document) add(line Line) {
+	kind := lineKind(line.Text)
+	if isIndented(line.Text) {
+		if open := d.openEntry(); open >= 0 {
+			entry := &d.Nodes[open]
+			for _, node := range d.Nodes[open+1:] {
+				entry.Lines = append(entry.Lines, node.Lines...)
+			}
+			d.Nodes = d.Nodes[:open+1]
+			entry.Lines = append(entry.Lines, line)
+			entry.Span = spanOf(entry.Lines)
+			return
+		}
+	}
+	if last := len(d.Nodes) - 1; kind == BlankNode && last >= 0 && d.Nodes[last].Kind == BlankNode {
+		d.Nodes[last].Lines = append(d.Nodes[last].Lines, line)
+		d.Nodes[last].Span = spanOf(d.Nodes[last].Lines)
+		return
+	}
+	d.Nodes = append(d.Nodes, Node{Kind: kind, Lines: []Line{line}, Span: line.Span})
+}
+
+// openEntry returns the index of the entry indented lines would continue, or
+// -1 when the last entry line has been followed by one that ends it.
+func (d *Document) openEntry() int {
+	for i := len(d.Nodes) - 1; i >= 0; i-- {
+		node := d.Nodes[i]
+		switch {
+		case node.Kind == BlankNode || node.Kind == UnknownNode || node.Kind == CommentNode:
+			continue
+		case isEntry(node):
+			return i
+		}
+		return -1
+	}
+	return -1
+}
+
+// isEntry reports whether a node is an entry that takes indented lines:
+// a transaction, automated or periodic transaction, or declaration.
+func isEntry(node Node) bool {
+	switch node.Kind {
+	case TransactionNode, AutomatedNode, PeriodicNode:
+		return true
+	case DirectiveNode:
+		keyword, arg, _ := parseDirectiveLine(node.Lines[0].Text)
+		return arg != "" && (keyword == "account" || keyword == "payee" || keyword == "commodity")
+	}
+	return false
+}
+
+// entryChildren splits the lines of an entry into its header, postings or
+// sub-directives, comments and blank lines. Indented comments are notes on
+// the header or posting directly above them, as in ParseFile.
+func entryChildren(kind NodeKind, lines []Line) []Node {
+	children := []Node{{Kind: HeaderNode, Lines: lines[:1]}}
+	for _, line := range lines[1:] {
+		last := &children[len(children)-1]
+		childKind := lineKind(line.Text)
+		switch {
+		case childKind == CommentNode && isIndented(line.Text) && (last.Kind == HeaderNode || last.Kind == PostingNode),
+			childKind == BlankNode && last.Kind == BlankNode:
+			last.Lines = append(last.Lines, line)
+			continue
+		case childKind == UnknownNode && isIndented(line.Text) && kind == DirectiveNode:
+			childKind = SubdirectiveNode
+		case childKind == UnknownNode && isIndented(line.Text):
+			childKind = PostingNode
+		}
+		children = append(children, Node{Kind: childKind, Lines: []Line{line}})
+	}
+	for i := range children {
+		children[i].Span = spanOf(children[i].Lines)
+	}
+	return children
+}
+
+// spanOf returns the span covering consecutive lines.
+func spanOf(lines []Line) Span {
+	span := lines[0].Span
+	last := lines[len(lines)-1].Span
+	span.EndLine = last.EndLine
+	span.End = last.End
+	return span
+}
+
+// lineKind returns the kind of node a line starts, following the same rules
+// as ParseFile. Indented lines that don't continue an entry are comments or unknown.
+func lineKind(text string) NodeKind {
+	trimmed := strings.TrimSpace(text)
+	switch {
+	case trimmed == "":
+		return BlankNode
+	case strings.HasPrefix(trimmed, ";"):
+		return CommentNode
+	case isIndented(text):
+		return UnknownNode
+	}
+	if _, ok := parseIncludeLine(text); ok {
+		return IncludeNode
+	}
+	if _, _, ok := parseDirectiveLine(text); ok {
+		return DirectiveNode
+	}
+	switch {
+	case strings.HasPrefix(text, "P ") || strings.HasPrefix(text, "P\t"):
+		return PriceNode
+	case strings.HasPrefix(text, "="):
+		return AutomatedNode
+	case strings.HasPrefix(text, "~"):
+		return PeriodicNode
+	case unicode.IsDigit(rune(text[0])):
+		return TransactionNode
+	}
+	return UnknownNode
+}
+
+// isIndented reports whether a line starts with whitespace.
+func isIndented(text string) bool {
+	return len(text) > 0 && unicode.IsSpace(rune(text[0]))
+}
+
+// splitLines splits data into lines, keeping each line's ending and span.
+func splitLines(name string, data []byte) []Line {
+	var lines []Line
+	offset := 0
+	for number := 1; offset < len(data); number++ {
+		end := bytes.IndexByte(data[offset:], '\n')
+		next := offset + end + 1
+		if end == -1 {
+			next = len(data)
+		}
+		text, ending := string(data[offset:next]), ""
+		if strings.HasSuffix(text, "\r\n") {
+			text, ending = text[:len(text)-2], "\r\n"
+		} else if strings.HasSuffix(text, "\n") {
+			text, ending = text[:len(text)-1], "\n"
+		}
+		lines = append(lines, Line{
+			Text:   text,
+			Ending: ending,
+			Span:   Span{File: name, StartLine: number, EndLine: number, Start: offset, End: next},
+		})
+		offset = next
+	}
+	return lines
+}
@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocumentRoundTripsSample(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "fixtures", "sample.ledger")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read sample ledger: %v", err)
+	}
+	doc, err := ParseDocument(path)
+	if err != nil {
+		t.Fatalf("ParseDocument returned error: %v", err)
+	}
+	if !bytes.Equal(doc.Bytes(), data) {
+		t.Fatalf("document doesn't reproduce the sample ledger:\n%s", doc.Bytes())
+	}
+}
+
+func TestDocumentKeepsEveryLine(t *testing.T) {
+	content := "; header comment\r\n" +
+		"account Assets:Checking\r\n" +
+		"    note main account\r\n" +
+		"\r\n" +
+		"\r\n" +
+		"2025/01/15 Grocery Store  ; weekly shop\r\n" +
+		"\tExpenses:Food    $25.00\r\n" +
+		"    ; paid by card\r\n" +
+		"\tAssets:Checking\r\n" +
+		"\r\n" +
+		"P 2025/01/15 AAPL $150.00\n" +
+		"  stray posting\n" +
+		"= /Food/\n" +
+		"    (Budget:Food)    -1\n" +
+		"\n" +
+		"tag unknown   \n" +
+		"~ Monthly"
+
+	doc := ReadDocument("test.ledger", []byte(content))
+	if got := string(doc.Bytes()); got != content {
+		t.Fatalf("document doesn't round trip:\n got %q\nwant %q", got, content)
+	}
+
+	wantKinds := []NodeKind{CommentNode, DirectiveNode, BlankNode, TransactionNode, BlankNode, PriceNode, UnknownNode, AutomatedNode, BlankNode, UnknownNode, PeriodicNode}
+	if len(doc.Nodes) != len(wantKinds) {
+		t.Fatalf("expected %d nodes, got %d: %+v", len(wantKinds), len(doc.Nodes), doc.Nodes)
+	}
+	for i, kind := range wantKinds {
+		if doc.Nodes[i].Kind != kind {
+			t.Errorf("node %d: expected %s, got %s", i, kind, doc.Nodes[i].Kind)
+		}
+	}
+
+	declaration := doc.Nodes[1]
+	if len(declaration.Children) != 2 || declaration.Children[1].Kind != SubdirectiveNode {
+		t.Errorf("expected the declaration's sub-directive as a child, got %+v", declaration.Children)
+	}
+
+	tx := doc.Nodes[3]
+	if tx.Span.StartLine != 6 || tx.Span.EndLine != 9 {
+		t.Errorf("expected transaction on lines 6-9, got %d-%d", tx.Span.StartLine, tx.Span.EndLine)
+	}
+	if got := content[tx.Span.Start:tx.Span.End]; got != tx.Text() {
+		t.Errorf("transaction span covers %q, want %q", got, tx.Text())
+	}
+	childKinds := []NodeKind{HeaderNode, PostingNode, PostingNode}
+	if len(tx.Lines) != 0 || len(tx.Children) != len(childKinds) {
+		t.Fatalf("expected transaction children %v, got %+v", childKinds, tx.Children)
+	}
+	for i, kind := range childKinds {
+		if tx.Children[i].Kind != kind {
+			t.Errorf("child %d: expected %s, got %s", i, kind, tx.Children[i].Kind)
+		}
+	}
+	food := tx.Children[1]
+	if len(food.Lines) != 2 || food.Lines[1].Text != "    ; paid by card" {
+		t.Errorf("expected the note to belong to its posting, got %+v", food.Lines)
+	}
+	if line := food.Lines[0]; line.Text != "\tExpenses:Food    $25.00" || line.Ending != "\r\n" {
+		t.Errorf("unexpected posting line %q ending %q", line.Text, line.Ending)
+	}
+	if food.Span.StartLine != 7 || food.Span.EndLine != 8 || content[food.Span.Start:food.Span.End] != food.Text() {
+		t.Errorf("unexpected posting span %+v", food.Span)
+	}
+
+	last := doc.Nodes[len(doc.Nodes)-1]
+	if last.Children[0].Lines[0].Ending != "" || last.Span.End != len(content) {
+		t.Errorf("expected last line without ending to end the file, got %+v", last)
+	}
+
+	node, ok := doc.NodeAt(7)
+	if !ok || node.Kind != TransactionNode || node.Span.File != "test.ledger" {
+		t.Errorf("expected line 7 to be in the transaction, got %+v", node)
+	}
+	if _, ok := doc.NodeAt(100); ok {
+		t.Errorf("expected no node past the end of the file")
+	}
+}
+
+func TestDocumentGroupsEntriesLikeParseFile(t *testing.T) {
+	content := "2025/01/15 Grocery Store\n" +
+		"    Expenses:Food    $25.00\n" +
+		"; a comment in the first column\n" +
+		"\n" +
+		"    Assets:Checking\n" +
+		"\n" +
+		"; between transactions\n" +
+		"2025/01/16 Coffee\n" +
+		"    Expenses:Food    $4.00\n" +
+		"oops\n" +
+		"    Assets:Checking\n"
+
+	path := filepath.Join(t.TempDir(), "grouping.ledger")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	doc, err := ParseDocument(path)
+	if err != nil {
+		t.Fatalf("ParseDocument returned error: %v", err)
+	}
+	if string(doc.Bytes()) != content {
+		t.Fatalf("document doesn't round trip: %q", doc.Bytes())
+	}
+
+	var transactions []Node
+	for _, node := range doc.Nodes {
+		if node.Kind == TransactionNode {
+			transactions = append(transactions, node)
+		}
+	}
+	if len(transactions) != len(result.Transactions) {
+		t.Fatalf("expected %d transaction nodes, got %d", len(result.Transactions), len(transactions))
+	}
+	for i, node := range transactions {
+		var postings []Node
+		for _, child := range node.Children {
+			if child.Kind == PostingNode {
+				postings = append(postings, child)
+			}
+		}
+		tx := result.Transactions[i]
+		if len(postings) != len(tx.Postings) {
+			t.Fatalf("transaction %d: expected %d posting nodes, got %+v", i, len(tx.Postings), node.Children)
+		}
+		for j, posting := range postings {
+			if !strings.Contains(posting.Text(), tx.Postings[j].Account) {
+				t.Errorf("transaction %d posting %d: expected %s, got %q", i, j, tx.Postings[j].Account, posting.Text())
+			}
+		}
+	}
+	if node, _ := doc.NodeAt(5); node.Kind != TransactionNode || node.Span.StartLine != 1 {
+		t.Errorf("expected the posting after the comment and blank line in the first transaction, got %+v", node)
+	}
+	if node, _ := doc.NodeAt(7); node.Kind != CommentNode {
+		t.Errorf("expected the comment between transactions on its own, got %+v", node)
+	}
+}
+
+func TestDocumentWritesEdits(t *testing.T) {
+	doc := ReadDocument("test.ledger", []byte("2025/01/15 Store\n    Expenses:Food  $5\n    Assets:Cash\n"))
+	doc.Nodes[0].Children[1].Lines[0].Text = "    Expenses:Food  $6"
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo returned error: %v", err)
+	}
+	if want := "2025/01/15 Store\n    Expenses:Food  $6\n    Assets:Cash\n"; buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
@@ -3,7 +3,10 @@ package parser
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 	"unicode"
@@ -12,99 +15,437 @@ import (
 )
 
 // ParseFile reads a ledger-cli file and converts it into Transaction structs.
+// Files pulled in with include directives are parsed in place, so the result
+// covers the whole journal.
 func ParseFile(filePath string) (core.ParseResult, error) {
+	state := newParseState(Options{})
+	if err := parseFile(filePath, nil, state); err != nil {
+		return core.ParseResult{}, err
+	}
+	return state.result, nil
+}
+
+// Options configures reading a ledger from a stream.
+type Options struct {
+	Name string // file name for issues and relative includes, which resolve from the working directory when empty
+	Year int    // year of dates written without one until a year directive sets it, the current year when zero
+
+	// DecimalComma reads a lone comma or point with three digits after it,
+	// as in "1,234" or "1.234", as a decimal comma and a thousands point, for
+	// commodities declared without a format. Amounts are otherwise read with
+	// a decimal point, and separators that can only be one or the other, as
+	// in "12,50" or "1,234.56", are read as they must be either way.
+	DecimalComma bool
+}
+
+// Parse reads a ledger from r, following its include directives like ParseFile.
+func Parse(r io.Reader, opts Options) (core.ParseResult, error) {
+	state := newParseState(opts)
+	if err := parseStream(r, opts, state); err != nil {
+		return core.ParseResult{}, err
+	}
+	return state.result, nil
+}
+
+// Stream reads a ledger from r like Parse, but hands each transaction to fn
+// once it's complete instead of keeping it, so the returned result holds
+// everything except the transactions. Parsing stops at the first error fn
+// returns.
+func Stream(r io.Reader, opts Options, fn func(core.Transaction) error) (core.ParseResult, error) {
+	state := newParseState(opts)
+	state.emit = fn
+	if err := parseStream(r, opts, state); err != nil {
+		return state.result, err
+	}
+	return state.result, nil
+}
+
+// parseState is shared by a file and the files it includes.
+type parseState struct {
+	result   core.ParseResult
+	balances core.RunningBalances  // running account balances, for checking balance assertions
+	aliases  map[string]string     // account aliases in effect, by name
+	year     int                   // year of dates written without one, set by year directives
+	prefixes []string              // accounts applied by open apply account blocks, outermost first
+	bucket   string                // account that balances transactions without an elided amount
+	formats  core.CommodityFormats // styles of commodities declared with a format, for reading their amounts
+
+	emit    func(core.Transaction) error // receives transactions instead of result when streaming
+	stopped error                        // error emit returned, which ends the parse
+}
+
+// newParseState returns the state for a new parse with opts, reading dates
+// without a year in opts.Year, or the current year when zero.
+func newParseState(opts Options) *parseState {
+	year := opts.Year
+	if year == 0 {
+		year = time.Now().Year()
+	}
+	return &parseState{
+		result:   core.ParseResult{DecimalComma: opts.DecimalComma},
+		balances: core.RunningBalances{},
+		aliases:  make(map[string]string),
+		year:     year,
+		formats:  core.CommodityFormats{DecimalComma: opts.DecimalComma},
+	}
+}
+
+// parseStream parses a stream given to Parse or Stream into state.
+func parseStream(r io.Reader, opts Options, state *parseState) error {
+	var including []string
+	if opts.Name != "" {
+		if absPath, err := filepath.Abs(opts.Name); err == nil {
+			including = append(including, absPath)
+		}
+	}
+	return parseReader(r, opts.Name, including, state)
+}
+
+// parseFile parses one file into state. including lists the absolute paths
+// of the files currently being parsed, outermost first, to detect cycles.
+func parseFile(filePath string, including []string, state *parseState) error {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return core.ParseResult{}, fmt.Errorf("failed to open file: %w", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	return parseReader(file, filePath, append(including, absPath), state)
+}
+
+// parseReader parses the lines of filePath, read from r, into state.
+// including ends with filePath's absolute path when it names a file.
+func parseReader(r io.Reader, filePath string, including []string, state *parseState) error {
 	var (
-		transactions       []core.Transaction
-		issues             []core.ParseIssue
-		scanner            = bufio.NewScanner(file)
+		scanner            = bufio.NewScanner(r)
 		lineNumber         = 0
 		currentTransaction *core.Transaction
+		currentAutomated   *core.AutomatedTransaction
+		currentPeriodic    *core.PeriodicTransaction
+		currentDirective   *directive
+		postingLines       []int
+		result             = &state.result
 	)
 
-	for scanner.Scan() {
+	addIssue := func(message string) {
+		result.Issues = append(result.Issues, core.ParseIssue{
+			File:    filePath,
+			Line:    lineNumber,
+			Message: message,
+		})
+	}
+	flush := func() {
+		if currentTransaction != nil {
+			applyTags(currentTransaction)
+			_, failures := state.balances.Apply(*currentTransaction)
+			for _, failure := range failures {
+				result.Issues = append(result.Issues, core.ParseIssue{
+					File:    filePath,
+					Line:    postingLines[failure.Posting],
+					Message: failure.String(),
+				})
+			}
+			if state.emit == nil {
+				result.Transactions = append(result.Transactions, *currentTransaction)
+			} else if state.stopped == nil {
+				state.stopped = state.emit(*currentTransaction)
+			}
+			currentTransaction = nil
+			postingLines = nil
+		}
+		if currentAutomated != nil {
+			result.Automated = append(result.Automated, *currentAutomated)
+			currentAutomated = nil
+		}
+		if currentPeriodic != nil {
+			result.Periodic = append(result.Periodic, *currentPeriodic)
+			currentPeriodic = nil
+		}
+		currentDirective = nil
+	}
+
+	for state.stopped == nil && scanner.Scan() {
 		lineNumber++
 		line := scanner.Text()
 
 		trimmed := strings.TrimSpace(line)
-		// Skip empty lines and comment lines
-		if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+		if trimmed == "" {
+			continue
+		}
+
+		// Indented comments inside a transaction are notes on the posting
+		// above them, or on the transaction before its first posting
+		if strings.HasPrefix(trimmed, ";") {
+			if currentTransaction != nil && unicode.IsSpace(rune(line[0])) {
+				addNote(currentTransaction, strings.TrimSpace(trimmed[1:]))
+			}
+			continue
+		}
+
+		// Indented lines below a declaration are its sub-directives
+		if currentDirective != nil && unicode.IsSpace(rune(line[0])) {
+			state.applySubdirective(*currentDirective, trimmed)
+			continue
+		}
+
+		// Declarations and aliases end any open transaction
+		if keyword, arg, ok := parseDirectiveLine(line); ok {
+			flush()
+			decl, err := state.applyDirective(keyword, arg)
+			if err != nil {
+				addIssue(err.Error())
+				continue
+			}
+			currentDirective = decl
+			continue
+		}
+
+		// Include directives pull another file's entries in at this point
+		if target, ok := parseIncludeLine(line); ok {
+			flush()
+			includeFiles(filePath, target, including, state, addIssue)
+			continue
+		}
+
+		// Price directives add to the price history and end any open transaction
+		if strings.HasPrefix(line, "P ") || strings.HasPrefix(line, "P\t") {
+			flush()
+			price, err := parsePriceLine(line, state.year, state.formats)
+			if err != nil {
+				addIssue(err.Error())
+				continue
+			}
+			result.Prices = append(result.Prices, price)
+			continue
+		}
+
+		// Automated and periodic transactions hold postings for other transactions
+		if strings.HasPrefix(line, "=") {
+			flush()
+			auto, err := parseAutomatedLine(line)
+			if err != nil {
+				addIssue(err.Error())
+				continue
+			}
+			currentAutomated = auto
+			continue
+		}
+		if strings.HasPrefix(line, "~") {
+			flush()
+			periodic, err := parsePeriodicLine(line)
+			if err != nil {
+				addIssue(err.Error())
+				continue
+			}
+			currentPeriodic = periodic
 			continue
 		}
 
 		// Check if line starts a new transaction (starts with a digit)
 		if len(line) > 0 && unicode.IsDigit(rune(line[0])) {
 			// Save previous transaction if exists
-			if currentTransaction != nil {
-				transactions = append(transactions, *currentTransaction)
-			}
+			flush()
 
 			// Parse transaction line
-			tx, err := parseTransactionLine(line)
+			tx, err := parseTransactionLine(line, state.year)
 			if err != nil {
-				issues = append(issues, core.ParseIssue{
-					Line:    lineNumber,
-					Message: err.Error(),
-				})
-				currentTransaction = nil
+				addIssue(err.Error())
 				continue
 			}
+			tx.Bucket = state.bucket
 
 			currentTransaction = tx
 			continue
 		}
 
 		// Otherwise, it should be a posting line
-		if currentTransaction == nil {
-			issues = append(issues, core.ParseIssue{
-				Line:    lineNumber,
-				Message: "encountered posting before any transaction date",
-			})
+		if currentTransaction == nil && currentAutomated == nil && currentPeriodic == nil {
+			addIssue("encountered posting before any transaction date")
 			continue
 		}
 
-		posting, err := parsePostingLine(line)
+		posting, err := parsePostingLine(line, state.formats)
 		if err != nil {
-			issues = append(issues, core.ParseIssue{
-				Line:    lineNumber,
-				Message: err.Error(),
-			})
+			addIssue(err.Error())
 			continue
 		}
+		posting.Account = state.applyAccount(expandAlias(state.aliases, posting.Account))
+
+		switch {
+		case currentAutomated != nil:
+			currentAutomated.Postings = append(currentAutomated.Postings, *posting)
+		case currentPeriodic != nil:
+			currentPeriodic.Postings = append(currentPeriodic.Postings, *posting)
+		default:
+			currentTransaction.Postings = append(currentTransaction.Postings, *posting)
+			postingLines = append(postingLines, lineNumber)
+		}
+	}
+
+	flush()
 
-		currentTransaction.Postings = append(currentTransaction.Postings, *posting)
+	if state.stopped != nil {
+		return state.stopped
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading file: %w", err)
 	}
 
-	if currentTransaction != nil {
-		transactions = append(transactions, *currentTransaction)
+	return nil
+}
+
+// addNote attaches a comment line to the last posting of tx, or to tx itself
+// when it has no postings yet.
+func addNote(tx *core.Transaction, note string) {
+	if n := len(tx.Postings); n > 0 {
+		tx.Postings[n-1].Notes = append(tx.Postings[n-1].Notes, note)
+		return
 	}
+	tx.Notes = append(tx.Notes, note)
+}
 
-	if err := scanner.Err(); err != nil {
-		return core.ParseResult{}, fmt.Errorf("error reading file: %w", err)
+// applyTags reads the tags and metadata out of the comments of tx and its
+// postings, along with any posting dates.
+func applyTags(tx *core.Transaction) {
+	tx.Tags, tx.Metadata = core.ParseTags(append([]string{tx.Comment}, tx.Notes...)...)
+	for i := range tx.Postings {
+		posting := &tx.Postings[i]
+		comments := append([]string{posting.Comment}, posting.Notes...)
+		posting.Tags, posting.Metadata = core.ParseTags(comments...)
+		posting.Date, posting.AuxDate = core.ParseDateTag(comments...)
 	}
+}
 
-	return core.ParseResult{Transactions: transactions, Issues: issues}, nil
+// parseIncludeLine recognizes an include directive and returns its target.
+// Expected format: include PATH (or !include PATH), starting in the first column.
+func parseIncludeLine(line string) (string, bool) {
+	if len(line) == 0 || unicode.IsSpace(rune(line[0])) {
+		return "", false
+	}
+	fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+	if fields[0] != "include" && fields[0] != "!include" {
+		return "", false
+	}
+	if len(fields) < 2 {
+		return "", true
+	}
+	return strings.Trim(strings.TrimSpace(fields[1]), `"`), true
+}
+
+// includeFiles parses the files an include directive in fromPath refers to.
+// Targets are relative to the including file's directory and may be globs.
+// Problems are reported through addIssue rather than failing the whole parse.
+func includeFiles(fromPath, target string, including []string, state *parseState, addIssue func(string)) {
+	if target == "" {
+		addIssue("include directive missing a file path")
+		return
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(fromPath), target)
+	}
+
+	paths := []string{target}
+	if strings.ContainsAny(target, "*?[") {
+		matches, err := filepath.Glob(target)
+		if err != nil {
+			addIssue(fmt.Sprintf("invalid include pattern %q: %v", target, err))
+			return
+		}
+		if len(matches) == 0 {
+			addIssue(fmt.Sprintf("include pattern %q matched no files", target))
+			return
+		}
+		paths = matches
+	}
+
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			addIssue(fmt.Sprintf("failed to include %q: %v", path, err))
+			continue
+		}
+		if slices.Contains(including, absPath) {
+			addIssue(fmt.Sprintf("include cycle: %s", describeCycle(including, absPath)))
+			continue
+		}
+		if err := parseFile(path, including, state); err != nil {
+			if state.stopped != nil {
+				return
+			}
+			addIssue(fmt.Sprintf("failed to include %q: %v", path, err))
+		}
+	}
+}
+
+// describeCycle formats the chain of includes that leads back to path.
+func describeCycle(including []string, path string) string {
+	start := slices.Index(including, path)
+	names := make([]string, 0, len(including)-start+1)
+	for _, file := range including[start:] {
+		names = append(names, filepath.Base(file))
+	}
+	names = append(names, filepath.Base(path))
+	return strings.Join(names, " -> ")
+}
+
+// parsePriceLine parses a price directive.
+// Expected format: P DATE [TIME] COMMODITY PRICE [; COMMENT]
+// Prices of declared commodities are read in their declared style.
+func parsePriceLine(line string, year int, formats core.CommodityFormats) (core.MarketPrice, error) {
+	text, _ := extractComment(strings.TrimSpace(line[1:]))
+	date, rest, err := parseDate(text, year)
+	if err != nil {
+		return core.MarketPrice{}, fmt.Errorf("price directive: %w", err)
+	}
+	rest = strings.TrimSpace(rest)
+
+	// An optional time of day follows the date; prices are kept per day
+	if fields := strings.Fields(rest); len(fields) > 0 && strings.Count(fields[0], ":") >= 1 && unicode.IsDigit(rune(fields[0][0])) {
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, fields[0]))
+	}
+
+	commodity, priceText, _ := core.SplitCommodity(rest)
+	if commodity == "" || priceText == "" {
+		return core.MarketPrice{}, fmt.Errorf("price directive missing commodity or price")
+	}
+	price, err := formats.ParseAmount(priceText)
+	if err != nil {
+		return core.MarketPrice{}, fmt.Errorf("price directive has invalid price %q", priceText)
+	}
+	return core.MarketPrice{Date: date, Commodity: commodity, Price: *price}, nil
 }
 
 // parseTransactionLine parses a transaction header line.
-// Expected format: DATE [*] PAYEE [; COMMENT]
-func parseTransactionLine(line string) (*core.Transaction, error) {
+// Expected format: DATE[=AUX_DATE] [*|!] [(CODE)] PAYEE [; COMMENT]
+// Dates written without a year fall in year.
+func parseTransactionLine(line string, year int) (*core.Transaction, error) {
 	s := line
 
 	// Parse date
-	date, rest, err := parseDate(s)
+	date, rest, err := parseDate(s, year)
 	if err != nil {
 		return nil, err
 	}
 	s = rest
 
-	// Parse cleared marker
-	cleared, rest := parseCleared(s)
+	// Parse auxiliary date
+	var auxDate time.Time
+	if strings.HasPrefix(s, "=") {
+		auxDate, rest, err = parseDate(s[1:], year)
+		if err != nil {
+			return nil, fmt.Errorf("auxiliary date: %w", err)
+		}
+		s = rest
+	}
+
+	// Parse status marker and code
+	status, rest := parseStatus(s)
+	s = rest
+	code, rest := parseCode(s)
 	s = rest
 
 	// Parse payee and comment
@@ -112,47 +453,101 @@ func parseTransactionLine(line string) (*core.Transaction, error) {
 
 	return &core.Transaction{
 		Date:     date,
+		AuxDate:  auxDate,
 		Payee:    payee,
 		Comment:  comment,
-		Cleared:  cleared,
+		Status:   status,
+		Code:     code,
 		Postings: []core.Posting{},
 	}, nil
 }
 
+// parseAutomatedLine parses an automated transaction header.
+// Expected format: = PREDICATE [; COMMENT]
+func parseAutomatedLine(line string) (*core.AutomatedTransaction, error) {
+	text, comment := extractComment(strings.TrimSpace(line[1:]))
+	predicate, err := core.ParsePredicate(text)
+	if err != nil {
+		return nil, fmt.Errorf("automated transaction: %w", err)
+	}
+	return &core.AutomatedTransaction{Predicate: predicate, Comment: comment}, nil
+}
+
+// parsePeriodicLine parses a periodic transaction header.
+// Expected format: ~ PERIOD [  DESCRIPTION] [; COMMENT], with two spaces or a
+// tab before the description
+func parsePeriodicLine(line string) (*core.PeriodicTransaction, error) {
+	text, comment := extractComment(strings.TrimSpace(line[1:]))
+	period, description := text, ""
+	if idx := strings.Index(text, "  "); idx != -1 {
+		period, description = text[:idx], text[idx:]
+	}
+	if idx := strings.Index(period, "\t"); idx != -1 {
+		period, description = text[:idx], text[idx:]
+	}
+	period = strings.TrimSpace(period)
+
+	interval, start, err := core.ParsePeriod(period)
+	if err != nil {
+		return nil, fmt.Errorf("periodic transaction: %w", err)
+	}
+	return &core.PeriodicTransaction{
+		Period:      period,
+		Interval:    interval,
+		Start:       start,
+		Description: strings.TrimSpace(description),
+		Comment:     comment,
+	}, nil
+}
+
 // parseDate extracts a date from the beginning of a string.
+// The date ends at whitespace or at the "=" that introduces an auxiliary date,
+// and falls in year when it's written as just a month and day.
 // Returns the parsed date and the remaining string.
-func parseDate(s string) (time.Time, string, error) {
+func parseDate(s string, year int) (time.Time, string, error) {
 	s = strings.TrimSpace(s)
 
-	// Find the date portion (YYYY-MM-DD or YYYY/MM/DD)
-	if len(s) < 10 {
-		return time.Time{}, s, fmt.Errorf("line too short to contain a date")
+	end := strings.IndexFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '=' || r == ';' })
+	if end == -1 {
+		end = len(s)
 	}
-
-	dateStr := s[:10]
-	rest := s[10:]
-
-	// Try both date formats
-	formats := []string{"2006-01-02", "2006/01/02"}
-	for _, format := range formats {
-		if date, err := time.Parse(format, dateStr); err == nil {
-			return date, rest, nil
-		}
+	if end == 0 {
+		return time.Time{}, s, fmt.Errorf("line missing a date")
 	}
 
-	return time.Time{}, s, fmt.Errorf("unrecognized date format '%s'", dateStr)
+	date, err := core.ParseDateInYear(s[:end], year)
+	if err != nil {
+		return time.Time{}, s, err
+	}
+	return date, s[end:], nil
 }
 
-// parseCleared checks if the string starts with a cleared marker (*).
-// Returns whether it's cleared and the remaining string.
-func parseCleared(s string) (bool, string) {
+// parseStatus checks if the string starts with a cleared (*) or pending (!) marker.
+// Returns the status and the remaining string.
+func parseStatus(s string) (core.Status, string) {
 	s = strings.TrimSpace(s)
 
-	if strings.HasPrefix(s, "*") {
-		return true, strings.TrimSpace(s[1:])
+	switch {
+	case strings.HasPrefix(s, "*"):
+		return core.Cleared, strings.TrimSpace(s[1:])
+	case strings.HasPrefix(s, "!"):
+		return core.Pending, strings.TrimSpace(s[1:])
 	}
 
-	return false, s
+	return core.Uncleared, s
+}
+
+// parseCode checks if the string starts with a code in parentheses, e.g. "(1042)".
+// Returns the code and the remaining string.
+func parseCode(s string) (string, string) {
+	if !strings.HasPrefix(s, "(") {
+		return "", s
+	}
+	end := strings.Index(s, ")")
+	if end == -1 {
+		return "", s
+	}
+	return strings.TrimSpace(s[1:end]), strings.TrimSpace(s[end+1:])
 }
 
 // parsePayeeAndComment extracts the payee and optional comment from a string.
@@ -172,8 +567,10 @@ func parsePayeeAndComment(s string) (payee, comment string) {
 }
 
 // parsePostingLine parses a posting line.
-// Expected format: WHITESPACE ACCOUNT [AMOUNT] [; COMMENT]
-func parsePostingLine(line string) (*core.Posting, error) {
+// Expected format: WHITESPACE ACCOUNT [AMOUNT] [= BALANCE] [; COMMENT], where ACCOUNT may be
+// wrapped as (ACCOUNT) or [ACCOUNT] for virtual postings. Amounts of declared
+// commodities are read in their declared style.
+func parsePostingLine(line string, formats core.CommodityFormats) (*core.Posting, error) {
 	// Posting lines must start with whitespace
 	if len(line) == 0 || !unicode.IsSpace(rune(line[0])) {
 		return nil, fmt.Errorf("posting line must start with whitespace")
@@ -185,17 +582,20 @@ func parsePostingLine(line string) (*core.Posting, error) {
 	text, comment := extractComment(s)
 
 	// Parse account and amount
-	account, amount := parseAccountAndAmount(text)
+	account, posting, err := parseAccountAndAmount(text, formats)
+	if err != nil {
+		return nil, err
+	}
+	account, kind := core.ParsePostingAccount(account)
 
 	if account == "" {
 		return nil, fmt.Errorf("posting missing account name")
 	}
 
-	return &core.Posting{
-		Account: account,
-		Amount:  amount,
-		Comment: comment,
-	}, nil
+	posting.Account = account
+	posting.Kind = kind
+	posting.Comment = comment
+	return &posting, nil
 }
 
 // extractComment separates a line into text and comment parts.
@@ -211,10 +611,11 @@ func extractComment(s string) (text, comment string) {
 	return
 }
 
-// parseAccountAndAmount extracts account and amount from a posting line text.
+// parseAccountAndAmount extracts the account from a posting line text, along with a
+// posting holding its amount, lot cost, price and balance assertion.
 // Splits on the first occurrence of either: (1) two or more spaces, or (2) one or more tabs.
 // This allows account names to contain single spaces.
-func parseAccountAndAmount(s string) (string, string) {
+func parseAccountAndAmount(s string, formats core.CommodityFormats) (string, core.Posting, error) {
 	s = strings.TrimSpace(s)
 
 	// Find the first occurrence of either 2+ spaces or 1+ tabs
@@ -234,85 +635,43 @@ func parseAccountAndAmount(s string) (string, string) {
 
 	if splitIdx == -1 {
 		// No separator found, entire string is the account
-		return s, ""
+		return s, core.Posting{}, nil
 	}
 
 	potentialAccount := strings.TrimSpace(s[:splitIdx])
 	potentialAmount := strings.TrimSpace(s[splitIdx:])
 
-	// Check if what we found after the separator looks like an amount
-	if isAmount(potentialAmount) {
-		return potentialAccount, cleanAmount(potentialAmount)
-	}
-
-	// Otherwise, entire string is the account
-	return s, ""
-}
-
-// isAmount checks if a string looks like a monetary amount.
-// Valid formats:
-//   - $ 123.45, $123.45 (dollar with optional space before digits)
-//   - -$ 123.45, -$123.45 (negative before dollar)
-//   - $ -123.45, $-123.45 (dollar before negative)
-//   - 123.45, -123.45, +123.45 (plain numbers)
-//
-// Invalid formats:
-//   - $- 123.45 (space between sign and digits)
-//   - $12 3.45 (space within digits)
-func isAmount(s string) bool {
-	if s == "" {
-		return false
-	}
-
-	s = strings.TrimSpace(s)
-	i := 0
-
-	// Track if we've seen a sign or dollar
-	hasLeadingSign := false
-	hasDollar := false
-
-	// Check for optional leading sign
-	if i < len(s) && (s[i] == '-' || s[i] == '+') {
-		hasLeadingSign = true
-		i++
-	}
-
-	// Check for optional dollar sign
-	if i < len(s) && s[i] == '$' {
-		hasDollar = true
-		i++
-	}
-
-	// Skip optional whitespace after dollar sign
-	if hasDollar {
-		for i < len(s) && s[i] == ' ' {
-			i++
+	// A balance assertion or assignment follows an "=", with the amount before it optional
+	if amountText, assertionText, ok := strings.Cut(potentialAmount, "="); ok {
+		assertion, err := formats.ParseAmount(assertionText)
+		if err != nil {
+			return "", core.Posting{}, fmt.Errorf("invalid balance assertion %q", strings.TrimSpace(assertionText))
+		}
+		posting := core.Posting{Assertion: assertion}
+		if amountText = strings.TrimSpace(amountText); amountText != "" {
+			posting.Amount, posting.Lot, posting.Price, err = formats.ParseAnnotatedAmount(amountText)
+			if err != nil {
+				return "", core.Posting{}, fmt.Errorf("invalid amount %q", amountText)
+			}
 		}
+		return potentialAccount, posting, nil
 	}
 
-	// Check for optional sign (if not already present)
-	if !hasLeadingSign && i < len(s) && (s[i] == '-' || s[i] == '+') {
-		i++
+	// Check if what we found after the separator is an amount, such as
+	// $ 123.45, -$123.45, $-123.45, 123.45 EUR or 10 AAPL, optionally
+	// followed by a lot cost ({$148.00}) and price (@ $150.00 or @@ $1500).
+	// Amounts with a space between the sign and digits ($- 123.45) or
+	// within the digits ($12 3.45) are not.
+	if amount, lot, price, err := formats.ParseAnnotatedAmount(potentialAmount); err == nil {
+		return potentialAccount, core.Posting{Amount: amount, Lot: lot, Price: price}, nil
 	}
 
-	// Now we must have digits, and no more whitespace is allowed
-	hasDigit := false
-	for i < len(s) {
-		r := rune(s[i])
-		if unicode.IsDigit(r) || r == '.' {
-			hasDigit = true
-			i++
-		} else {
-			// Invalid character (including whitespace within digits)
-			return false
-		}
+	// Digits after the separator are a mistyped amount rather than part of
+	// the account
+	if strings.ContainsAny(potentialAmount, "0123456789") {
+		return "", core.Posting{}, fmt.Errorf("invalid amount %q", potentialAmount)
 	}
 
-	return hasDigit
-}
-
-// cleanAmount removes currency symbols from an amount string.
-func cleanAmount(s string) string {
-	clean := strings.ReplaceAll(s, "$", "")
-	return strings.ReplaceAll(clean, " ", "")
+	// Otherwise, entire string is the account
+	return s, core.Posting{}, nil
 }
@@ -3,8 +3,14 @@ package parser
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"github.com/shopspring/decimal"
 )
 
 func TestParseFile(t *testing.T) {
@@ -38,7 +44,7 @@ func TestParseFile(t *testing.T) {
 			t.Errorf("Expected payee 'Super Grocery Store', got '%s'", tx.Payee)
 		}
 
-		if !tx.Cleared {
+		if tx.Status != core.Cleared {
 			t.Errorf("expected first transaction to be cleared")
 		}
 
@@ -54,7 +60,7 @@ func TestParseFile(t *testing.T) {
 			if tx.Postings[0].Account != "Expenses:Food:Groceries" {
 				t.Errorf("Expected first account 'Expenses:Food:Groceries', got '%s'", tx.Postings[0].Account)
 			}
-			if tx.Postings[0].Amount != "85.42" {
+			if tx.Postings[0].Amount.String() != "85.42" {
 				t.Errorf("Expected first amount '85.42', got '%s'", tx.Postings[0].Amount)
 			}
 			if tx.Postings[0].Comment != "Pantry staples" {
@@ -98,7 +104,7 @@ func TestParseFile(t *testing.T) {
 		if tx.Payee != "Online Purchase" {
 			t.Fatalf("expected sixth transaction to be Online Purchase, got %s", tx.Payee)
 		}
-		if tx.Cleared {
+		if tx.Status == core.Cleared {
 			t.Errorf("expected Online Purchase to be not cleared")
 		}
 		if tx.Comment != "Awaiting shipment" {
@@ -137,14 +143,14 @@ func TestParsePostingAmountWithCurrencyBeforeSign(t *testing.T) {
 		t.Fatalf("expected 3 postings, got %d", len(tx.Postings))
 	}
 
-	if tx.Postings[0].Amount != "" {
+	if tx.Postings[0].Amount != nil {
 		t.Errorf("expected first posting amount to be elided, got %q", tx.Postings[0].Amount)
 	}
-	if tx.Postings[1].Amount != "14.10" {
-		t.Errorf("expected second posting amount to be 14.10, got %q", tx.Postings[1].Amount)
+	if tx.Postings[1].Amount.String() != "$14.10" {
+		t.Errorf("expected second posting amount to be $14.10, got %q", tx.Postings[1].Amount)
 	}
-	if tx.Postings[2].Amount != "-91.41" {
-		t.Errorf("expected third posting amount to be -91.41, got %q", tx.Postings[2].Amount)
+	if tx.Postings[2].Amount.String() != "$-91.41" {
+		t.Errorf("expected third posting amount to be $-91.41, got %q", tx.Postings[2].Amount)
 	}
 }
 
@@ -159,35 +165,35 @@ func TestParseAmountWithWhitespace(t *testing.T) {
 			ledgerContent: "2025/01/01 * Test\n" +
 				"    Expenses:Test  $ 123.45\n" +
 				"    Assets:Cash\n",
-			expectedAmount: "123.45",
+			expectedAmount: "$ 123.45",
 		},
 		{
 			name: "dollar sign without space",
 			ledgerContent: "2025/01/01 * Test\n" +
 				"    Expenses:Test  $123.45\n" +
 				"    Assets:Cash\n",
-			expectedAmount: "123.45",
+			expectedAmount: "$123.45",
 		},
 		{
 			name: "negative sign before dollar sign with space",
 			ledgerContent: "2025/01/01 * Test\n" +
 				"    Expenses:Test  -$ 123.45\n" +
 				"    Assets:Cash\n",
-			expectedAmount: "-123.45",
+			expectedAmount: "$ -123.45",
 		},
 		{
 			name: "dollar sign before negative sign no space",
 			ledgerContent: "2025/01/01 * Test\n" +
 				"    Expenses:Test  $-123.45\n" +
 				"    Assets:Cash\n",
-			expectedAmount: "-123.45",
+			expectedAmount: "$-123.45",
 		},
 		{
 			name: "dollar sign with space before negative sign and digits",
 			ledgerContent: "2025/01/01 * Test\n" +
 				"    Expenses:Test  $ -123.45\n" +
 				"    Assets:Cash\n",
-			expectedAmount: "-123.45",
+			expectedAmount: "$ -123.45",
 		},
 		{
 			name: "plain negative amount no dollar sign",
@@ -208,7 +214,7 @@ func TestParseAmountWithWhitespace(t *testing.T) {
 			ledgerContent: "2025/01/01 * Test\n" +
 				"    Expenses:Test  +$123.45\n" +
 				"    Assets:Cash\n",
-			expectedAmount: "+123.45",
+			expectedAmount: "$123.45",
 		},
 	}
 
@@ -238,8 +244,8 @@ func TestParseAmountWithWhitespace(t *testing.T) {
 				t.Fatalf("expected at least 1 posting, got %d", len(tx.Postings))
 			}
 
-			if tx.Postings[0].Amount != tt.expectedAmount {
-				t.Errorf("expected amount %q, got %q", tt.expectedAmount, tx.Postings[0].Amount)
+			if tx.Postings[0].Amount == nil || tx.Postings[0].Amount.String() != tt.expectedAmount {
+				t.Errorf("expected amount %q, got %v", tt.expectedAmount, tx.Postings[0].Amount)
 			}
 		})
 	}
@@ -289,10 +295,831 @@ func TestParseInvalidAmountsWithWhitespace(t *testing.T) {
 			if len(tx.Postings) > 0 {
 				// If parsed as account with amount, the amount should be empty
 				// or it should be reported as an issue
-				if tx.Postings[0].Amount != "" && len(result.Issues) == 0 {
+				if tx.Postings[0].Amount != nil && len(result.Issues) == 0 {
 					t.Errorf("expected invalid amount to be rejected or treated as account, but got amount: %q", tx.Postings[0].Amount)
 				}
 			}
 		})
 	}
 }
+
+func TestParseFileFollowsIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.ledger": "include years/2023.ledger\n" +
+			"2025/01/05 * Corner Store\n" +
+			"    Expenses:Food  $10.00\n" +
+			"    Assets:Cash\n" +
+			"include \"years/202[45].ledger\"\n",
+		"years/2023.ledger": "2023/03/01 * Old Shop\n" +
+			"    Expenses:Misc  $1.00\n" +
+			"    Assets:Cash\n",
+		"years/2024.ledger": "include ../shared/*.ledger\n" +
+			"2024/06/01 * Hardware\n" +
+			"    Expenses:Home  $5.00\n" +
+			"    Assets:Cash\n",
+		"years/2025.ledger": "2025/02/01 * Bakery\n" +
+			"  Expenses:Food\n" +
+			"2025/02/x1 * Broken\n",
+		"shared/a.ledger": "2024/01/01 * Shared Entry\n" +
+			"    Expenses:Misc  $2.00\n" +
+			"    Assets:Cash\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	result, err := ParseFile(filepath.Join(dir, "main.ledger"))
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+
+	var payees []string
+	for _, tx := range result.Transactions {
+		payees = append(payees, tx.Payee)
+	}
+	expected := []string{"Old Shop", "Corner Store", "Shared Entry", "Hardware", "Bakery"}
+	if !reflect.DeepEqual(payees, expected) {
+		t.Fatalf("expected transactions %v in include order, got %v", expected, payees)
+	}
+
+	if len(result.Issues) != 1 {
+		t.Fatalf("expected 1 issue, got %d: %+v", len(result.Issues), result.Issues)
+	}
+	issue := result.Issues[0]
+	if issue.File != filepath.Join(dir, "years", "2025.ledger") || issue.Line != 3 {
+		t.Fatalf("expected issue to point at years/2025.ledger line 3, got %s", issue.Location())
+	}
+}
+
+func TestParseFileReportsIncludeProblems(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.ledger": "include a.ledger\n" +
+			"include missing.ledger\n" +
+			"include none-*.ledger\n",
+		"a.ledger": "2025/01/01 * A\n" +
+			"    Expenses:Misc  $1.00\n" +
+			"    Assets:Cash\n" +
+			"include b.ledger\n",
+		"b.ledger": "include a.ledger\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	mainPath := filepath.Join(dir, "main.ledger")
+	result, err := ParseFile(mainPath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected the cycle to be parsed once, got %d transactions", len(result.Transactions))
+	}
+
+	if len(result.Issues) != 3 {
+		t.Fatalf("expected 3 issues, got %d: %+v", len(result.Issues), result.Issues)
+	}
+	cycle := result.Issues[0]
+	if cycle.File != filepath.Join(dir, "b.ledger") || cycle.Message != "include cycle: a.ledger -> b.ledger -> a.ledger" {
+		t.Errorf("unexpected cycle issue: %s: %s", cycle.Location(), cycle.Message)
+	}
+	if missing := result.Issues[1]; missing.File != mainPath || missing.Line != 2 || !strings.Contains(missing.Message, "missing.ledger") {
+		t.Errorf("unexpected missing-file issue: %s: %s", missing.Location(), missing.Message)
+	}
+	if glob := result.Issues[2]; glob.Line != 3 || !strings.Contains(glob.Message, "matched no files") {
+		t.Errorf("unexpected glob issue: %s: %s", glob.Location(), glob.Message)
+	}
+}
+
+func TestParsePostingCostsRoundTrip(t *testing.T) {
+	ledger := "2025/03/03 * Broker\n" +
+		"    Assets:Brokerage        10 AAPL {$148.00} @ $150.00\n" +
+		"    Assets:Euro             €200 @@ $218.50  ; holiday cash\n" +
+		"    Assets:Checking\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "broker.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 0 || len(result.Transactions) != 1 {
+		t.Fatalf("expected one transaction without issues, got %d and %+v", len(result.Transactions), result.Issues)
+	}
+
+	postings := result.Transactions[0].Postings
+	if postings[0].Lot == nil || postings[0].Lot.LotString() != "{$148.00}" {
+		t.Errorf("expected lot cost {$148.00}, got %v", postings[0].Lot)
+	}
+	if postings[0].Price == nil || postings[0].Price.String() != "@ $150.00" {
+		t.Errorf("expected price @ $150.00, got %v", postings[0].Price)
+	}
+	if postings[1].Price == nil || !postings[1].Price.Total || postings[1].Comment != "holiday cash" {
+		t.Errorf("expected total price with comment, got %+v", postings[1])
+	}
+
+	// Writing the transaction back and parsing it again keeps the costs
+	written := filepath.Join(dir, "written.ledger")
+	if err := os.WriteFile(written, []byte(result.Transactions[0].String()), 0o600); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	reparsed, err := ParseFile(written)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	for i, posting := range reparsed.Transactions[0].Postings {
+		if !posting.Equal(postings[i]) {
+			t.Errorf("posting %d changed on round trip: %+v != %+v", i, posting, postings[i])
+		}
+	}
+}
+
+func TestParsePriceDirectives(t *testing.T) {
+	ledger := "P 2025/01/15 AAPL $182.31\n" +
+		"P 2025-01-16 12:30:00 EUR 1.08 USD ; afternoon rate\n" +
+		"2025/01/16 * Broker\n" +
+		"    Assets:Brokerage        1 AAPL\n" +
+		"    Assets:Checking\n" +
+		"P 2025/01/17 \"Gold Coin\" $1900\n" +
+		"P 2025/01/17 AAPL\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "prices.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 || len(result.Transactions[0].Postings) != 2 {
+		t.Fatalf("expected the transaction to end at the next price, got %+v", result.Transactions)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Line != 7 {
+		t.Fatalf("expected one issue for the incomplete directive, got %+v", result.Issues)
+	}
+
+	if len(result.Prices) != 3 {
+		t.Fatalf("expected 3 prices, got %d: %+v", len(result.Prices), result.Prices)
+	}
+	expected := []struct {
+		date      string
+		commodity string
+		price     string
+	}{
+		{date: "2025-01-15", commodity: "AAPL", price: "$182.31"},
+		{date: "2025-01-16", commodity: "EUR", price: "1.08 USD"},
+		{date: "2025-01-17", commodity: "Gold Coin", price: "$1900"},
+	}
+	for i, want := range expected {
+		got := result.Prices[i]
+		if got.Date.Format("2006-01-02") != want.date || got.Commodity != want.commodity || got.Price.String() != want.price {
+			t.Errorf("price %d: expected %+v, got %s %s %s", i, want, got.Date.Format("2006-01-02"), got.Commodity, got.Price)
+		}
+	}
+}
+
+func TestParseCommentsTagsAndMetadata(t *testing.T) {
+	ledger := "; file comment\n" +
+		"2025/03/04 * Airline  ; Conference :travel:\n" +
+		"    ; Booked online\n" +
+		"    ; Trip: Berlin\n" +
+		"    Expenses:Travel        $300.00  ; :work:reimbursable:\n" +
+		"        ; Receipt: emailed\n" +
+		"    Assets:Checking\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tags.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 0 || len(result.Transactions) != 1 {
+		t.Fatalf("expected one transaction without issues, got %d and %+v", len(result.Transactions), result.Issues)
+	}
+
+	tx := result.Transactions[0]
+	if !reflect.DeepEqual(tx.Notes, []string{"Booked online", "Trip: Berlin"}) {
+		t.Errorf("unexpected transaction notes: %q", tx.Notes)
+	}
+	if !reflect.DeepEqual(tx.Tags, []string{"travel"}) || tx.Metadata["Trip"] != "Berlin" {
+		t.Errorf("unexpected transaction tags %v and metadata %v", tx.Tags, tx.Metadata)
+	}
+	posting := tx.Postings[0]
+	if !reflect.DeepEqual(posting.Tags, []string{"work", "reimbursable"}) || posting.Metadata["Receipt"] != "emailed" {
+		t.Errorf("unexpected posting tags %v and metadata %v", posting.Tags, posting.Metadata)
+	}
+	if len(tx.Postings[1].Notes) != 0 {
+		t.Errorf("expected no notes on the last posting, got %q", tx.Postings[1].Notes)
+	}
+
+	// Writing the transaction back and parsing it again keeps the comments
+	written := filepath.Join(dir, "written.ledger")
+	if err := os.WriteFile(written, []byte(tx.String()), 0o600); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	reparsed, err := ParseFile(written)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	again := reparsed.Transactions[0]
+	if !reflect.DeepEqual(again.Notes, tx.Notes) || !reflect.DeepEqual(again.Tags, tx.Tags) || !reflect.DeepEqual(again.Metadata, tx.Metadata) {
+		t.Errorf("transaction comments changed on round trip: %+v", again)
+	}
+	for i, posting := range again.Postings {
+		if !posting.Equal(tx.Postings[i]) {
+			t.Errorf("posting %d changed on round trip: %+v != %+v", i, posting, tx.Postings[i])
+		}
+	}
+}
+
+func TestParseStatusAndCode(t *testing.T) {
+	ledger := "2025-01-20 ! (1042) Landlord  ; January rent\n" +
+		"    Expenses:Rent           $1500.00\n" +
+		"    Assets:Checking\n" +
+		"2025-01-21 * (ATM) Cash\n" +
+		"    Expenses:Cash           $40.00\n" +
+		"    Assets:Checking\n" +
+		"2025-01-22 (Lunch) Cafe\n" +
+		"    Expenses:Food           $12.00\n" +
+		"    Assets:Checking\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "status.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 0 || len(result.Transactions) != 3 {
+		t.Fatalf("expected three transactions without issues, got %d and %+v", len(result.Transactions), result.Issues)
+	}
+
+	expected := []struct {
+		status core.Status
+		code   string
+		payee  string
+	}{
+		{status: core.Pending, code: "1042", payee: "Landlord"},
+		{status: core.Cleared, code: "ATM", payee: "Cash"},
+		{status: core.Uncleared, code: "Lunch", payee: "Cafe"},
+	}
+	for i, want := range expected {
+		tx := result.Transactions[i]
+		if tx.Status != want.status || tx.Code != want.code || tx.Payee != want.payee {
+			t.Errorf("transaction %d: expected %+v, got status=%v code=%q payee=%q", i, want, tx.Status, tx.Code, tx.Payee)
+		}
+	}
+
+	// Writing the transactions back and parsing them again keeps the status and code
+	var written strings.Builder
+	for _, tx := range result.Transactions {
+		written.WriteString(tx.String())
+	}
+	writtenPath := filepath.Join(dir, "written.ledger")
+	if err := os.WriteFile(writtenPath, []byte(written.String()), 0o600); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	reparsed, err := ParseFile(writtenPath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	for i, tx := range reparsed.Transactions {
+		want := result.Transactions[i]
+		if tx.Status != want.Status || tx.Code != want.Code || tx.Payee != want.Payee || tx.Comment != want.Comment {
+			t.Errorf("transaction %d changed on round trip: %+v", i, tx)
+		}
+	}
+}
+
+func TestParseAuxiliaryDates(t *testing.T) {
+	ledger := "2025/01/15=2025/01/17 * Card Purchase\n" +
+		"    Expenses:Food           $12.00  ; [=2025/01/18] lunch\n" +
+		"    Liabilities:Card\n" +
+		"        ; [2025/01/16]\n" +
+		"2025/1/20=bad Broken\n" +
+		"    Expenses:Food           $1.00\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "aux.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected one transaction, got %d", len(result.Transactions))
+	}
+	if len(result.Issues) != 2 || !strings.Contains(result.Issues[0].Message, "auxiliary date") {
+		t.Fatalf("expected an auxiliary date issue and an orphan posting, got %+v", result.Issues)
+	}
+
+	tx := result.Transactions[0]
+	if tx.Date.Format("2006-01-02") != "2025-01-15" || tx.AuxDate.Format("2006-01-02") != "2025-01-17" || tx.Payee != "Card Purchase" {
+		t.Fatalf("unexpected header dates: %v=%v %q", tx.Date, tx.AuxDate, tx.Payee)
+	}
+	if got := tx.Postings[0].AuxDate.Format("2006-01-02"); got != "2025-01-18" || !tx.Postings[0].Date.IsZero() {
+		t.Errorf("expected posting auxiliary date 2025-01-18, got %v=%s", tx.Postings[0].Date, got)
+	}
+	if got := tx.Postings[1].Date.Format("2006-01-02"); got != "2025-01-16" {
+		t.Errorf("expected posting date from its note, got %s", got)
+	}
+
+	// Writing the transaction back and parsing it again keeps the dates
+	written := filepath.Join(dir, "written.ledger")
+	if err := os.WriteFile(written, []byte(tx.String()), 0o600); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	reparsed, err := ParseFile(written)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	again := reparsed.Transactions[0]
+	if !again.AuxDate.Equal(tx.AuxDate) {
+		t.Errorf("auxiliary date changed on round trip: %v", again.AuxDate)
+	}
+	for i, posting := range again.Postings {
+		if !posting.Equal(tx.Postings[i]) {
+			t.Errorf("posting %d changed on round trip: %+v != %+v", i, posting, tx.Postings[i])
+		}
+	}
+}
+
+func TestParseVirtualPostings(t *testing.T) {
+	ledger := "2025/01/15 Grocery Store\n" +
+		"    Expenses:Food           $40.00\n" +
+		"    Assets:Checking\n" +
+		"    (Budget:Food)           $-40.00\n" +
+		"    [Savings:Goal]          $10.00\n" +
+		"    [Assets:Checking]       $-10.00\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "virtual.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 || len(result.Issues) != 0 {
+		t.Fatalf("expected one clean transaction, got %d and issues %+v", len(result.Transactions), result.Issues)
+	}
+
+	postings := result.Transactions[0].Postings
+	expected := []struct {
+		account string
+		kind    core.PostingKind
+	}{
+		{"Expenses:Food", core.RealPosting},
+		{"Assets:Checking", core.RealPosting},
+		{"Budget:Food", core.VirtualPosting},
+		{"Savings:Goal", core.BalancedVirtualPosting},
+		{"Assets:Checking", core.BalancedVirtualPosting},
+	}
+	for i, want := range expected {
+		if postings[i].Account != want.account || postings[i].Kind != want.kind {
+			t.Errorf("posting %d: expected %s (%d), got %s (%d)", i, want.account, want.kind, postings[i].Account, postings[i].Kind)
+		}
+	}
+
+	// Writing the transaction back keeps the brackets
+	written := result.Transactions[0].String()
+	for _, account := range []string{"(Budget:Food)", "[Savings:Goal]", "[Assets:Checking]"} {
+		if !strings.Contains(written, account) {
+			t.Errorf("expected %s in written transaction:\n%s", account, written)
+		}
+	}
+}
+
+func TestParseBalanceAssertions(t *testing.T) {
+	ledger := "2025/01/01 Opening Balance\n" +
+		"    Assets:Checking         $1,284.56\n" +
+		"    Equity:Opening\n" +
+		"\n" +
+		"2025/01/15 Grocery Store\n" +
+		"    Expenses:Food           $50.00\n" +
+		"    Assets:Checking         $-50.00 = $1,234.56\n" +
+		"\n" +
+		"2025/01/20 Coffee\n" +
+		"    Expenses:Food           $4.00\n" +
+		"    Assets:Checking         $-4.00 = $1,234.56  ; off by four\n" +
+		"\n" +
+		"2025/01/31 Reconcile\n" +
+		"    Assets:Checking         = $1,200.00\n" +
+		"    Expenses:Misc\n" +
+		"\n" +
+		"2025/02/01 Check\n" +
+		"    Expenses:Misc           $1.00\n" +
+		"    Assets:Checking         $-1.00 = $1,199.00\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "assertions.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Transactions) != 5 {
+		t.Fatalf("expected five transactions, got %d", len(result.Transactions))
+	}
+	if len(result.Issues) != 1 {
+		t.Fatalf("expected one failed assertion, got %+v", result.Issues)
+	}
+	issue := result.Issues[0]
+	if issue.Line != 11 || issue.Message != "balance assertion failed for Assets:Checking: expected $1,234.56, got $1,230.56" {
+		t.Fatalf("unexpected issue: %+v", issue)
+	}
+
+	posting := result.Transactions[1].Postings[1]
+	if posting.Amount.String() != "$-50.00" || posting.Assertion.String() != "$1,234.56" {
+		t.Fatalf("unexpected assertion posting: %+v", posting)
+	}
+	assignment := result.Transactions[3].Postings[0]
+	if assignment.Amount != nil || !assignment.IsAssignment() || assignment.Assertion.String() != "$1,200.00" {
+		t.Fatalf("expected a balance assignment, got %+v", assignment)
+	}
+
+	// Writing the transactions back and parsing them again keeps the assertions
+	var written strings.Builder
+	for _, tx := range result.Transactions {
+		written.WriteString(tx.String() + "\n")
+	}
+	writtenPath := filepath.Join(dir, "written.ledger")
+	if err := os.WriteFile(writtenPath, []byte(written.String()), 0o600); err != nil {
+		t.Fatalf("failed to write ledger: %v", err)
+	}
+	reparsed, err := ParseFile(writtenPath)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(reparsed.Transactions) != len(result.Transactions) || len(reparsed.Issues) != 1 {
+		t.Fatalf("expected the written journal to parse the same way, got %d transactions and issues %+v", len(reparsed.Transactions), reparsed.Issues)
+	}
+	for i, tx := range reparsed.Transactions {
+		for j, posting := range tx.Postings {
+			if !posting.Equal(result.Transactions[i].Postings[j]) {
+				t.Errorf("transaction %d posting %d changed after writing:\n%s", i, j, written.String())
+			}
+		}
+	}
+}
+
+func TestParseRejectsInvalidBalanceAssertion(t *testing.T) {
+	ledger := "2025/01/15 Grocery Store\n" +
+		"    Expenses:Food           $50.00\n" +
+		"    Assets:Checking         $-50.00 = lots\n"
+
+	path := filepath.Join(t.TempDir(), "invalid.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].Line != 3 || !strings.Contains(result.Issues[0].Message, "invalid balance assertion") {
+		t.Fatalf("expected an invalid assertion issue on line 3, got %+v", result.Issues)
+	}
+}
+
+func TestParseAutomatedAndPeriodicTransactions(t *testing.T) {
+	ledger := "= /^Expenses:Food/  ; envelope budgeting\n" +
+		"    (Budget:Food)           -1\n" +
+		"\n" +
+		"~ Monthly from 2025/01/01  Rent\n" +
+		"    Expenses:Rent           $1,500.00\n" +
+		"    Assets:Checking\n" +
+		"\n" +
+		"~ Every 2 weeks\n" +
+		"    Assets:Checking         $2,000.00\n" +
+		"    Income:Salary\n" +
+		"\n" +
+		"~ Whenever\n" +
+		"    Expenses:Misc           $1.00\n" +
+		"\n" +
+		"2025/01/15 Grocery Store\n" +
+		"    Expenses:Food           $40.00\n" +
+		"    Assets:Checking\n"
+
+	path := filepath.Join(t.TempDir(), "budget.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 || len(result.Transactions[0].Postings) != 2 {
+		t.Fatalf("expected one ordinary transaction, got %+v", result.Transactions)
+	}
+	if len(result.Issues) != 2 || result.Issues[0].Line != 12 || !strings.Contains(result.Issues[0].Message, "unrecognized period") {
+		t.Fatalf("expected an unrecognized period and an orphan posting, got %+v", result.Issues)
+	}
+
+	if len(result.Automated) != 1 {
+		t.Fatalf("expected one automated transaction, got %d", len(result.Automated))
+	}
+	auto := result.Automated[0]
+	if auto.Predicate.Text != "/^Expenses:Food/" || auto.Comment != "envelope budgeting" || len(auto.Postings) != 1 {
+		t.Fatalf("unexpected automated transaction: %+v", auto)
+	}
+	if posting := auto.Postings[0]; posting.Account != "Budget:Food" || posting.Kind != core.VirtualPosting || posting.Amount.String() != "-1" {
+		t.Fatalf("unexpected automated posting: %+v", posting)
+	}
+
+	if len(result.Periodic) != 2 {
+		t.Fatalf("expected two periodic transactions, got %d", len(result.Periodic))
+	}
+	rent := result.Periodic[0]
+	if rent.Period != "Monthly from 2025/01/01" || rent.Description != "Rent" || rent.Interval != (core.Interval{Months: 1}) {
+		t.Fatalf("unexpected periodic transaction: %+v", rent)
+	}
+	if rent.Start.Format("2006-01-02") != "2025-01-01" || len(rent.Postings) != 2 {
+		t.Fatalf("unexpected periodic start or postings: %+v", rent)
+	}
+	if payroll := result.Periodic[1]; payroll.Interval != (core.Interval{Days: 14}) || payroll.Description != "" {
+		t.Fatalf("unexpected periodic transaction: %+v", payroll)
+	}
+}
+
+func TestParseDeclarationsAndAliases(t *testing.T) {
+	ledger := "account Expenses:Food:Groceries  ; weekly shop\n" +
+		"    note Supermarkets and markets\n" +
+		"    alias groceries\n" +
+		"account Assets:Checking\n" +
+		"payee Costco\n" +
+		"commodity $\n" +
+		"    format $1,000.00\n" +
+		"commodity 1,000.00 AAPL\n" +
+		"alias checking=Assets:Checking\n" +
+		"\n" +
+		"2025/01/15 Costco\n" +
+		"    groceries               $40.00\n" +
+		"    checking:Joint\n" +
+		"\n" +
+		"end aliases\n" +
+		"2025/01/16 Costco\n" +
+		"    Expenses:Food:Groceries $10.00\n" +
+		"    checking\n" +
+		"alias broken\n" +
+		"end apply tag\n"
+
+	path := filepath.Join(t.TempDir(), "declarations.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 2 || result.Issues[0].Line != 19 || result.Issues[1].Line != 20 {
+		t.Fatalf("expected issues for the broken alias and unknown end directive, got %+v", result.Issues)
+	}
+	if !slices.Equal(result.Accounts, []string{"Expenses:Food:Groceries", "Assets:Checking"}) {
+		t.Errorf("unexpected declared accounts: %v", result.Accounts)
+	}
+	if !slices.Equal(result.Payees, []string{"Costco"}) {
+		t.Errorf("unexpected declared payees: %v", result.Payees)
+	}
+	expectedCommodities := []core.Commodity{{Symbol: "$", Format: "$1,000.00"}, {Symbol: "AAPL", Format: "1,000.00 AAPL"}}
+	if !slices.Equal(result.Commodities, expectedCommodities) {
+		t.Errorf("unexpected declared commodities: %+v", result.Commodities)
+	}
+
+	if len(result.Transactions) != 2 {
+		t.Fatalf("expected two transactions, got %d", len(result.Transactions))
+	}
+	first := result.Transactions[0].Postings
+	if first[0].Account != "Expenses:Food:Groceries" || first[1].Account != "Assets:Checking:Joint" {
+		t.Errorf("expected aliases to expand, got %s and %s", first[0].Account, first[1].Account)
+	}
+	if second := result.Transactions[1].Postings; second[1].Account != "checking" {
+		t.Errorf("expected aliases to end, got %s", second[1].Account)
+	}
+}
+
+func TestParseAmountsInDeclaredFormats(t *testing.T) {
+	ledger := "commodity €\n" +
+		"    format 1.000,00 €\n" +
+		"commodity $1,000.00\n" +
+		"\n" +
+		"P 2025/01/01 AAPL 182,31 €\n" +
+		"\n" +
+		"2025/01/15 Bäckerei\n" +
+		"    Expenses:Food           12,50 €\n" +
+		"    Expenses:Rent           1.234,56 €\n" +
+		"    Expenses:Misc           $1,000.00\n" +
+		"    Assets:Bank             -1.247,06 € = -1.247,06 €\n" +
+		"    Assets:Checking\n"
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "formats.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 0 {
+		t.Fatalf("unexpected issues: %+v", result.Issues)
+	}
+	if len(result.Prices) != 1 || result.Prices[0].Price.String() != "182,31 €" {
+		t.Fatalf("expected a price with a decimal comma, got %+v", result.Prices)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected one transaction, got %d", len(result.Transactions))
+	}
+	tx := result.Transactions[0]
+	want := []struct{ account, quantity, amount string }{
+		{"Expenses:Food", "12.5", "12,50 €"},
+		{"Expenses:Rent", "1234.56", "1.234,56 €"},
+		{"Expenses:Misc", "1000", "$1,000.00"},
+		{"Assets:Bank", "-1247.06", "-1.247,06 €"},
+	}
+	for i, w := range want {
+		posting := tx.Postings[i]
+		if posting.Account != w.account || posting.Amount == nil || !posting.Amount.Quantity.Equal(decimal.RequireFromString(w.quantity)) {
+			t.Fatalf("posting %d: expected %s %s, got %+v", i, w.account, w.quantity, posting)
+		}
+		if got := posting.Amount.String(); got != w.amount {
+			t.Errorf("posting %d: expected to write %q, got %q", i, w.amount, got)
+		}
+	}
+
+	// Writing the transaction back and parsing it again keeps the amounts
+	rewritten := filepath.Join(dir, "rewritten.ledger")
+	if err := os.WriteFile(rewritten, []byte("commodity €\n    format 1.000,00 €\n\n"+tx.String()), 0o600); err != nil {
+		t.Fatalf("failed to write rewritten ledger: %v", err)
+	}
+	reparsed, err := ParseFile(rewritten)
+	if err != nil || len(reparsed.Issues) != 0 || len(reparsed.Transactions) != 1 {
+		t.Fatalf("failed to reparse %q: %v %+v", tx.String(), err, reparsed.Issues)
+	}
+	for i, posting := range reparsed.Transactions[0].Postings {
+		if !posting.Equal(tx.Postings[i]) {
+			t.Errorf("posting %d changed when written back: %+v, want %+v", i, posting, tx.Postings[i])
+		}
+	}
+}
+
+func TestParseYearApplyAccountAndBucket(t *testing.T) {
+	ledger := "year 2019\n" +
+		"bucket Assets:Checking\n" +
+		"\n" +
+		"01/15 Coffee Shop\n" +
+		"    Expenses:Coffee         $4.00 = $4.00\n" +
+		"\n" +
+		"apply account Business\n" +
+		"account Expenses:Travel\n" +
+		"01/20=01/22 * Airline\n" +
+		"    Expenses:Travel         $300.00\n" +
+		"    Liabilities:Card\n" +
+		"apply account Client\n" +
+		"01/21 Hotel\n" +
+		"    Expenses:Lodging        $120.00\n" +
+		"end apply account\n" +
+		"end apply\n" +
+		"Y2020\n" +
+		"A Assets:Cash\n" +
+		"02/01 Market\n" +
+		"    Expenses:Food           $10.00\n" +
+		"end apply account\n" +
+		"apply tag trip\n"
+
+	path := filepath.Join(t.TempDir(), "blocks.ledger")
+	if err := os.WriteFile(path, []byte(ledger), 0o600); err != nil {
+		t.Fatalf("failed to write temp ledger: %v", err)
+	}
+
+	result, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile returned error: %v", err)
+	}
+	if len(result.Issues) != 2 || result.Issues[0].Line != 21 || result.Issues[1].Line != 22 {
+		t.Fatalf("expected issues for the unmatched end and unsupported apply, got %+v", result.Issues)
+	}
+	if len(result.Transactions) != 4 {
+		t.Fatalf("expected four transactions, got %d", len(result.Transactions))
+	}
+
+	expected := []struct {
+		date     string
+		bucket   string
+		accounts []string
+	}{
+		{"2019-01-15", "Assets:Checking", []string{"Expenses:Coffee"}},
+		{"2019-01-20", "Assets:Checking", []string{"Business:Expenses:Travel", "Business:Liabilities:Card"}},
+		{"2019-01-21", "Assets:Checking", []string{"Business:Client:Expenses:Lodging"}},
+		{"2020-02-01", "Assets:Cash", []string{"Expenses:Food"}},
+	}
+	for i, want := range expected {
+		tx := result.Transactions[i]
+		if got := tx.Date.Format("2006-01-02"); got != want.date || tx.Bucket != want.bucket {
+			t.Errorf("transaction %d: expected %s with bucket %s, got %s with %s", i, want.date, want.bucket, got, tx.Bucket)
+		}
+		var accounts []string
+		for _, posting := range tx.Postings {
+			accounts = append(accounts, posting.Account)
+		}
+		if !slices.Equal(accounts, want.accounts) {
+			t.Errorf("transaction %d: expected accounts %v, got %v", i, want.accounts, accounts)
+		}
+	}
+	if got := result.Transactions[1].AuxDate.Format("2006-01-02"); got != "2019-01-22" {
+		t.Errorf("expected the auxiliary date in the default year, got %s", got)
+	}
+	if !slices.Equal(result.Accounts, []string{"Business:Expenses:Travel"}) {
+		t.Errorf("expected declarations to be applied too, got %v", result.Accounts)
+	}
+}
+
+func TestParseDecimalCommaOption(t *testing.T) {
+	ledger := "2025/01/15 Bäckerei\n" +
+		"    Expenses:Food           12,50 €\n" +
+		"    Expenses:Rent           1.234 €\n" +
+		"    Expenses:Misc           1,234 €\n" +
+		"    Assets:Bank\n"
+
+	tests := []struct {
+		decimalComma bool
+		quantities   []string
+	}{
+		{decimalComma: false, quantities: []string{"12.5", "1.234", "1234"}},
+		{decimalComma: true, quantities: []string{"12.5", "1234", "1.234"}},
+	}
+	for _, tc := range tests {
+		result, err := Parse(strings.NewReader(ledger), Options{DecimalComma: tc.decimalComma})
+		if err != nil {
+			t.Fatalf("Parse returned error: %v", err)
+		}
+		if len(result.Issues) != 0 || len(result.Transactions) != 1 {
+			t.Fatalf("decimal comma %v: unexpected result %+v", tc.decimalComma, result)
+		}
+		if result.DecimalComma != tc.decimalComma {
+			t.Errorf("expected the result to record decimal comma %v", tc.decimalComma)
+		}
+		for i, quantity := range tc.quantities {
+			amount := result.Transactions[0].Postings[i].Amount
+			if amount == nil || !amount.Quantity.Equal(decimal.RequireFromString(quantity)) {
+				t.Errorf("decimal comma %v, posting %d: expected %s, got %v", tc.decimalComma, i, quantity, amount)
+			}
+		}
+	}
+}
+
+func TestParseReportsMistypedAmounts(t *testing.T) {
+	ledger := "2025/01/15 Store\n" +
+		"    Expenses:Food  $12,34.00\n" +
+		"    Expenses:Misc  1.234,5.6 €\n" +
+		"    Expenses:Gifts  Flowers\n" +
+		"    Assets:Bank\n"
+
+	result, err := Parse(strings.NewReader(ledger), Options{})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(result.Issues) != 2 || result.Issues[0].Line != 2 || result.Issues[1].Line != 3 {
+		t.Fatalf("expected issues for the amounts on lines 2 and 3, got %+v", result.Issues)
+	}
+	if !strings.Contains(result.Issues[0].Message, `"$12,34.00"`) {
+		t.Errorf("expected the issue to quote the amount, got %q", result.Issues[0].Message)
+	}
+	postings := result.Transactions[0].Postings
+	if len(postings) != 2 || postings[0].Account != "Expenses:Gifts  Flowers" {
+		t.Errorf("expected text without digits to stay part of the account, got %+v", postings)
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 	"time"
 
 	"git.sr.ht/~jakintosh/teller/internal/core"
-	"github.com/shopspring/decimal"
 )
 
 // SetBatch replaces the current batch with a new set of transactions
@@ -54,6 +54,7 @@ func (m *Model) defaultDate() time.Time {
 // startNewTransaction initializes a new transaction form and switches to the transaction view
 func (m *Model) startNewTransaction() {
 	m.resetForm(m.defaultDate())
+	m.formReturnView = viewBatch
 	m.currentView = viewTransaction
 }
 
@@ -62,36 +63,56 @@ func (m *Model) startEditingTransaction(index int) {
 	if index < 0 || index >= len(m.batch) {
 		return
 	}
-	tx := m.batch[index]
+	m.loadTransaction(m.batch[index], index)
+}
+
+// loadTransaction fills the form with a transaction and switches to the transaction view
+// index is the batch entry being edited, or -1 for a new entry
+// Postings with elided amounts keep their account with an empty amount to fill in
+func (m *Model) loadTransaction(tx core.Transaction, index int) {
 	m.resetForm(tx.Date)
+	m.form.date.setAuxTime(tx.AuxDate)
 	m.editingIndex = index
-	m.form.cleared = tx.Cleared
+	m.form.status = tx.Status
 	m.form.payeeInput.SetValue(tx.Payee)
 	m.form.payeeInput.CursorEnd()
 	m.form.commentInput.SetValue(tx.Comment)
 	m.form.commentInput.CursorEnd()
+	m.form.tagsInput.SetValue(strings.Join(tx.Tags, " "))
+	m.form.tagsInput.CursorEnd()
+	m.form.codeInput.SetValue(tx.Code)
+	m.form.codeInput.CursorEnd()
 	m.refreshTemplateOptions()
 
 	m.form.debitLines = nil
 	m.form.creditLines = nil
+	var elided []postingLine
 	for _, posting := range tx.Postings {
-		amount, err := decimal.NewFromString(posting.Amount)
-		if err != nil {
-			continue
-		}
 		line := newPostingLine()
-		line.accountInput.SetValue(posting.Account)
+		line.accountInput.SetValue(posting.Kind.FormatAccount(posting.Account))
 		line.accountInput.CursorEnd()
-		line.amountInput.SetValue(amount.StringFixed(2))
-		line.amountInput.CursorEnd()
 		line.commentInput.SetValue(posting.Comment)
 		line.commentInput.CursorEnd()
-		if amount.Sign() >= 0 {
+		if posting.Amount == nil {
+			elided = append(elided, line)
+			continue
+		}
+		line.amountInput.SetValue(formatAmountInput(posting.Amount, m.db.Formats))
+		line.amountInput.CursorEnd()
+		line.priceInput.SetValue(formatPriceInput(posting.Lot, posting.Price))
+		line.priceInput.CursorEnd()
+		if posting.Amount.Quantity.Sign() >= 0 {
 			m.form.debitLines = append(m.form.debitLines, line)
 		} else {
 			m.form.creditLines = append(m.form.creditLines, line)
 		}
 	}
+	// An elided amount balances the others, so it belongs to the empty side
+	if len(m.form.debitLines) == 0 {
+		m.form.debitLines = elided
+	} else {
+		m.form.creditLines = append(m.form.creditLines, elided...)
+	}
 	if len(m.form.debitLines) == 0 {
 		m.form.debitLines = []postingLine{newPostingLine()}
 	}
@@ -100,17 +121,31 @@ func (m *Model) startEditingTransaction(index int) {
 	}
 	m.recalculateTotals()
 	m.focusSection(sectionCredit, 0, focusSectionAccount)
+	if tx.Draft && strings.TrimSpace(m.form.debitLines[0].accountInput.Value()) == "" {
+		// Imported drafts only carry the funding side, so start on the empty debit line
+		m.focusSection(sectionDebit, 0, focusSectionAccount)
+	}
 	m.captureFormBaseline()
+	m.formReturnView = viewBatch
 	m.currentView = viewTransaction
 }
 
 // cancelTransaction cancels the current transaction and returns to the batch view
 func (m *Model) cancelTransaction() {
 	m.resetForm(m.defaultDate())
-	m.currentView = viewBatch
+	m.leaveForm()
 	m.ensureBatchCursorVisible()
 }
 
+// leaveForm returns from the transaction form to the view that opened it
+func (m *Model) leaveForm() {
+	if m.formReturnView == viewReview {
+		m.showReview()
+		return
+	}
+	m.currentView = viewBatch
+}
+
 // openConfirm switches to the confirmation view for the specified action
 func (m *Model) openConfirm(kind confirmKind, returnView viewState) {
 	m.pendingConfirm = kind
@@ -118,6 +153,27 @@ func (m *Model) openConfirm(kind confirmKind, returnView viewState) {
 	m.currentView = viewConfirm
 }
 
+// sortBatch orders the batch by date and payee
+func (m *Model) sortBatch() {
+	sort.SliceStable(m.batch, func(i, j int) bool {
+		if m.batch[i].Date.Equal(m.batch[j].Date) {
+			return m.batch[i].Payee < m.batch[j].Payee
+		}
+		return m.batch[i].Date.Before(m.batch[j].Date)
+	})
+}
+
+// draftCount returns the number of imported transactions in the batch that are not yet categorized
+func (m *Model) draftCount() int {
+	count := 0
+	for _, tx := range m.batch {
+		if tx.Draft {
+			count++
+		}
+	}
+	return count
+}
+
 // writeTransactionsToLedger appends all batch transactions to the ledger file
 func (m *Model) writeTransactionsToLedger() error {
 	if len(m.batch) == 0 {
@@ -174,6 +230,7 @@ func (m *Model) ensureBatchCursorVisible() {
 	headerSize := 1 // title line
 	headerSize += len(m.loadSummaryLines())
 	headerSize += 1 // blank line after summary
+	headerSize += len(m.expectedLines())
 
 	// Calculate footer size
 	footerSize := 1 // blank line before commands
@@ -9,34 +9,89 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-// setTime initializes the date field with a given time value
+// setTime initializes the date field with a given time value, without an auxiliary date
 func (d *dateField) setTime(t time.Time) {
 	d.year = t.Year()
 	d.month = int(t.Month())
 	d.day = t.Day()
+	d.auxYear, d.auxMonth, d.auxDay = 0, 0, 0
 	d.segment = dateSegmentYear
 	d.buffer = ""
 }
 
+// setAuxTime sets the auxiliary date, clearing it for a zero time
+func (d *dateField) setAuxTime(t time.Time) {
+	if t.IsZero() {
+		d.auxYear, d.auxMonth, d.auxDay = 0, 0, 0
+		return
+	}
+	d.auxYear = t.Year()
+	d.auxMonth = int(t.Month())
+	d.auxDay = t.Day()
+}
+
 // time converts the date field to a time.Time value
 func (d dateField) time() time.Time {
-	if d.year == 0 || d.month == 0 || d.day == 0 {
+	return makeDate(d.year, d.month, d.day)
+}
+
+// auxTime returns the auxiliary date, or the zero time when there is none
+func (d dateField) auxTime() time.Time {
+	return makeDate(d.auxYear, d.auxMonth, d.auxDay)
+}
+
+// hasAux reports whether the field has an auxiliary date
+func (d dateField) hasAux() bool {
+	return d.auxYear != 0
+}
+
+// makeDate builds a date from its parts, or the zero time when any is missing
+func makeDate(year, month, day int) time.Time {
+	if year == 0 || month == 0 || day == 0 {
 		return time.Time{}
 	}
-	return time.Date(d.year, time.Month(d.month), d.day, 0, 0, 0, 0, time.Local)
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
 }
 
 // display returns a formatted date string with the focused segment highlighted
+// An auxiliary date follows the date after "="
 func (d dateField) display(focused bool) string {
 	parts := []string{
 		fmt.Sprintf("%04d", d.year),
 		fmt.Sprintf("%02d", d.month),
 		fmt.Sprintf("%02d", d.day),
 	}
-	if focused {
+	if d.hasAux() {
+		parts = append(parts,
+			fmt.Sprintf("%04d", d.auxYear),
+			fmt.Sprintf("%02d", d.auxMonth),
+			fmt.Sprintf("%02d", d.auxDay),
+		)
+	}
+	if focused && int(d.segment) < len(parts) {
 		parts[d.segment] = "[" + parts[d.segment] + "]"
 	}
-	return strings.Join(parts, "-")
+	display := strings.Join(parts[:3], "-")
+	if d.hasAux() {
+		display += "=" + strings.Join(parts[3:], "-")
+	}
+	return display
+}
+
+// parts returns the year, month and day of the date the focused segment belongs to
+func (d *dateField) parts() (*int, *int, *int) {
+	if d.segment >= dateSegmentAuxYear {
+		return &d.auxYear, &d.auxMonth, &d.auxDay
+	}
+	return &d.year, &d.month, &d.day
+}
+
+// lastSegment returns the rightmost segment that can be focused
+func (d dateField) lastSegment() dateSegment {
+	if d.hasAux() {
+		return dateSegmentAuxDay
+	}
+	return dateSegmentDay
 }
 
 // segmentLeft moves focus to the previous date segment
@@ -50,34 +105,51 @@ func (d *dateField) segmentLeft() {
 // segmentRight moves focus to the next date segment
 func (d *dateField) segmentRight() {
 	d.buffer = ""
-	if d.segment < dateSegmentDay {
+	if d.segment < d.lastSegment() {
 		d.segment++
 	}
 }
 
+// startAux adds an auxiliary date, starting from the date, and focuses its day
+func (d *dateField) startAux() {
+	if !d.hasAux() {
+		d.auxYear, d.auxMonth, d.auxDay = d.year, d.month, d.day
+	}
+	d.segment = dateSegmentAuxDay
+	d.buffer = ""
+}
+
+// clearAux removes the auxiliary date and returns focus to the day
+func (d *dateField) clearAux() {
+	d.auxYear, d.auxMonth, d.auxDay = 0, 0, 0
+	d.segment = dateSegmentDay
+	d.buffer = ""
+}
+
 // increment adjusts the focused date segment by the given delta
 func (d *dateField) increment(delta int) {
+	year, month, day := d.parts()
 	switch d.segment {
-	case dateSegmentYear:
-		d.year += delta
-	case dateSegmentMonth:
-		d.month += delta
-		if d.month < 1 {
-			d.month = 12
-			d.year--
-		} else if d.month > 12 {
-			d.month = 1
-			d.year++
+	case dateSegmentYear, dateSegmentAuxYear:
+		*year += delta
+	case dateSegmentMonth, dateSegmentAuxMonth:
+		*month += delta
+		if *month < 1 {
+			*month = 12
+			*year--
+		} else if *month > 12 {
+			*month = 1
+			*year++
 		}
-	case dateSegmentDay:
-		t := d.time()
+	case dateSegmentDay, dateSegmentAuxDay:
+		t := makeDate(*year, *month, *day)
 		if t.IsZero() {
 			t = time.Now()
 		}
 		t = t.AddDate(0, 0, delta)
-		d.year = t.Year()
-		d.month = int(t.Month())
-		d.day = t.Day()
+		*year = t.Year()
+		*month = int(t.Month())
+		*day = t.Day()
 	}
 	d.ensureDayInMonth()
 }
@@ -87,16 +159,17 @@ func (d *dateField) handleDigit(r rune) {
 	if r < '0' || r > '9' {
 		return
 	}
+	year, month, day := d.parts()
 	d.buffer += string(r)
 	switch d.segment {
-	case dateSegmentYear:
+	case dateSegmentYear, dateSegmentAuxYear:
 		if len(d.buffer) > 4 {
 			d.buffer = d.buffer[len(d.buffer)-4:]
 		}
 		if val, err := strconv.Atoi(d.buffer); err == nil {
-			d.year = val
+			*year = val
 		}
-	case dateSegmentMonth:
+	case dateSegmentMonth, dateSegmentAuxMonth:
 		if len(d.buffer) > 2 {
 			d.buffer = d.buffer[len(d.buffer)-2:]
 		}
@@ -107,40 +180,49 @@ func (d *dateField) handleDigit(r rune) {
 			if val > 12 {
 				val = 12
 			}
-			d.month = val
+			*month = val
 		}
 		if len(d.buffer) >= 2 {
 			d.segmentRight()
 		}
-	case dateSegmentDay:
+	case dateSegmentDay, dateSegmentAuxDay:
 		if len(d.buffer) > 2 {
 			d.buffer = d.buffer[len(d.buffer)-2:]
 		}
 		if val, err := strconv.Atoi(d.buffer); err == nil {
-			maxDay := daysInMonth(d.year, d.month)
+			maxDay := daysInMonth(*year, *month)
 			if val < 1 {
 				val = 1
 			}
 			if val > maxDay {
 				val = maxDay
 			}
-			d.day = val
+			*day = val
 		}
 		if len(d.buffer) >= 2 {
-			d.segmentRight()
+			// The day ends its date, so typing doesn't run on into the auxiliary date
+			d.buffer = ""
 		}
 	}
 	d.ensureDayInMonth()
 }
 
-// ensureDayInMonth validates and clamps the day value to the valid range for the current month
+// ensureDayInMonth validates and clamps the day values to the valid range for their months
 func (d *dateField) ensureDayInMonth() {
-	maxDay := daysInMonth(d.year, d.month)
-	if d.day > maxDay {
-		d.day = maxDay
+	clampDay(&d.day, d.year, d.month)
+	if d.hasAux() {
+		clampDay(&d.auxDay, d.auxYear, d.auxMonth)
+	}
+}
+
+// clampDay limits day to the days in the given month
+func clampDay(day *int, year, month int) {
+	maxDay := daysInMonth(year, month)
+	if *day > maxDay {
+		*day = maxDay
 	}
-	if d.day < 1 {
-		d.day = 1
+	if *day < 1 {
+		*day = 1
 	}
 }
 
@@ -169,6 +251,14 @@ func (m *Model) handleDateKey(msg tea.KeyMsg) bool {
 	case "down":
 		m.form.date.increment(-1)
 		return true
+	case "=":
+		m.form.date.startAux()
+		return true
+	case "backspace", "delete":
+		if m.form.date.segment >= dateSegmentAuxYear {
+			m.form.date.clearAux()
+			return true
+		}
 	}
 	if len(msg.Runes) == 1 {
 		r := msg.Runes[0]
@@ -1,40 +1,48 @@
 package tui
 
 import (
+	"git.sr.ht/~jakintosh/teller/internal/core"
 	"github.com/charmbracelet/bubbles/textinput"
 )
 
 // buildFocusPath creates an ordered list of all focusable elements based on visual layout
-// Order: Date → Payee → Comment → Template → Debits → Credits
+// Order: Date → Payee → Comment → Tags → Code → Template → Debits → Credits
 func (m *Model) buildFocusPath() []focusPosition {
 	path := []focusPosition{
 		{field: focusDate},
 		{field: focusPayee},
 		{field: focusComment},
+		{field: focusTags},
+		{field: focusCode},
 		{field: focusTemplateButton},
 	}
 
-	// Add all debit lines (account → amount → comment for each)
+	// Add all debit lines (account → amount → [price] → comment for each)
 	for i := range m.form.debitLines {
-		path = append(path,
-			focusPosition{field: focusSectionAccount, section: sectionDebit, index: i},
-			focusPosition{field: focusSectionAmount, section: sectionDebit, index: i},
-			focusPosition{field: focusSectionComment, section: sectionDebit, index: i},
-		)
+		path = appendLinePath(path, sectionDebit, i, &m.form.debitLines[i], m.db.Formats)
 	}
 
-	// Add all credit lines (account → amount → comment for each)
+	// Add all credit lines (account → amount → [price] → comment for each)
 	for i := range m.form.creditLines {
-		path = append(path,
-			focusPosition{field: focusSectionAccount, section: sectionCredit, index: i},
-			focusPosition{field: focusSectionAmount, section: sectionCredit, index: i},
-			focusPosition{field: focusSectionComment, section: sectionCredit, index: i},
-		)
+		path = appendLinePath(path, sectionCredit, i, &m.form.creditLines[i], m.db.Formats)
 	}
 
 	return path
 }
 
+// appendLinePath adds the focusable fields of a posting line to the focus path
+// The price field is only included when the line offers one
+func appendLinePath(path []focusPosition, section sectionType, index int, line *postingLine, formats core.CommodityFormats) []focusPosition {
+	path = append(path,
+		focusPosition{field: focusSectionAccount, section: section, index: index},
+		focusPosition{field: focusSectionAmount, section: section, index: index},
+	)
+	if line.hasPriceField(formats) {
+		path = append(path, focusPosition{field: focusSectionPrice, section: section, index: index})
+	}
+	return append(path, focusPosition{field: focusSectionComment, section: section, index: index})
+}
+
 // currentPosition returns the current focus position
 func (m *Model) currentPosition() focusPosition {
 	return focusPosition{
@@ -50,7 +58,7 @@ func (m *Model) currentPosition() focusPosition {
 func (m *Model) validateFocusState() {
 	// Only validate if focus is on a posting line field
 	switch m.form.focusedField {
-	case focusSectionAccount, focusSectionAmount, focusSectionComment:
+	case focusSectionAccount, focusSectionAmount, focusSectionPrice, focusSectionComment:
 		var maxIndex int
 		switch m.form.focusedSection {
 		case sectionDebit:
@@ -92,9 +100,9 @@ func findPositionInPath(path []focusPosition, pos focusPosition) int {
 		// For header fields, only compare the field itself
 		if p.field == pos.field {
 			switch p.field {
-			case focusDate, focusPayee, focusComment, focusTemplateButton:
+			case focusDate, focusPayee, focusComment, focusTags, focusCode, focusTemplateButton:
 				return i
-			case focusSectionAccount, focusSectionAmount, focusSectionComment:
+			case focusSectionAccount, focusSectionAmount, focusSectionPrice, focusSectionComment:
 				// For posting line fields, also compare section and index
 				if p.section == pos.section && p.index == pos.index {
 					return i
@@ -118,13 +126,19 @@ func (m *Model) moveFocusToPosition(pos focusPosition) {
 		m.form.payeeInput.Focus()
 	case focusComment:
 		m.form.commentInput.Focus()
-	case focusSectionAccount, focusSectionAmount, focusSectionComment:
+	case focusTags:
+		m.form.tagsInput.Focus()
+	case focusCode:
+		m.form.codeInput.Focus()
+	case focusSectionAccount, focusSectionAmount, focusSectionPrice, focusSectionComment:
 		if line := m.currentLine(); line != nil {
 			switch pos.field {
 			case focusSectionAccount:
 				line.accountInput.Focus()
 			case focusSectionAmount:
 				line.amountInput.Focus()
+			case focusSectionPrice:
+				line.priceInput.Focus()
 			case focusSectionComment:
 				line.commentInput.Focus()
 			}
@@ -210,12 +224,15 @@ func (m *Model) focusSection(section sectionType, index int, field focusedField)
 	if line := m.currentLine(); line != nil {
 		line.accountInput.Blur()
 		line.amountInput.Blur()
+		line.priceInput.Blur()
 		line.commentInput.Blur()
 		switch field {
 		case focusSectionAccount:
 			line.accountInput.Focus()
 		case focusSectionAmount:
 			line.amountInput.Focus()
+		case focusSectionPrice:
+			line.priceInput.Focus()
 		case focusSectionComment:
 			line.commentInput.Focus()
 		}
@@ -263,21 +280,26 @@ func (m *Model) focusFirstPostingLine() {
 
 // blurCurrent removes focus from the currently focused input field
 func (m *Model) blurCurrent() {
+	// Clear suggestions before blurring to prevent stale state in unfocused inputs
+	m.setSuggestions(nil)
 	if line := m.currentLine(); line != nil {
-		// Clear suggestions before blurring to prevent stale state in unfocused inputs
-		line.accountInput.SetSuggestions(nil)
 		line.accountInput.Blur()
 		line.amountInput.Blur()
+		line.priceInput.Blur()
 		line.commentInput.Blur()
 	}
 	if m.form.focusedField == focusPayee {
-		// Clear suggestions before blurring to prevent stale state in unfocused inputs
-		m.form.payeeInput.SetSuggestions(nil)
 		m.form.payeeInput.Blur()
 	}
 	if m.form.focusedField == focusComment {
 		m.form.commentInput.Blur()
 	}
+	if m.form.focusedField == focusTags {
+		m.form.tagsInput.Blur()
+	}
+	if m.form.focusedField == focusCode {
+		m.form.codeInput.Blur()
+	}
 }
 
 // currentLine returns the currently focused posting line, or nil if no line is focused
@@ -297,7 +319,7 @@ func (m *Model) currentLine() *postingLine {
 
 // lineHasFocus returns true if the specified posting line currently has focus
 func (m *Model) lineHasFocus(section sectionType, index int) bool {
-	if m.form.focusedField != focusSectionAccount && m.form.focusedField != focusSectionAmount && m.form.focusedField != focusSectionComment {
+	if !isLineField(m.form.focusedField) {
 		return false
 	}
 	return m.form.focusedSection == section && m.form.focusedIndex == index
@@ -305,12 +327,21 @@ func (m *Model) lineHasFocus(section sectionType, index int) bool {
 
 // hasActiveLine returns true if a posting line field currently has focus
 func (m *Model) hasActiveLine() bool {
-	if m.form.focusedField != focusSectionAccount && m.form.focusedField != focusSectionAmount && m.form.focusedField != focusSectionComment {
+	if !isLineField(m.form.focusedField) {
 		return false
 	}
 	return m.currentLine() != nil
 }
 
+// isLineField reports whether a field belongs to a posting line
+func isLineField(field focusedField) bool {
+	switch field {
+	case focusSectionAccount, focusSectionAmount, focusSectionPrice, focusSectionComment:
+		return true
+	}
+	return false
+}
+
 // currentTextInput returns the currently focused text input, or nil if no text input is focused
 func (m *Model) currentTextInput() *textinput.Model {
 	switch m.form.focusedField {
@@ -318,6 +349,10 @@ func (m *Model) currentTextInput() *textinput.Model {
 		return &m.form.payeeInput
 	case focusComment:
 		return &m.form.commentInput
+	case focusTags:
+		return &m.form.tagsInput
+	case focusCode:
+		return &m.form.codeInput
 	case focusSectionAccount:
 		if line := m.currentLine(); line != nil {
 			return &line.accountInput
@@ -326,6 +361,10 @@ func (m *Model) currentTextInput() *textinput.Model {
 		if line := m.currentLine(); line != nil {
 			return &line.amountInput
 		}
+	case focusSectionPrice:
+		if line := m.currentLine(); line != nil {
+			return &line.priceInput
+		}
 	case focusSectionComment:
 		if line := m.currentLine(); line != nil {
 			return &line.commentInput
@@ -16,9 +16,12 @@ func (m *Model) formIsDirty() bool {
 func (m *Model) currentFormSnapshot() formSnapshot {
 	snapshot := formSnapshot{
 		date:    m.form.date.time(),
-		cleared: m.form.cleared,
+		auxDate: m.form.date.auxTime(),
+		status:  m.form.status,
 		payee:   strings.TrimSpace(m.form.payeeInput.Value()),
 		comment: strings.TrimSpace(m.form.commentInput.Value()),
+		tags:    strings.Join(parseTagsInput(m.form.tagsInput.Value()), " "),
+		code:    strings.TrimSpace(m.form.codeInput.Value()),
 		debit:   make([]postingSnapshot, len(m.form.debitLines)),
 		credit:  make([]postingSnapshot, len(m.form.creditLines)),
 	}
@@ -28,6 +31,7 @@ func (m *Model) currentFormSnapshot() formSnapshot {
 		snapshot.debit[i] = postingSnapshot{
 			account: strings.TrimSpace(line.accountInput.Value()),
 			amount:  strings.TrimSpace(line.amountInput.Value()),
+			price:   strings.TrimSpace(line.priceInput.Value()),
 			comment: strings.TrimSpace(line.commentInput.Value()),
 		}
 	}
@@ -36,6 +40,7 @@ func (m *Model) currentFormSnapshot() formSnapshot {
 		snapshot.credit[i] = postingSnapshot{
 			account: strings.TrimSpace(line.accountInput.Value()),
 			amount:  strings.TrimSpace(line.amountInput.Value()),
+			price:   strings.TrimSpace(line.priceInput.Value()),
 			comment: strings.TrimSpace(line.commentInput.Value()),
 		}
 	}
@@ -44,13 +49,13 @@ func (m *Model) currentFormSnapshot() formSnapshot {
 
 // equals compares two form snapshots for equality
 func (a formSnapshot) equals(b formSnapshot) bool {
-	if !a.date.Equal(b.date) {
+	if !a.date.Equal(b.date) || !a.auxDate.Equal(b.auxDate) {
 		return false
 	}
-	if a.cleared != b.cleared {
+	if a.status != b.status {
 		return false
 	}
-	if a.payee != b.payee || a.comment != b.comment {
+	if a.payee != b.payee || a.comment != b.comment || a.tags != b.tags || a.code != b.code {
 		return false
 	}
 	if len(a.debit) != len(b.debit) || len(a.credit) != len(b.credit) {
@@ -18,6 +18,8 @@ func (m *Model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, m.updateTemplateView(msg)
 	case viewConfirm:
 		return m, m.updateConfirmView(msg)
+	case viewReview:
+		return m, m.updateReviewView(msg)
 	default:
 		return m, nil
 	}
@@ -47,11 +49,17 @@ func (m *Model) updateBatchView(msg tea.KeyMsg) tea.Cmd {
 	case "w":
 		if len(m.batch) == 0 {
 			m.setStatus("No transactions to write", statusInfo, statusShortDuration)
+		} else if drafts := m.draftCount(); drafts > 0 {
+			m.setStatus(fmt.Sprintf("%d imported draft(s) still need categorizing", drafts), statusError, statusDuration)
 		} else {
 			m.openConfirm(confirmWrite, viewBatch)
 		}
+	case "r":
+		m.openReview()
 	case "q":
 		m.openConfirm(confirmQuit, viewBatch)
+	case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+		m.addExpectedTransaction(int(msg.String()[0] - '1'))
 	}
 	return nil
 }
@@ -69,7 +77,7 @@ func (m *Model) updateTransactionView(msg tea.KeyMsg) tea.Cmd {
 	case "ctrl+q":
 		return tea.Quit
 	case "ctrl+c":
-		m.form.cleared = !m.form.cleared
+		m.form.status = m.form.status.Next()
 		return nil
 	case "ctrl+s":
 		m.confirmTransaction()
@@ -105,11 +113,25 @@ func (m *Model) updateTransactionView(msg tea.KeyMsg) tea.Cmd {
 			m.deleteLine(m.form.focusedSection)
 		}
 		return nil
+	case "ctrl+t":
+		if line := m.currentLine(); line != nil && m.hasActiveLine() {
+			line.cycleKind()
+			m.recalculateTotals()
+		}
+		return nil
 	case "ctrl+b":
 		if m.balanceAnyLine() {
 			m.recalculateTotals()
 		}
 		return nil
+	case "up", "ctrl+p":
+		if m.moveSuggestion(-1) {
+			return nil
+		}
+	case "down", "ctrl+n":
+		if m.moveSuggestion(1) {
+			return nil
+		}
 	}
 
 	cmd := m.updateFocusedInput(msg)
@@ -134,6 +156,14 @@ func (m *Model) handleEnterKey() bool {
 	case focusComment:
 		m.advanceFocus()
 		return true
+	case focusTags:
+		if !m.tryAcceptSuggestion() {
+			m.advanceFocus()
+		}
+		return true
+	case focusCode:
+		m.advanceFocus()
+		return true
 	case focusTemplateButton:
 		m.openTemplateSelection()
 		return true
@@ -147,6 +177,13 @@ func (m *Model) handleEnterKey() bool {
 			}
 		}
 		return true
+	case focusSectionPrice:
+		if line := m.currentLine(); line != nil {
+			if m.validatePriceInput(line) {
+				m.advanceFocus()
+			}
+		}
+		return true
 	case focusSectionComment:
 		m.advanceFocus()
 		return true
@@ -165,6 +202,14 @@ func (m *Model) updateFocusedInput(msg tea.KeyMsg) tea.Cmd {
 		var cmd tea.Cmd
 		m.form.commentInput, cmd = m.form.commentInput.Update(msg)
 		return cmd
+	case focusTags:
+		var cmd tea.Cmd
+		m.form.tagsInput, cmd = m.form.tagsInput.Update(msg)
+		return cmd
+	case focusCode:
+		var cmd tea.Cmd
+		m.form.codeInput, cmd = m.form.codeInput.Update(msg)
+		return cmd
 	case focusSectionAccount:
 		if line := m.currentLine(); line != nil {
 			var cmd tea.Cmd
@@ -177,6 +222,12 @@ func (m *Model) updateFocusedInput(msg tea.KeyMsg) tea.Cmd {
 			line.amountInput, cmd = line.amountInput.Update(msg)
 			return cmd
 		}
+	case focusSectionPrice:
+		if line := m.currentLine(); line != nil {
+			var cmd tea.Cmd
+			line.priceInput, cmd = line.priceInput.Update(msg)
+			return cmd
+		}
 	case focusSectionComment:
 		if line := m.currentLine(); line != nil {
 			var cmd tea.Cmd
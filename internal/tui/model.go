@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"git.sr.ht/~jakintosh/teller/internal/core"
 	"git.sr.ht/~jakintosh/teller/internal/intelligence"
 	"git.sr.ht/~jakintosh/teller/internal/session"
 	tea "github.com/charmbracelet/bubbletea"
@@ -21,11 +22,22 @@ func NewModel(db *intelligence.IntelligenceDB, ledgerFilePath string, report int
 		currentView:    viewBatch,
 		editingIndex:   -1,
 		buildReport:    report,
+		currency:       core.DefaultCommodity,
 	}
 	m.resetForm(time.Now())
 	return m
 }
 
+// SetCurrency sets the reporting commodity totals are converted to
+func (m *Model) SetCurrency(commodity string) {
+	m.currency = commodity
+}
+
+// SetStrict requires every account in a confirmed transaction to be declared in the ledger
+func (m *Model) SetStrict(strict bool) {
+	m.strict = strict
+}
+
 // Init initializes the model and returns the initial command
 func (m *Model) Init() tea.Cmd {
 	return tea.Tick(time.Second, func(time.Time) tea.Msg { return statusTick{} })
@@ -91,6 +103,8 @@ func (m *Model) View() string {
 		return m.renderTemplateView()
 	case viewConfirm:
 		return m.renderConfirmView()
+	case viewReview:
+		return m.renderReviewView()
 	default:
 		return "Unknown view"
 	}
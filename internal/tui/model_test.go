@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"git.sr.ht/~jakintosh/teller/internal/core"
 	"git.sr.ht/~jakintosh/teller/internal/intelligence"
@@ -25,15 +27,15 @@ func testDB(t *testing.T) *intelligence.IntelligenceDB {
 		{
 			Payee: "Sample Market",
 			Postings: []core.Posting{
-				{Account: "Expenses:Food:Groceries", Amount: "50.00"},
-				{Account: "Assets:Checking", Amount: "-50.00"},
+				{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("50.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-50.00"), "")},
 			},
 		},
 		{
 			Payee: "Fuel Station",
 			Postings: []core.Posting{
-				{Account: "Expenses:Auto:Gas", Amount: "40.00"},
-				{Account: "Assets:Credit Card", Amount: "-40.00"},
+				{Account: "Expenses:Auto:Gas", Amount: core.NewAmount(decimal.RequireFromString("40.00"), "")},
+				{Account: "Assets:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-40.00"), "")},
 			},
 		},
 	}
@@ -94,6 +96,54 @@ func TestNewTransactionHighlightsDaySegment(t *testing.T) {
 	}
 }
 
+func TestDateFieldEditsAuxiliaryDate(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.resetForm(time.Date(2025, time.January, 15, 0, 0, 0, 0, time.Local))
+	model.currentView = viewTransaction
+
+	// "=" starts the auxiliary date from the date and focuses its day
+	model.updateTransactionView(keyRunes('='))
+	for _, r := range "17" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	if got := model.form.date.display(true); got != "2025-01-15=2025-01-[17]" {
+		t.Fatalf("unexpected date display: %q", got)
+	}
+
+	model.form.payeeInput.SetValue("Card Purchase")
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food")
+	model.form.debitLines[0].amountInput.SetValue("12")
+	model.form.creditLines[0].accountInput.SetValue("Liabilities:Card")
+	model.form.creditLines[0].amountInput.SetValue("-12")
+	model.form.creditLines[0].commentInput.SetValue("[=2025/01/16]")
+	model.recalculateTotals()
+	if !model.confirmTransaction() {
+		t.Fatalf("expected transaction to confirm: %s", model.statusMessage)
+	}
+	tx := model.batch[0]
+	if tx.AuxDate.Format("2006-01-02") != "2025-01-17" {
+		t.Fatalf("expected auxiliary date to be saved, got %v", tx.AuxDate)
+	}
+	if tx.Postings[1].AuxDate.Format("2006-01-02") != "2025-01-16" {
+		t.Fatalf("expected posting date from its comment, got %+v", tx.Postings[1])
+	}
+
+	// Editing restores the auxiliary date, and backspace on it removes it
+	model.startEditingTransaction(0)
+	model.moveFocusToPosition(focusPosition{field: focusDate})
+	model.form.date.segment = dateSegmentAuxDay
+	if got := model.form.date.display(false); got != "2025-01-15=2025-01-17" {
+		t.Fatalf("expected auxiliary date to be restored, got %q", got)
+	}
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyBackspace})
+	if model.form.date.hasAux() || model.form.date.segment != dateSegmentDay {
+		t.Fatalf("expected backspace to remove the auxiliary date, got %q", model.form.date.display(true))
+	}
+}
+
 func TestTransactionActionsStackedVertically(t *testing.T) {
 	db := testDB(t)
 	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
@@ -128,18 +178,75 @@ func TestEmptyPayeePrevSuggestionThenTabAdvancesWithoutPanic(t *testing.T) {
 	}
 }
 
-func TestTransactionFlowAddsBatchEntry(t *testing.T) {
+func TestFuzzySuggestionsRequireSelectionToAccept(t *testing.T) {
 	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
 
-	tempDir := t.TempDir()
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd: %v", err)
+	for _, r := range "mrkt" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	if len(model.form.suggestions) != 1 || model.form.suggestions[0].Text != "Sample Market" {
+		t.Fatalf("expected fuzzy payee suggestion, got %+v", model.form.suggestions)
 	}
-	defer func() { _ = os.Chdir(wd) }()
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("chdir: %v", err)
+	if got := model.form.suggestions[0].Positions; !reflect.DeepEqual(got, []int{7, 9, 10, 12}) {
+		t.Fatalf("unexpected matched positions: %v", got)
 	}
+	if view := model.renderTransactionView(); !strings.Contains(view, "> ") || !strings.Contains(view, "Market") {
+		t.Fatalf("expected suggestion list in view: %q", view)
+	}
+
+	// Tab must not swap a loose match in for what was typed
+	if model.tryAcceptSuggestion() {
+		t.Fatalf("expected fuzzy suggestion to need an explicit selection")
+	}
+
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyDown})
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
+	if got := model.form.payeeInput.Value(); got != "Sample Market" {
+		t.Fatalf("expected selected suggestion to be accepted, got %q", got)
+	}
+
+	model.focusSection(sectionDebit, 0, focusSectionAccount)
+	for _, r := range "gas" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	if len(model.form.suggestions) != 1 || model.form.suggestions[0].Text != "Expenses:Auto:Gas" {
+		t.Fatalf("expected full account path suggestion, got %+v", model.form.suggestions)
+	}
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyDown})
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
+	if got := model.form.debitLines[0].accountInput.Value(); got != "Expenses:Auto:Gas" {
+		t.Fatalf("expected account suggestion to be accepted, got %q", got)
+	}
+}
+
+func TestAccountSuggestionsFavourPayeeAccounts(t *testing.T) {
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Fuel Station")
+
+	model.focusSection(sectionCredit, 0, focusSectionAccount)
+	for _, r := range "Assets:" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	if len(model.form.suggestions) != 2 || model.form.suggestions[0].Text != "Assets:Credit Card" {
+		t.Fatalf("expected the payee's account first, got %+v", model.form.suggestions)
+	}
+
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
+	if got := model.form.creditLines[0].accountInput.Value(); got != "Assets:Credit Card" {
+		t.Fatalf("expected tab to accept the top suggestion, got %q", got)
+	}
+}
+
+func TestTransactionFlowAddsBatchEntry(t *testing.T) {
+	db := testDB(t)
+
+	tempDir := t.TempDir()
+	t.Chdir(tempDir)
 
 	ledgerPath := filepath.Join(tempDir, "ledger.dat")
 	if err := os.WriteFile(ledgerPath, []byte(""), 0644); err != nil {
@@ -155,7 +262,9 @@ func TestTransactionFlowAddsBatchEntry(t *testing.T) {
 		tm.Send(keyRunes(r))
 	}
 	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // payee -> comment
-	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // comment -> template button
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // comment -> tags
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // tags -> code
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // code -> template button
 	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // template button -> debit account
 	for _, r := range "Expenses:Food:Groceries" {
 		tm.Send(keyRunes(r))
@@ -185,7 +294,7 @@ func TestTransactionFlowAddsBatchEntry(t *testing.T) {
 	if tx.Payee != "Grocery Store" {
 		t.Fatalf("unexpected payee: %s", tx.Payee)
 	}
-	if !tx.Cleared {
+	if tx.Status != core.Cleared {
 		t.Fatalf("expected new transactions to default to cleared")
 	}
 	if tx.Comment != "" {
@@ -194,13 +303,13 @@ func TestTransactionFlowAddsBatchEntry(t *testing.T) {
 	if len(tx.Postings) != 2 {
 		t.Fatalf("expected 2 postings, got %d", len(tx.Postings))
 	}
-	if tx.Postings[0].Account != "Expenses:Food:Groceries" || tx.Postings[0].Amount != "100.00" {
+	if tx.Postings[0].Account != "Expenses:Food:Groceries" || tx.Postings[0].Amount.String() != "100.00" {
 		t.Fatalf("unexpected debit posting: %+v", tx.Postings[0])
 	}
 	if tx.Postings[0].Comment != "" {
 		t.Fatalf("expected debit comment to be empty, got %q", tx.Postings[0].Comment)
 	}
-	if tx.Postings[1].Account != "Assets:Checking" || tx.Postings[1].Amount != "-100.00" {
+	if tx.Postings[1].Account != "Assets:Checking" || tx.Postings[1].Amount.String() != "-100.00" {
 		t.Fatalf("unexpected credit posting: %+v", tx.Postings[1])
 	}
 	if tx.Postings[1].Comment != "" {
@@ -212,6 +321,7 @@ func TestTransactionCapturesCommentsAndCleared(t *testing.T) {
 	db := testDB(t)
 
 	tempDir := t.TempDir()
+	t.Chdir(tempDir)
 	ledgerPath := filepath.Join(tempDir, "ledger.dat")
 	if err := os.WriteFile(ledgerPath, []byte(""), 0644); err != nil {
 		t.Fatalf("create ledger file: %v", err)
@@ -230,7 +340,12 @@ func TestTransactionCapturesCommentsAndCleared(t *testing.T) {
 	for _, r := range "Monthly restock" {
 		tm.Send(keyRunes(r))
 	}
-	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // comment -> template button
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // comment -> tags
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // tags -> code
+	for _, r := range "1042" {
+		tm.Send(keyRunes(r))
+	}
+	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // code -> template button
 	tm.Send(tea.KeyMsg{Type: tea.KeyTab}) // template -> debit account
 	for _, r := range "Expenses:Office:Supplies" {
 		tm.Send(keyRunes(r))
@@ -264,12 +379,15 @@ func TestTransactionCapturesCommentsAndCleared(t *testing.T) {
 	}
 
 	tx := model.batch[0]
-	if tx.Cleared {
+	if tx.Status != core.Uncleared {
 		t.Fatalf("expected transaction to be marked uncleared")
 	}
 	if tx.Comment != "Monthly restock" {
 		t.Fatalf("unexpected transaction comment: %q", tx.Comment)
 	}
+	if tx.Code != "1042" {
+		t.Fatalf("unexpected transaction code: %q", tx.Code)
+	}
 	if len(tx.Postings) != 2 {
 		t.Fatalf("expected 2 postings, got %d", len(tx.Postings))
 	}
@@ -281,6 +399,20 @@ func TestTransactionCapturesCommentsAndCleared(t *testing.T) {
 	}
 }
 
+func TestStatusCyclesThroughPendingAndBackToCleared(t *testing.T) {
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+
+	expected := []string{"uncleared", "pending", "cleared"}
+	for _, want := range expected {
+		model.updateTransactionView(tea.KeyMsg{Type: tea.KeyCtrlC})
+		if view := model.renderTransactionView(); !strings.Contains(view, "Status ["+want+"]") {
+			t.Fatalf("expected status %s, got %q", want, view)
+		}
+	}
+}
+
 func TestDeleteLineKeepsAtLeastOne(t *testing.T) {
 	db := testDB(t)
 	model := NewModel(db, "test-ledger.dat", intelligence.BuildReport{})
@@ -312,6 +444,365 @@ func TestBalanceShortcutFillsCreditDifference(t *testing.T) {
 	}
 }
 
+func TestTransactionBalancesEachCommodity(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Broker")
+	model.form.debitLines[0].accountInput.SetValue("Assets:Brokerage")
+	model.form.debitLines[0].amountInput.SetValue("10 AAPL")
+	model.addLine(sectionDebit, false)
+	model.form.debitLines[1].accountInput.SetValue("Assets:Euro")
+	model.form.debitLines[1].amountInput.SetValue("€20*2")
+	model.form.creditLines[0].accountInput.SetValue("Equity:Conversion")
+	model.form.creditLines[0].amountInput.SetValue("-10 AAPL")
+	model.recalculateTotals()
+
+	if view := model.renderTransactionView(); !strings.Contains(view, "Debits   (total 10 AAPL, €40.00)") {
+		t.Fatalf("expected per-commodity debit total, got %q", view)
+	}
+	if model.confirmTransaction() {
+		t.Fatalf("expected unbalanced euros to block confirmation")
+	}
+	if got := model.form.debitLines[1].amountInput.Value(); got != "€40.00" {
+		t.Fatalf("expected expression to keep its commodity, got %q", got)
+	}
+
+	model.addLine(sectionCredit, false)
+	model.form.creditLines[1].accountInput.SetValue("Equity:Conversion")
+	model.recalculateTotals()
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyCtrlB})
+	if got := model.form.creditLines[1].amountInput.Value(); got != "€-40.00" {
+		t.Fatalf("expected balance shortcut to fill the unbalanced commodity, got %q", got)
+	}
+
+	if !model.confirmTransaction() {
+		t.Fatalf("expected balanced transaction to confirm: %s", model.statusMessage)
+	}
+	postings := model.batch[0].Postings
+	if postings[0].Amount.String() != "10 AAPL" || postings[1].Amount.String() != "€40.00" {
+		t.Fatalf("unexpected postings: %+v", postings)
+	}
+}
+
+func TestBalanceToleranceAcceptsOneCent(t *testing.T) {
+	if !isBalanced(core.Balance{"$": decimal.RequireFromString("0.01")}) {
+		t.Errorf("expected a difference of the tolerance to balance")
+	}
+	if isBalanced(core.Balance{"$": decimal.RequireFromString("-0.02")}) {
+		t.Errorf("expected a difference over the tolerance not to balance")
+	}
+}
+
+func TestVirtualPostingsStayOutOfTheBalance(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Grocery Store")
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food")
+	model.form.debitLines[0].amountInput.SetValue("40")
+	model.form.creditLines[0].accountInput.SetValue("Assets:Checking")
+	model.form.creditLines[0].amountInput.SetValue("-40")
+	model.addLine(sectionCredit, false)
+	model.form.creditLines[1].accountInput.SetValue("Budget:Food")
+	model.form.creditLines[1].amountInput.SetValue("-40")
+	model.focusSection(sectionCredit, 1, focusSectionAccount)
+
+	// ctrl+t cycles the line through virtual, balanced virtual and back to real
+	expected := []string{"(Budget:Food)", "[Budget:Food]", "Budget:Food", "(Budget:Food)"}
+	for _, want := range expected {
+		model.updateTransactionView(tea.KeyMsg{Type: tea.KeyCtrlT})
+		if got := model.form.creditLines[1].accountInput.Value(); got != want {
+			t.Fatalf("expected account %q, got %q", want, got)
+		}
+	}
+	if !isBalanced(model.form.remaining) {
+		t.Fatalf("expected the virtual line to stay out of the remaining balance, got %v", model.form.remaining)
+	}
+
+	if !model.confirmTransaction() {
+		t.Fatalf("expected transaction to confirm: %s", model.statusMessage)
+	}
+	posting := model.batch[0].Postings[2]
+	if posting.Account != "Budget:Food" || posting.Kind != core.VirtualPosting {
+		t.Fatalf("expected a virtual Budget:Food posting, got %+v", posting)
+	}
+}
+
+func TestFormPreviewsAutomatedPostings(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db := testDB(t)
+	predicate, err := core.ParsePredicate("/^Expenses:Food/")
+	if err != nil {
+		t.Fatalf("ParsePredicate returned error: %v", err)
+	}
+	db.Automated = []core.AutomatedTransaction{{
+		Predicate: predicate,
+		Postings:  []core.Posting{{Account: "Budget:Food", Kind: core.VirtualPosting, Amount: core.NewAmount(decimal.RequireFromString("-1"), "")}},
+	}}
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Sample Market")
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Fun")
+	model.form.debitLines[0].amountInput.SetValue("25")
+	model.form.creditLines[0].accountInput.SetValue("Assets:Checking")
+	model.form.creditLines[0].amountInput.SetValue("-25")
+	model.recalculateTotals()
+	if view := model.renderTransactionView(); strings.Contains(view, "Automated") {
+		t.Fatalf("expected no automated postings for unmatched accounts, got %q", view)
+	}
+
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food:Groceries")
+	view := model.renderTransactionView()
+	if !strings.Contains(view, "Automated (added by the ledger)") || !strings.Contains(view, "(Budget:Food)") || !strings.Contains(view, "-25") {
+		t.Fatalf("expected the automated budget posting in the preview, got %q", view)
+	}
+
+	// The ledger adds automated postings when it reads the file, so they aren't written
+	if !model.confirmTransaction() {
+		t.Fatalf("expected transaction to confirm: %s", model.statusMessage)
+	}
+	if postings := model.batch[0].Postings; len(postings) != 2 {
+		t.Fatalf("expected only the entered postings, got %+v", postings)
+	}
+}
+
+func TestStrictModeRequiresDeclaredAccounts(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{Accounts: []string{"Expenses:Food", "Assets:Checking"}})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.SetStrict(true)
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Grocery Store")
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Fod")
+	model.form.debitLines[0].amountInput.SetValue("40")
+	model.form.creditLines[0].accountInput.SetValue("Assets:Checking")
+	model.form.creditLines[0].amountInput.SetValue("-40")
+	model.recalculateTotals()
+
+	if model.confirmTransaction() {
+		t.Fatalf("expected an undeclared account to block confirmation")
+	}
+	if model.statusMessage != "Account Expenses:Fod is not declared" {
+		t.Fatalf("unexpected status: %q", model.statusMessage)
+	}
+
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food")
+	if !model.confirmTransaction() {
+		t.Fatalf("expected declared accounts to confirm: %s", model.statusMessage)
+	}
+}
+
+func TestFormPostingsUseDeclaredCommodityFormat(t *testing.T) {
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{Commodities: []core.Commodity{{Symbol: "€", Format: "1.000,00 €"}}})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food")
+	model.form.debitLines[0].amountInput.SetValue("12,50 €")
+	model.addLine(sectionDebit, false)
+	model.form.debitLines[1].accountInput.SetValue("Expenses:Rent")
+	model.form.debitLines[1].amountInput.SetValue("€1.234,5")
+	model.form.creditLines[0].accountInput.SetValue("Assets:Checking")
+	model.form.creditLines[0].amountInput.SetValue("€-1.247,00")
+	model.recalculateTotals()
+
+	postings := model.formPostings()
+	if len(postings) != 3 {
+		t.Fatalf("expected three postings, got %+v", postings)
+	}
+	want := []struct{ quantity, amount string }{
+		{"12.5", "12,50 €"},
+		{"1234.5", "1.234,50 €"},
+		{"-1247", "-1.247,00 €"},
+	}
+	for i, w := range want {
+		if !postings[i].Amount.Quantity.Equal(decimal.RequireFromString(w.quantity)) {
+			t.Errorf("posting %d: expected quantity %s, got %s", i, w.quantity, postings[i].Amount.Quantity)
+		}
+		if got := postings[i].Amount.String(); got != w.amount {
+			t.Errorf("posting %d: expected %q, got %q", i, w.amount, got)
+		}
+	}
+	if !model.form.remaining.IsZero() {
+		t.Errorf("expected the form to balance, got %s", model.form.remaining)
+	}
+
+	// Expressions use the declared decimal mark, and are shown back in the declared format
+	line := &model.form.debitLines[0]
+	line.amountInput.SetValue("€12,50*2")
+	if !model.evaluateInput(&line.amountInput) || line.amountInput.Value() != "25,00 €" {
+		t.Errorf("expected the expression to evaluate to 25,00 €, got %q (%s)", line.amountInput.Value(), model.statusMessage)
+	}
+	line.setPredictedAmount(decimal.RequireFromString("1234.5"), "€", db.Formats)
+	if amount := lineAmount(line, db.Formats); amount == nil || !amount.Quantity.Equal(decimal.RequireFromString("1234.5")) {
+		t.Errorf("expected the predicted amount %q to read back as 1234.5, got %v", line.amountInput.Value(), amount)
+	}
+
+	// Text that isn't a number in the declared style is rejected rather than guessed
+	line.amountInput.SetValue("€1234.5")
+	if model.evaluateInput(&line.amountInput) {
+		t.Errorf("expected an amount written with a decimal point to be rejected, got %q", line.amountInput.Value())
+	}
+}
+
+func TestMixedCommodityTotalsShowConvertedValue(t *testing.T) {
+	date := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{Prices: []core.MarketPrice{
+		{Date: date, Commodity: "AAPL", Price: *core.NewAmount(decimal.RequireFromString("150.00"), "$")},
+		{Date: date, Commodity: "€", Price: *core.NewAmount(decimal.RequireFromString("1.10"), "$")},
+	}})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.date.setTime(date.AddDate(0, 0, 3))
+	model.form.debitLines[0].amountInput.SetValue("2 AAPL")
+	model.addLine(sectionDebit, false)
+	model.form.debitLines[1].amountInput.SetValue("€10.00")
+	model.form.creditLines[0].amountInput.SetValue("-50")
+	model.recalculateTotals()
+
+	view := model.renderTransactionView()
+	if !strings.Contains(view, "Debits   (total 2 AAPL, €10.00 ≈ $311.00)") {
+		t.Fatalf("expected converted debit total, got %q", view)
+	}
+	if !strings.Contains(view, "Remaining: $-50.00, 2 AAPL, €10.00 --") {
+		t.Fatalf("expected remaining balance per commodity, got %q", view)
+	}
+	if !strings.Contains(view, "Credits  (total -50.00)\n") {
+		t.Fatalf("expected single-commodity credit total without conversion, got %q", view)
+	}
+
+	model.SetCurrency("€")
+	if view := model.renderTransactionView(); !strings.Contains(view, "Credits  (total -50.00 ≈ €-45.45)") {
+		t.Fatalf("expected totals converted to the reporting currency, got %q", view)
+	}
+}
+
+func TestPriceFieldBalancesAtCost(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Broker")
+	model.form.debitLines[0].accountInput.SetValue("Assets:Brokerage")
+	model.focusSection(sectionDebit, 0, focusSectionAmount)
+	for _, r := range "10 AAPL" {
+		model.updateTransactionView(keyRunes(r))
+	}
+
+	// Shares get a price field between the amount and the comment
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
+	if model.form.focusedField != focusSectionPrice {
+		t.Fatalf("expected price field to follow a share amount, got %v", model.form.focusedField)
+	}
+	for _, r := range "$150" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	model.recalculateTotals()
+	if view := model.renderTransactionView(); !strings.Contains(view, "Debits   (total 1500.00)") {
+		t.Fatalf("expected debit total at cost, got %q", view)
+	}
+
+	model.form.creditLines[0].accountInput.SetValue("Assets:Checking")
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyCtrlB})
+	if got := model.form.creditLines[0].amountInput.Value(); got != "-1500.00" {
+		t.Fatalf("expected balance shortcut to fill the cost, got %q", got)
+	}
+	if !model.confirmTransaction() {
+		t.Fatalf("expected priced transaction to confirm: %s", model.statusMessage)
+	}
+	posting := model.batch[0].Postings[0]
+	if posting.Price == nil || posting.Price.String() != "@ $150" {
+		t.Fatalf("expected price to be saved, got %+v", posting)
+	}
+
+	// Editing brings the price back into its field
+	model.startEditingTransaction(0)
+	if got := model.form.debitLines[0].priceInput.Value(); got != "@ $150" {
+		t.Fatalf("expected price to be restored for editing, got %q", got)
+	}
+}
+
+func TestTagsAutocompleteAndSurviveEditing(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	db := testDB(t)
+	db.Tags["travel"] = 3
+	db.Tags["reimbursable"] = 1
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.SetBatch([]core.Transaction{{
+		Date:     time.Date(2025, time.March, 4, 0, 0, 0, 0, time.UTC),
+		Payee:    "Airline",
+		Status:   core.Cleared,
+		Notes:    []string{"Booked online", "Trip: Berlin"},
+		Metadata: map[string]string{"Trip": "Berlin"},
+		Postings: []core.Posting{
+			{Account: "Expenses:Travel", Amount: core.NewAmount(decimal.RequireFromString("300.00"), ""), Notes: []string{"Receipt: emailed"}},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-300.00"), "")},
+		},
+	}})
+
+	model.startEditingTransaction(0)
+	model.moveFocusToPosition(focusPosition{field: focusTags})
+	for _, r := range "tr" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	if len(model.form.suggestions) == 0 || model.form.suggestions[0].Text != "travel" {
+		t.Fatalf("expected tag suggestion, got %+v", model.form.suggestions)
+	}
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
+	if got := model.form.tagsInput.Value(); got != "travel " {
+		t.Fatalf("expected tab to complete the tag, got %q", got)
+	}
+
+	// Only the tag being typed is completed, and entered tags aren't offered again
+	for _, r := range "r" {
+		model.updateTransactionView(keyRunes(r))
+	}
+	for _, match := range model.form.suggestions {
+		if match.Text == "travel" {
+			t.Fatalf("expected entered tag to be left out, got %+v", model.form.suggestions)
+		}
+	}
+	model.updateTransactionView(tea.KeyMsg{Type: tea.KeyTab})
+	if got := model.form.tagsInput.Value(); got != "travel reimbursable " {
+		t.Fatalf("expected second tag to be completed, got %q", got)
+	}
+
+	if !model.confirmTransaction() {
+		t.Fatalf("expected edited transaction to confirm: %s", model.statusMessage)
+	}
+	tx := model.batch[0]
+	if !reflect.DeepEqual(tx.Tags, []string{"travel", "reimbursable"}) {
+		t.Fatalf("expected tags to be saved, got %v", tx.Tags)
+	}
+	if !reflect.DeepEqual(tx.Notes, []string{"Booked online", "Trip: Berlin"}) || tx.Metadata["Trip"] != "Berlin" {
+		t.Fatalf("expected notes and metadata to survive editing, got %q and %v", tx.Notes, tx.Metadata)
+	}
+	if tx.Postings[0].Metadata["Receipt"] != "emailed" {
+		t.Fatalf("expected posting metadata to survive editing, got %+v", tx.Postings[0])
+	}
+	if db.Runtime.Tags["reimbursable"] != 1 {
+		t.Fatalf("expected runtime intelligence to learn the tags, got %v", db.Runtime.Tags)
+	}
+}
+
 func TestTemplateSelectionPopulatesSections(t *testing.T) {
 	db := testDB(t)
 	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
@@ -340,6 +831,48 @@ func TestTemplateSelectionPopulatesSections(t *testing.T) {
 	}
 }
 
+func TestTemplatePrefillsPredictedAmounts(t *testing.T) {
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{Transactions: []core.Transaction{
+		{
+			Date:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			Payee: "Streaming Co",
+			Postings: []core.Posting{
+				{Account: "Expenses:Subscriptions", Amount: core.NewAmount(decimal.RequireFromString("15.99"), "")},
+				{Account: "Liabilities:Credit Card", Amount: core.NewAmount(decimal.RequireFromString("-15.99"), "")},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.payeeInput.SetValue("Streaming Co")
+	model.openTemplateSelection()
+	if view := model.renderTemplateView(); !strings.Contains(view, "Predicted $15.99 (last amount)") {
+		t.Fatalf("expected template view to show the prediction, got %q", view)
+	}
+	model.updateTemplateView(tea.KeyMsg{Type: tea.KeyEnter})
+
+	debit := &model.form.debitLines[0]
+	credit := &model.form.creditLines[0]
+	if debit.amountInput.Value() != "15.99" || credit.amountInput.Value() != "-15.99" {
+		t.Fatalf("expected predicted amounts, got %q / %q", debit.amountInput.Value(), credit.amountInput.Value())
+	}
+	if !model.form.remaining.IsZero() {
+		t.Fatalf("expected predicted amounts to balance, remaining %s", model.form.remaining)
+	}
+	if view := model.renderTransactionView(); strings.Count(view, "~predicted") != 2 {
+		t.Fatalf("expected both lines to be marked as predicted, got %q", view)
+	}
+
+	debit.amountInput.SetValue("17.99")
+	if view := model.renderTransactionView(); strings.Count(view, "~predicted") != 1 {
+		t.Fatalf("expected edited amount to lose its marker, got %q", view)
+	}
+}
+
 func TestTemplateViewDisplaysAccountsVertically(t *testing.T) {
 	db := testDB(t)
 	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
@@ -520,6 +1053,322 @@ func TestQuitConfirmDisplaysPendingCount(t *testing.T) {
 	}
 }
 
+func TestWriteBlockedWhileDraftsRemain(t *testing.T) {
+	db := testDB(t)
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.windowHeight = 24
+	model.batch = []core.Transaction{
+		{Payee: "Categorized"},
+		{Payee: "ACH DEBIT 1234", Draft: true, Description: "ACH DEBIT 1234"},
+	}
+
+	model.updateBatchView(keyRunes('w'))
+
+	if model.currentView != viewBatch {
+		t.Fatalf("expected write to stay on batch view, got %v", model.currentView)
+	}
+	if !strings.Contains(model.statusMessage, "1 imported draft(s)") {
+		t.Fatalf("expected draft warning, got %q", model.statusMessage)
+	}
+	if view := model.renderBatchView(); !strings.Contains(view, "(uncategorized)") {
+		t.Fatalf("expected draft to be marked in batch view, got %q", view)
+	}
+}
+
+func TestConfirmFlagsDuplicateUntilConfirmedAgain(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	recorded := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{Transactions: []core.Transaction{
+		{
+			Date:  recorded,
+			Payee: "Sample Market",
+			Postings: []core.Posting{
+				{Account: "Expenses:Food:Groceries", Amount: core.NewAmount(decimal.RequireFromString("42.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-42.00"), "")},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.startNewTransaction()
+	model.form.date.setTime(recorded.AddDate(0, 0, 2))
+	model.form.payeeInput.SetValue("sample market")
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food:Groceries")
+	model.form.debitLines[0].amountInput.SetValue("42")
+	model.form.creditLines[0].accountInput.SetValue("Assets:Credit Card")
+	model.form.creditLines[0].amountInput.SetValue("-42")
+
+	if model.confirmTransaction() {
+		t.Fatalf("expected first confirm to be held for a possible duplicate")
+	}
+	if view := model.renderTransactionView(); !strings.Contains(view, "Possible duplicate of 2025-01-15 Sample Market ($42.00) in the ledger") {
+		t.Fatalf("expected inline duplicate warning, got %q", view)
+	}
+
+	// Changing the entry re-runs the check instead of accepting the old warning
+	model.form.debitLines[0].amountInput.SetValue("42.00")
+	model.form.creditLines[0].amountInput.SetValue("-42.00")
+	model.form.commentInput.SetValue("second trip")
+	if model.confirmTransaction() {
+		t.Fatalf("expected edited form to be checked again")
+	}
+
+	if !model.confirmTransaction() {
+		t.Fatalf("expected second confirm to accept, got status %q", model.statusMessage)
+	}
+	if len(model.batch) != 1 || model.currentView != viewBatch {
+		t.Fatalf("expected transaction to be added, got %d entries in view %v", len(model.batch), model.currentView)
+	}
+
+	// Batch entries count too, but never the entry being edited
+	enterCoffee := func() {
+		model.startNewTransaction()
+		model.form.date.setTime(recorded)
+		model.form.payeeInput.SetValue("Coffee Shop")
+		model.form.debitLines[0].accountInput.SetValue("Expenses:Food:Coffee")
+		model.form.debitLines[0].amountInput.SetValue("4.50")
+		model.form.creditLines[0].accountInput.SetValue("Assets:Checking")
+		model.form.creditLines[0].amountInput.SetValue("-4.50")
+	}
+	enterCoffee()
+	if !model.confirmTransaction() {
+		t.Fatalf("expected first coffee to be added, got status %q", model.statusMessage)
+	}
+	enterCoffee()
+	if model.confirmTransaction() || !strings.Contains(model.activeDuplicateWarning(), "in the batch") {
+		t.Fatalf("expected repeat coffee to be flagged against the batch, got %q", model.activeDuplicateWarning())
+	}
+	model.cancelTransaction()
+
+	model.startEditingTransaction(model.cursor)
+	if !model.confirmTransaction() {
+		t.Fatalf("expected edit to skip comparing the entry with itself, got status %q", model.statusMessage)
+	}
+}
+
+func TestBatchAddsExpectedRecurringTransaction(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// Weekly lessons, last recorded ten days ago, so one is due three days ago
+	now := time.Now()
+	lastEntry := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -10)
+	var history []core.Transaction
+	for week := 3; week >= 0; week-- {
+		history = append(history, core.Transaction{
+			Date:  lastEntry.AddDate(0, 0, -7*week),
+			Payee: "Piano Lessons",
+			Postings: []core.Posting{
+				{Account: "Expenses:Education", Amount: core.NewAmount(decimal.RequireFromString("60.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-60.00"), "")},
+			},
+		})
+	}
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{Transactions: history})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.windowHeight = 24
+	due := lastEntry.AddDate(0, 0, 7).Format("2006-01-02")
+	view := model.renderBatchView()
+	if !strings.Contains(view, "Expected but not yet entered:") || !strings.Contains(view, "[1] "+due+" Piano Lessons") {
+		t.Fatalf("expected due lesson to be listed, got %q", view)
+	}
+
+	model.updateBatchView(keyRunes('1'))
+
+	if model.currentView != viewTransaction || len(model.batch) != 0 {
+		t.Fatalf("expected the expected transaction to open in the form, got view %v with %d entries", model.currentView, len(model.batch))
+	}
+	if !model.confirmTransaction() {
+		t.Fatalf("expected prefilled entry to confirm: %s", model.statusMessage)
+	}
+	tx := model.batch[0]
+	if tx.Payee != "Piano Lessons" || tx.Date.Format("2006-01-02") != due || tx.Postings[0].Amount.String() != "60.00" {
+		t.Fatalf("unexpected prefilled entry: %+v", tx)
+	}
+	if view := model.renderBatchView(); strings.Contains(view, "Expected but not yet entered:") {
+		t.Fatalf("expected entered lesson to drop off the list, got %q", view)
+	}
+}
+
+func TestExpectedBudgetIsCheckedBeforeJoiningBatch(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	// A monthly rent budget with an elided funding amount, due since the last entry
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	db, _, err := intelligence.NewIntelligenceDB(core.ParseResult{
+		Transactions: []core.Transaction{{
+			Date:  today.AddDate(0, 0, -40),
+			Payee: "Cafe",
+			Postings: []core.Posting{
+				{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("4.00"), "")},
+				{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-4.00"), "")},
+			},
+		}},
+		Periodic: []core.PeriodicTransaction{{
+			Period:      "Monthly",
+			Interval:    core.Interval{Months: 1},
+			Start:       time.Date(today.Year()-1, today.Month(), 1, 0, 0, 0, 0, time.UTC),
+			Description: "Rent",
+			Postings: []core.Posting{
+				{Account: "Expenses:Rent", Amount: core.NewAmount(decimal.RequireFromString("1500.00"), "$")},
+				{Account: "Assets:Checking"},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("failed to build intelligence db: %v", err)
+	}
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.windowHeight = 24
+	model.updateBatchView(keyRunes('1'))
+
+	if model.currentView != viewTransaction {
+		t.Fatalf("expected the budget to open in the form, got view %v", model.currentView)
+	}
+	credit := model.form.creditLines[0]
+	if credit.accountInput.Value() != "Assets:Checking" || credit.amountInput.Value() != "" {
+		t.Fatalf("expected the elided posting as an empty credit line, got %q %q", credit.accountInput.Value(), credit.amountInput.Value())
+	}
+	if model.confirmTransaction() || len(model.batch) != 0 {
+		t.Fatalf("expected the unbalanced budget to be rejected")
+	}
+
+	model.form.creditLines[0].amountInput.SetValue("$-1500.00")
+	model.recalculateTotals()
+	if !model.confirmTransaction() {
+		t.Fatalf("expected the completed budget to confirm: %s", model.statusMessage)
+	}
+	if tx := model.batch[0]; tx.Payee != "Rent" || len(tx.Postings) != 2 || tx.Postings[1].Amount.String() != "-1500.00" {
+		t.Fatalf("unexpected entry: %+v", tx)
+	}
+}
+
+func reviewBatch() []core.Transaction {
+	return []core.Transaction{
+		{
+			Payee:       "Sample Market",
+			Status:      core.Cleared,
+			Draft:       true,
+			Description: "Sample Market",
+			Postings:    []core.Posting{{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-42.00"), "")}},
+		},
+		{
+			Payee:       "UNKNOWN VENDOR 991",
+			Status:      core.Cleared,
+			Draft:       true,
+			Description: "UNKNOWN VENDOR 991",
+			Postings:    []core.Posting{{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-9.00"), "")}},
+		},
+	}
+}
+
+func TestReviewAcceptsTopSuggestion(t *testing.T) {
+	db := testDB(t)
+
+	t.Chdir(t.TempDir())
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.batch = reviewBatch()
+
+	model.updateBatchView(keyRunes('r'))
+	if model.currentView != viewReview {
+		t.Fatalf("expected review view, got %v", model.currentView)
+	}
+	view := model.renderReviewView()
+	if !strings.Contains(view, "0 of 2 categorized") {
+		t.Fatalf("expected progress counter, got %q", view)
+	}
+	if !strings.Contains(view, "Expenses:Food:Groceries") {
+		t.Fatalf("expected template suggestion in review view, got %q", view)
+	}
+
+	model.updateReviewView(keyRunes('a'))
+
+	tx := model.batch[0]
+	if tx.Draft {
+		t.Fatalf("expected accepted draft to be categorized")
+	}
+	if len(tx.Postings) != 2 || tx.Postings[0].Account != "Expenses:Food:Groceries" || tx.Postings[0].Amount.String() != "42.00" {
+		t.Fatalf("unexpected postings after accept: %+v", tx.Postings)
+	}
+	if model.currentView != viewReview {
+		t.Fatalf("expected to stay in review for remaining draft, got %v", model.currentView)
+	}
+	if view := model.renderReviewView(); !strings.Contains(view, "1 of 2 categorized") || !strings.Contains(view, "UNKNOWN VENDOR 991") {
+		t.Fatalf("expected next draft with updated progress, got %q", view)
+	}
+
+	// No template matches the second draft, so accept is a no-op
+	model.updateReviewView(keyRunes('a'))
+	if !model.batch[1].Draft {
+		t.Fatalf("expected draft without suggestion to remain uncategorized")
+	}
+}
+
+func TestReviewSkipAndDrop(t *testing.T) {
+	db := testDB(t)
+
+	t.Chdir(t.TempDir())
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.batch = reviewBatch()
+	model.openReview()
+
+	model.updateReviewView(keyRunes('s'))
+	if index := model.currentDraftIndex(); index != 1 {
+		t.Fatalf("expected skip to move to second draft, got index %d", index)
+	}
+
+	model.updateReviewView(keyRunes('d'))
+	if len(model.batch) != 1 || model.batch[0].Payee != "Sample Market" {
+		t.Fatalf("expected second draft to be dropped, got %+v", model.batch)
+	}
+	if model.currentView != viewBatch {
+		t.Fatalf("expected end of queue to return to batch, got %v", model.currentView)
+	}
+}
+
+func TestReviewEditReturnsToQueue(t *testing.T) {
+	db := testDB(t)
+
+	t.Chdir(t.TempDir())
+
+	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
+	model.batch = reviewBatch()
+	model.openReview()
+
+	model.updateReviewView(keyRunes('e'))
+	if model.currentView != viewTransaction {
+		t.Fatalf("expected edit to open transaction form, got %v", model.currentView)
+	}
+	if model.form.focusedSection != sectionDebit || model.form.focusedField != focusSectionAccount {
+		t.Fatalf("expected focus on empty debit account for draft")
+	}
+
+	model.form.debitLines[0].accountInput.SetValue("Expenses:Food:Groceries")
+	model.form.debitLines[0].amountInput.SetValue("42.00")
+	if !model.confirmTransaction() {
+		t.Fatalf("expected draft edit to confirm, status=%q", model.statusMessage)
+	}
+
+	if model.currentView != viewReview {
+		t.Fatalf("expected to return to review view, got %v", model.currentView)
+	}
+	categorized, total := model.reviewProgress()
+	if categorized != 1 || total != 2 {
+		t.Fatalf("expected 1 of 2 categorized, got %d of %d", categorized, total)
+	}
+}
+
 func TestTemplateApplyThenTabAndAddLineDoesNotPanic(t *testing.T) {
 	db := testDB(t)
 	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
@@ -557,29 +1406,21 @@ func TestTemplateApplyThenTabAndAddLineDoesNotPanic(t *testing.T) {
 func TestUpdateRecoverySavesBatchSession(t *testing.T) {
 	db := testDB(t)
 
-	tempDir := t.TempDir()
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd: %v", err)
-	}
-	defer func() { _ = os.Chdir(wd) }()
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("chdir: %v", err)
-	}
+	t.Chdir(t.TempDir())
 
 	model := NewModel(db, "ledger.dat", intelligence.BuildReport{})
 	model.batch = []core.Transaction{{
 		Payee: "Recovered Transaction",
 		Postings: []core.Posting{
-			{Account: "Expenses:Food", Amount: "10.00"},
-			{Account: "Assets:Checking", Amount: "-10.00"},
+			{Account: "Expenses:Food", Amount: core.NewAmount(decimal.RequireFromString("10.00"), "")},
+			{Account: "Assets:Checking", Amount: core.NewAmount(decimal.RequireFromString("-10.00"), "")},
 		},
 	}}
 
 	model.startNewTransaction()
 	model.form.focusedField = focusPayee
 	model.form.payeeInput.Focus()
-	model.db = nil // force panic in refreshSuggestions -> m.db.FindPayees
+	model.db = nil // force panic in refreshSuggestions -> m.db.MatchPayees
 
 	if _, _ = model.Update(keyRunes('x')); model.err == nil {
 		t.Fatalf("expected panic to be recovered and exposed via model.err")
@@ -597,18 +1438,19 @@ func TestUpdateRecoverySavesBatchSession(t *testing.T) {
 	}
 }
 
+// confirmAcceptingDuplicates confirms the form, pressing ctrl+s a second time if a duplicate is flagged
+func confirmAcceptingDuplicates(model *Model) bool {
+	if model.confirmTransaction() {
+		return true
+	}
+	return model.activeDuplicateWarning() != "" && model.confirmTransaction()
+}
+
 func TestLongSessionSmokeDiverseInputs(t *testing.T) {
 	db := testDB(t)
 
 	tempDir := t.TempDir()
-	wd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("getwd: %v", err)
-	}
-	defer func() { _ = os.Chdir(wd) }()
-	if err := os.Chdir(tempDir); err != nil {
-		t.Fatalf("chdir: %v", err)
-	}
+	t.Chdir(tempDir)
 
 	ledgerPath := filepath.Join(tempDir, "ledger.dat")
 	if err := os.WriteFile(ledgerPath, []byte(""), 0644); err != nil {
@@ -713,7 +1555,7 @@ func TestLongSessionSmokeDiverseInputs(t *testing.T) {
 		model.form.creditLines[0].amountInput.SetValue(debitTotal.Add(otherCredits).Neg().StringFixed(2))
 		model.form.creditLines[0].amountInput.CursorEnd()
 
-		if !model.confirmTransaction() {
+		if !confirmAcceptingDuplicates(model) {
 			t.Fatalf("iteration %d: confirm failed with status %q", i, model.statusMessage)
 		}
 		if model.err != nil {
@@ -730,7 +1572,7 @@ func TestLongSessionSmokeDiverseInputs(t *testing.T) {
 				t.Fatalf("iteration %d: expected transaction view while editing", i)
 			}
 			model.form.commentInput.SetValue(fmt.Sprintf("edited-%d", i))
-			if !model.confirmTransaction() {
+			if !confirmAcceptingDuplicates(model) {
 				t.Fatalf("iteration %d: edit confirm failed with status %q", i, model.statusMessage)
 			}
 			if model.err != nil {
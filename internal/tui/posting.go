@@ -4,6 +4,8 @@ import (
 	"strings"
 	"time"
 
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/util"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/shopspring/decimal"
 )
@@ -15,7 +17,6 @@ func newTextInput(placeholder string) textinput.Model {
 	ti.Prompt = ""
 	ti.CharLimit = 256
 	ti.Width = 40
-	ti.ShowSuggestions = true
 	return ti
 }
 
@@ -23,27 +24,181 @@ func newTextInput(placeholder string) textinput.Model {
 func newPostingLine() postingLine {
 	account := newTextInput("Account")
 	amount := newTextInput("Amount")
-	amount.ShowSuggestions = false
+	price := newTextInput("@ Price")
+	price.Width = 20
 	comment := newTextInput("Comment")
-	comment.ShowSuggestions = false
 	comment.Width = 30
-	return postingLine{accountInput: account, amountInput: amount, commentInput: comment}
+	return postingLine{accountInput: account, amountInput: amount, priceInput: price, commentInput: comment}
 }
 
-// lineAmount extracts the decimal amount from a posting line
-// Returns zero if the amount is empty or invalid
-func lineAmount(line *postingLine) decimal.Decimal {
-	value := strings.TrimSpace(line.amountInput.Value())
-	if value == "" {
-		return decimal.Zero
+// hasPriceField reports whether the line offers a price field
+// Only amounts in another commodity, such as shares or foreign currency, are priced
+func (l *postingLine) hasPriceField(formats core.CommodityFormats) bool {
+	if strings.TrimSpace(l.priceInput.Value()) != "" {
+		return true
+	}
+	amount := lineAmount(l, formats)
+	return amount != nil && amount.Unit() != core.DefaultCommodity
+}
+
+// setPredictedAmount pre-fills the amount field with a value predicted from history
+func (l *postingLine) setPredictedAmount(amount decimal.Decimal, commodity string, formats core.CommodityFormats) {
+	l.predictedAmount = formatAmountInput(core.NewAmount(amount, commodity), formats)
+	l.amountInput.SetValue(l.predictedAmount)
+	l.amountInput.CursorEnd()
+}
+
+// isPredicted reports whether the amount field still holds its predicted value
+func (l *postingLine) isPredicted() bool {
+	return l.predictedAmount != "" && strings.TrimSpace(l.amountInput.Value()) == l.predictedAmount
+}
+
+// kind returns the posting kind the line's account field is written as
+func (l *postingLine) kind() core.PostingKind {
+	_, kind := parseAccountInput(l.accountInput.Value())
+	return kind
+}
+
+// cycleKind switches the line between a real, virtual and balanced virtual posting
+func (l *postingLine) cycleKind() {
+	account, kind := parseAccountInput(l.accountInput.Value())
+	l.accountInput.SetValue(((kind + 1) % 3).FormatAccount(account))
+	l.accountInput.CursorEnd()
+}
+
+// parseAccountInput reads an account field, where "(Account)" is a virtual posting and
+// "[Account]" a balanced virtual one; the closing bracket may still be missing while typing
+func parseAccountInput(value string) (string, core.PostingKind) {
+	value = strings.TrimSpace(value)
+	kind := core.RealPosting
+	switch {
+	case strings.HasPrefix(value, "("):
+		kind = core.VirtualPosting
+		value = strings.TrimSuffix(value[1:], ")")
+	case strings.HasPrefix(value, "["):
+		kind = core.BalancedVirtualPosting
+		value = strings.TrimSuffix(value[1:], "]")
 	}
-	amount, err := decimal.NewFromString(value)
+	return strings.TrimSpace(value), kind
+}
+
+// lineAmount extracts the amount from a posting line
+// Returns nil if the amount is empty or invalid
+func lineAmount(line *postingLine, formats core.CommodityFormats) *core.Amount {
+	amount, err := parseAmountInput(line.amountInput.Value(), formats)
 	if err != nil {
-		return decimal.Zero
+		return nil
 	}
 	return amount
 }
 
+// linePosting builds the posting a line describes, without its account and comment
+// Returns false if the amount is empty or the amount or price is invalid
+func linePosting(line *postingLine, formats core.CommodityFormats) (core.Posting, bool) {
+	amount := lineAmount(line, formats)
+	if amount == nil {
+		return core.Posting{}, false
+	}
+	lot, price, err := parsePriceInput(line.priceInput.Value())
+	if err != nil {
+		return core.Posting{}, false
+	}
+	return core.Posting{Amount: amount, Lot: lot, Price: price}, true
+}
+
+// parsePriceInput reads a price field such as "$150", "@@ $1500" or "{$148} @ $150"
+// A bare amount is a per-unit price
+func parsePriceInput(value string) (*core.Price, *core.Price, error) {
+	value = strings.TrimSpace(value)
+	if value != "" && !strings.HasPrefix(value, "@") && !strings.HasPrefix(value, "{") {
+		value = "@ " + value
+	}
+	return core.ParseAnnotations(value)
+}
+
+// formatPriceInput formats a posting's lot cost and price for a price field
+func formatPriceInput(lot, price *core.Price) string {
+	var parts []string
+	if lot != nil {
+		parts = append(parts, lot.LotString())
+	}
+	if price != nil {
+		parts = append(parts, price.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseAmountInput reads an amount field, which may be an expression with a commodity
+// before or after it, such as "12.50*2", "€30" or "10 AAPL"
+// Commodities declared with a format are read in its style, so "12,50 €" is 12.50 when € has a decimal comma
+// Bare numbers are in the default commodity; returns nil for an empty field
+func parseAmountInput(value string, formats core.CommodityFormats) (*core.Amount, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	commodity, expression, suffix := core.SplitCommodity(value)
+	if commodity == core.DefaultCommodity {
+		commodity = ""
+	}
+	if !isArithmetic(expression) {
+		// Plain quantities are read as the parser reads them, so "12,50" has a decimal comma and a
+		// misplaced separator is an error; those with a commodity keep their precision, so shares aren't
+		// rounded to cents
+		amount, err := formats.ParseAmount(value)
+		if err != nil {
+			return nil, err
+		}
+		if commodity != "" {
+			return amount, nil
+		}
+	} else if format, ok := formats.Sample(commodity); ok {
+		expression = plainExpression(expression, format.Style)
+	}
+	evaluated, err := util.EvaluateExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := decimal.NewFromString(evaluated)
+	if err != nil {
+		return nil, err
+	}
+	amount := core.NewAmount(quantity, commodity)
+	if commodity != "" {
+		amount.Suffix = suffix
+		amount.Spaced = suffix
+	}
+	return amount, nil
+}
+
+// isArithmetic reports whether an amount field holds an expression rather than a plain quantity
+func isArithmetic(expression string) bool {
+	return strings.ContainsAny(expression, "+*/()") || strings.LastIndex(expression, "-") > 0
+}
+
+// plainExpression rewrites the numbers of an expression written in a declared style with decimal points
+func plainExpression(expression string, style core.NumberStyle) string {
+	if !style.DecimalComma {
+		return expression
+	}
+	if style.Group != 0 {
+		expression = strings.ReplaceAll(expression, string(style.Group), "")
+	}
+	return strings.ReplaceAll(expression, ",", ".")
+}
+
+// formatAmountInput formats an amount for an amount field
+// Default commodity amounts are shown as bare numbers, others in their declared format
+func formatAmountInput(amount *core.Amount, formats core.CommodityFormats) string {
+	if amount == nil {
+		return ""
+	}
+	if amount.Unit() == core.DefaultCommodity {
+		return amount.Quantity.StringFixed(2)
+	}
+	return formats.Apply(*amount).String()
+}
+
 // categorySeed extracts the category prefix from an account value
 // Used when cloning a line to suggest a related account
 func categorySeed(value string) string {
@@ -125,24 +280,29 @@ func newTransactionForm(baseDate time.Time) transactionForm {
 
 	payee := newTextInput("Payee")
 	comment := newTextInput("Comment")
-	comment.ShowSuggestions = false
 	comment.Width = 60
+	tags := newTextInput("Tags")
+	tags.Width = 60
+	code := newTextInput("Code")
+	code.Width = 12
 
 	debit := []postingLine{newPostingLine()}
 	credit := []postingLine{newPostingLine()}
 
 	return transactionForm{
 		date:           date,
-		cleared:        true,
+		status:         core.Cleared,
 		payeeInput:     payee,
 		commentInput:   comment,
+		tagsInput:      tags,
+		codeInput:      code,
 		debitLines:     debit,
 		creditLines:    credit,
 		focusedField:   focusDate,
 		focusedSection: sectionDebit,
 		focusedIndex:   0,
-		remaining:      decimal.Zero,
-		debitTotal:     decimal.Zero,
-		creditTotal:    decimal.Zero,
+		remaining:      core.Balance{},
+		debitTotal:     core.Balance{},
+		creditTotal:    core.Balance{},
 	}
 }
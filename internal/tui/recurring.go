@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
+)
+
+// maxExpectedDisplay is the number of expected transactions listed, one per digit key
+const maxExpectedDisplay = 9
+
+// expectedTransactions returns recurring transactions due since the last ledger date
+// that have no matching entry in the batch yet
+func (m *Model) expectedTransactions() []intelligence.DueTransaction {
+	if m.db == nil {
+		return nil
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return m.db.ExpectedTransactions(today, m.batch)
+}
+
+// expectedLines renders the expected-but-not-entered section of the batch view
+// Returns no lines when nothing is due, otherwise ends with a blank line
+func (m *Model) expectedLines() []string {
+	due := m.expectedTransactions()
+	if len(due) == 0 {
+		return nil
+	}
+	lines := []string{"Expected but not yet entered:"}
+	for i, item := range due {
+		if i == maxExpectedDisplay {
+			lines = append(lines, fmt.Sprintf("    ... %d more", len(due)-maxExpectedDisplay))
+			break
+		}
+		payee := item.Recurrence.Payee
+		if len(payee) > 28 {
+			payee = payee[:25] + "..."
+		}
+		frequency := item.Recurrence.Period.String()
+		if item.Recurrence.Budget != nil {
+			frequency += " budget"
+		}
+		lines = append(lines, fmt.Sprintf("[%d] %s %-28s %s %s", i+1, item.Date.Format("2006-01-02"), payee,
+			formatAmount(item.Recurrence.Amount, item.Recurrence.Commodity), formatFrequency(frequency)))
+	}
+	return append(lines, "")
+}
+
+// addExpectedTransaction opens the expected transaction at index prefilled in the form,
+// so it is checked like any other entry before it joins the batch
+func (m *Model) addExpectedTransaction(index int) {
+	due := m.expectedTransactions()
+	if index < 0 || index >= len(due) || index >= maxExpectedDisplay {
+		return
+	}
+	tx := due[index].Transaction()
+
+	m.loadTransaction(tx, -1)
+	m.setStatus(fmt.Sprintf("Expected %s for %s (adjust and confirm)", tx.Payee, tx.Date.Format("2006-01-02")), statusInfo, statusShortDuration)
+}
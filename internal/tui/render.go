@@ -3,8 +3,10 @@ package tui
 import (
 	"fmt"
 	"strings"
+	"time"
 
-	"github.com/charmbracelet/bubbles/textinput"
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
 	"github.com/shopspring/decimal"
 )
 
@@ -16,6 +18,10 @@ func (m *Model) renderBatchView() string {
 		fmt.Fprintf(&b, "%s\n", line)
 	}
 	b.WriteString("\n")
+	expected := m.expectedLines()
+	for _, line := range expected {
+		fmt.Fprintf(&b, "%s\n", line)
+	}
 
 	if len(m.batch) == 0 {
 		b.WriteString("No transactions in current batch.\n\n")
@@ -24,6 +30,7 @@ func (m *Model) renderBatchView() string {
 		headerSize := 1 // title line
 		headerSize += len(m.loadSummaryLines())
 		headerSize += 1 // blank line after summary
+		headerSize += len(expected)
 
 		footerSize := 1 // blank line before commands
 		if msg := m.statusLine(); msg != "" {
@@ -73,7 +80,9 @@ func (m *Model) renderBatchView() string {
 				payee = payee[:25] + "..."
 			}
 			primary := ""
-			if len(tx.Postings) > 0 {
+			if tx.Draft {
+				primary = "uncategorized"
+			} else if len(tx.Postings) > 0 {
 				primary = tx.Postings[0].Account
 				parts := strings.Split(primary, ":")
 				primary = parts[len(parts)-1]
@@ -92,6 +101,12 @@ func (m *Model) renderBatchView() string {
 		fmt.Fprintf(&b, "%s\n\n", msg)
 	}
 	b.WriteString("[n]ew  [e]dit  [w]rite  [q]uit  [enter]edit selected")
+	if drafts := m.draftCount(); drafts > 0 {
+		fmt.Fprintf(&b, "  [r]eview %d draft(s)", drafts)
+	}
+	if len(expected) > 0 {
+		b.WriteString("  [1-9]add expected")
+	}
 	return b.String()
 }
 
@@ -123,56 +138,82 @@ func (m *Model) loadSummaryLines() []string {
 // renderTransactionView displays the transaction entry form
 func (m *Model) renderTransactionView() string {
 	var b strings.Builder
-	isBalanced := m.form.remaining.Abs().LessThan(decimal.NewFromFloat(balanceTolerance))
-	remainingStr := formatBalanced(m.form.remaining.StringFixed(2), isBalanced)
+	remaining := formatTotal(m.form.remaining)
+	if commodities := m.form.remaining.Commodities(); len(commodities) == 0 || (len(commodities) == 1 && commodities[0] == core.DefaultCommodity) {
+		remaining = core.DefaultCommodity + remaining
+	}
+	remainingStr := formatBalanced(remaining, isBalanced(m.form.remaining))
 	fmt.Fprintf(&b, "-- Transaction Entry -- Remaining: %s --\n\n", remainingStr)
 
 	dateDisplay := m.form.date.display(m.form.focusedField == focusDate)
-	clearedMark := " "
-	if m.form.cleared {
-		clearedMark = "x"
-	}
-	fmt.Fprintf(&b, "Date    %s  Cleared [%s]\n", dateDisplay, clearedMark)
+	fmt.Fprintf(&b, "Date    %s  Status [%s]\n", dateDisplay, m.form.status)
 	fmt.Fprintf(&b, "Payee   %s", m.form.payeeInput.View())
 	if m.form.focusedField == focusPayee {
-		b.WriteString(renderSuggestionList(m.form.payeeInput))
+		b.WriteString(m.renderSuggestionList(m.form.payeeInput.Value()))
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "Comment %s\n", m.form.commentInput.View())
+	fmt.Fprintf(&b, "Tags    %s", m.form.tagsInput.View())
+	if m.form.focusedField == focusTags {
+		_, token := splitTagsInput(m.form.tagsInput.Value())
+		b.WriteString(m.renderSuggestionList(token))
 	}
 	b.WriteString("\n")
-	fmt.Fprintf(&b, "Comment %s\n\n", m.form.commentInput.View())
+	fmt.Fprintf(&b, "Code    %s\n\n", m.form.codeInput.View())
 	buttonCursor := " "
 	if m.form.focusedField == focusTemplateButton {
 		buttonCursor = formatCursor(">")
 	}
 	fmt.Fprintf(&b, "        %s[%s]\n\n", buttonCursor, templateAvailabilityLabel(len(m.templateOptions)))
 
-	fmt.Fprintf(&b, "Debits   (total %s)\n", formatDebitTotal(m.form.debitTotal.StringFixed(2)))
+	fmt.Fprintf(&b, "Debits   (total %s%s)\n", formatDebitTotal(formatTotal(m.form.debitTotal)), m.convertedTotal(m.form.debitTotal))
 	for i, line := range m.form.debitLines {
 		cursor := " "
 		if m.lineHasFocus(sectionDebit, i) {
 			cursor = formatCursor(">")
 		}
-		fmt.Fprintf(&b, "%s [%s] [%s] [%s]", cursor, line.accountInput.View(), line.amountInput.View(), line.commentInput.View())
+		fmt.Fprintf(&b, "%s [%s] [%s]", cursor, line.accountInput.View(), line.amountInput.View())
+		if line.hasPriceField(m.db.Formats) {
+			fmt.Fprintf(&b, " [%s]", line.priceInput.View())
+		}
+		fmt.Fprintf(&b, " [%s]", line.commentInput.View())
+		if line.isPredicted() {
+			b.WriteString(" " + formatPredicted("~predicted"))
+		}
 		if m.lineHasFocus(sectionDebit, i) && m.form.focusedField == focusSectionAccount {
-			b.WriteString(renderSuggestionList(line.accountInput))
+			b.WriteString(m.renderSuggestionList(line.accountInput.Value()))
 		}
 		b.WriteString("\n")
 	}
 	b.WriteString("\n")
 
-	fmt.Fprintf(&b, "Credits  (total %s)\n", formatCreditTotal(m.form.creditTotal.StringFixed(2)))
+	fmt.Fprintf(&b, "Credits  (total %s%s)\n", formatCreditTotal(formatTotal(m.form.creditTotal)), m.convertedTotal(m.form.creditTotal))
 	for i, line := range m.form.creditLines {
 		cursor := " "
 		if m.lineHasFocus(sectionCredit, i) {
 			cursor = formatCursor(">")
 		}
-		fmt.Fprintf(&b, "%s [%s] [%s] [%s]", cursor, line.accountInput.View(), line.amountInput.View(), line.commentInput.View())
+		fmt.Fprintf(&b, "%s [%s] [%s]", cursor, line.accountInput.View(), line.amountInput.View())
+		if line.hasPriceField(m.db.Formats) {
+			fmt.Fprintf(&b, " [%s]", line.priceInput.View())
+		}
+		fmt.Fprintf(&b, " [%s]", line.commentInput.View())
+		if line.isPredicted() {
+			b.WriteString(" " + formatPredicted("~predicted"))
+		}
 		if m.lineHasFocus(sectionCredit, i) && m.form.focusedField == focusSectionAccount {
-			b.WriteString(renderSuggestionList(line.accountInput))
+			b.WriteString(m.renderSuggestionList(line.accountInput.Value()))
 		}
 		b.WriteString("\n")
 	}
 	b.WriteString("\n")
 
+	b.WriteString(m.renderAutomatedPostings())
+
+	if warning := m.activeDuplicateWarning(); warning != "" {
+		fmt.Fprintf(&b, "%s\n\n", formatIssues(warning))
+	}
+
 	if msg := m.statusLine(); msg != "" {
 		fmt.Fprintf(&b, "%s\n\n", msg)
 	}
@@ -183,7 +224,9 @@ func (m *Model) renderTransactionView() string {
 		formatCommand("[ctrl+a]add line", m.hasActiveLine()),
 		formatCommand("[ctrl+d]delete line", m.hasActiveLine()),
 		formatCommand("[ctrl+b]balance", m.canBalanceAnyLine()),
-		"[ctrl+c]toggle cleared",
+		formatCommand("[ctrl+t]virtual", m.hasActiveLine()),
+		formatCommand("[=]aux date", m.form.focusedField == focusDate),
+		"[ctrl+c]cycle status",
 		"[ctrl+s]confirm",
 		"[esc]cancel",
 		"[ctrl+q]quit",
@@ -192,6 +235,26 @@ func (m *Model) renderTransactionView() string {
 	return b.String()
 }
 
+// renderAutomatedPostings previews the postings the ledger's automated transactions add to the entry
+// They aren't written with it; ledger adds them again whenever the file is read
+func (m *Model) renderAutomatedPostings() string {
+	if m.db == nil || len(m.db.Automated) == 0 {
+		return ""
+	}
+	tx := core.Transaction{Payee: strings.TrimSpace(m.form.payeeInput.Value()), Postings: m.formPostings()}
+	generated := core.ApplyAutomated(m.db.Automated, tx)
+	if len(generated) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Automated (added by the ledger)\n")
+	for _, posting := range generated {
+		fmt.Fprintf(&b, "  %s %s\n", posting.Kind.FormatAccount(posting.Account), formatPredicted(posting.Amount.String()))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
 // templateAvailabilityLabel returns a label describing the number of templates available
 func templateAvailabilityLabel(count int) string {
 	if count == 1 {
@@ -200,6 +263,53 @@ func templateAvailabilityLabel(count int) string {
 	return fmt.Sprintf("%d templates available", count)
 }
 
+// predictionLabel describes the amount a template will pre-fill and where it came from
+func predictionLabel(stats intelligence.AmountStats, total decimal.Decimal) string {
+	if stats.CommonCount > 1 {
+		return fmt.Sprintf("Predicted %s (seen %d times, last %s)",
+			formatAmount(total, stats.Commodity), stats.CommonCount, formatAmount(stats.Last, stats.Commodity))
+	}
+	return fmt.Sprintf("Predicted %s (last amount)", formatAmount(total, stats.Commodity))
+}
+
+// formatAmount formats a quantity of a commodity for display, e.g. "$12.50" or "10 AAPL"
+func formatAmount(quantity decimal.Decimal, commodity string) string {
+	if commodity == "" || commodity == core.DefaultCommodity {
+		return core.DefaultCommodity + quantity.StringFixed(2)
+	}
+	return core.NewAmount(quantity, commodity).String()
+}
+
+// convertedTotal describes a total in other commodities converted to the reporting currency
+// at prices as of the form's date; empty when there's nothing to convert or a price is missing
+func (m *Model) convertedTotal(balance core.Balance) string {
+	commodities := balance.Commodities()
+	if len(commodities) == 0 || (len(commodities) == 1 && commodities[0] == m.currency) || m.db.Prices == nil {
+		return ""
+	}
+	date := m.form.date.time()
+	if date.IsZero() {
+		date = time.Now()
+	}
+	converted, missing := m.db.Prices.ConvertBalance(balance, m.currency, date)
+	if len(missing) > 0 {
+		return ""
+	}
+	return " ≈ " + formatAmount(converted.Quantity, m.currency)
+}
+
+// formatTotal formats a form total, listing each commodity when there is more than one
+func formatTotal(balance core.Balance) string {
+	commodities := balance.Commodities()
+	switch {
+	case len(commodities) == 0:
+		return decimal.Zero.StringFixed(2)
+	case len(commodities) == 1 && commodities[0] == core.DefaultCommodity:
+		return balance[core.DefaultCommodity].StringFixed(2)
+	}
+	return balance.String()
+}
+
 // renderTemplateView displays the template selection screen
 func (m *Model) renderTemplateView() string {
 	var b strings.Builder
@@ -225,6 +335,9 @@ func (m *Model) renderTemplateView() string {
 		}
 		frequencyText := fmt.Sprintf("Used %d %s", tpl.Frequency, usageLabel)
 		fmt.Fprintf(&b, "%s %d. %s\n", cursor, i+1, formatFrequency(frequencyText))
+		if total, ok := tpl.Amounts.Predicted(); ok {
+			fmt.Fprintf(&b, "    %s\n", formatPredicted(predictionLabel(tpl.Amounts, total)))
+		}
 		b.WriteString("    Debit Accounts:\n")
 		if len(tpl.DebitAccounts) == 0 {
 			b.WriteString("      (none)\n")
@@ -272,21 +385,87 @@ func (m *Model) renderConfirmView() string {
 	return b.String()
 }
 
+// renderReviewView displays the draft currently under review in the import queue
+func (m *Model) renderReviewView() string {
+	var b strings.Builder
+	categorized, total := m.reviewProgress()
+	fmt.Fprintf(&b, "-- Import Review (%d of %d categorized) --\n\n", categorized, total)
+
+	index := m.currentDraftIndex()
+	if index < 0 {
+		b.WriteString("No drafts to review\n\n[esc]back")
+		return b.String()
+	}
+	tx := m.batch[index]
+	description := tx.Description
+	if description == "" {
+		description = tx.Payee
+	}
+	fmt.Fprintf(&b, "Draft        %d of %d remaining\n", m.reviewCursor+1, m.draftCount())
+	fmt.Fprintf(&b, "Date         %s\n", tx.Date.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Description  %s\n", description)
+	for _, posting := range tx.Postings {
+		amount := ""
+		if posting.Amount != nil {
+			amount = formatAmountInput(posting.Amount, m.db.Formats)
+		}
+		fmt.Fprintf(&b, "Amount       %s  %s\n", amount, posting.Account)
+	}
+	b.WriteString("\n")
+
+	record, hasSuggestion := m.reviewSuggestion()
+	if hasSuggestion {
+		usageLabel := "times"
+		if record.Frequency == 1 {
+			usageLabel = "time"
+		}
+		fmt.Fprintf(&b, "Suggestion   %s\n", formatFrequency(fmt.Sprintf("Used %d %s", record.Frequency, usageLabel)))
+		for _, account := range record.DebitAccounts {
+			fmt.Fprintf(&b, "  Debit   %s\n", account)
+		}
+		for _, account := range record.CreditAccounts {
+			fmt.Fprintf(&b, "  Credit  %s\n", account)
+		}
+	} else {
+		b.WriteString("Suggestion   (none)\n")
+	}
+	b.WriteString("\n")
+
+	if msg := m.statusLine(); msg != "" {
+		fmt.Fprintf(&b, "%s\n\n", msg)
+	}
+
+	commands := []string{
+		formatCommand("[a]ccept suggestion", hasSuggestion),
+		"[e]dit",
+		"[s]kip",
+		"[d]rop",
+		"[esc]back",
+	}
+	b.WriteString(strings.Join(commands, "  "))
+	return b.String()
+}
+
 // renderSuggestionList displays autocomplete suggestions below an input field
-func renderSuggestionList(input textinput.Model) string {
-	matches := input.MatchedSuggestions()
-	if len(matches) <= 1 {
+// Matched characters are highlighted to show why each suggestion was offered
+func (m *Model) renderSuggestionList(value string) string {
+	matches := m.form.suggestions
+	if len(matches) == 0 || (len(matches) == 1 && strings.EqualFold(matches[0].Text, strings.TrimSpace(value))) {
 		return ""
 	}
 	var b strings.Builder
 	b.WriteString("\n")
 	display := min(len(matches), maxSuggestionDisplay)
-	for i := range display {
+	start := 0
+	if m.form.suggestionIndex >= display {
+		start = m.form.suggestionIndex - display + 1
+	}
+	for i := start; i < start+display; i++ {
 		cursor := " "
-		if i == input.CurrentSuggestionIndex() {
+		if i == m.form.suggestionIndex {
 			cursor = formatCursor(">")
 		}
-		fmt.Fprintf(&b, "      %s %s\n", cursor, matches[i])
+		fmt.Fprintf(&b, "      %s %s\n", cursor, formatMatch(matches[i].Text, matches[i].Positions))
 	}
 	if len(matches) > display {
 		fmt.Fprintf(&b, "      ... and %d more\n", len(matches)-display)
@@ -0,0 +1,169 @@
+package tui
+
+import (
+	"fmt"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
+	"git.sr.ht/~jakintosh/teller/internal/session"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// openReview starts walking through the uncategorized drafts from the beginning
+func (m *Model) openReview() {
+	if m.draftCount() == 0 {
+		m.setStatus("No imported drafts to review", statusInfo, statusShortDuration)
+		return
+	}
+	m.reviewCursor = 0
+	m.showReview()
+}
+
+// showReview displays the current draft, or returns to the batch when the queue is exhausted
+func (m *Model) showReview() {
+	drafts := m.draftCount()
+	if drafts == 0 {
+		m.setStatus("All imported drafts categorized", statusSuccess, statusShortDuration)
+		m.currentView = viewBatch
+		return
+	}
+	if m.reviewCursor >= drafts {
+		m.setStatus(fmt.Sprintf("End of review queue (%d draft(s) skipped)", drafts), statusInfo, statusShortDuration)
+		m.currentView = viewBatch
+		return
+	}
+	m.currentView = viewReview
+}
+
+// currentDraftIndex returns the batch index of the draft under review, or -1 if there is none
+func (m *Model) currentDraftIndex() int {
+	position := 0
+	for i, tx := range m.batch {
+		if !tx.Draft {
+			continue
+		}
+		if position == m.reviewCursor {
+			return i
+		}
+		position++
+	}
+	return -1
+}
+
+// reviewProgress returns how many imported transactions are categorized out of the total imported
+func (m *Model) reviewProgress() (categorized, total int) {
+	for _, tx := range m.batch {
+		if tx.Description == "" {
+			continue
+		}
+		total++
+		if !tx.Draft {
+			categorized++
+		}
+	}
+	return categorized, total
+}
+
+// reviewSuggestion returns the top template for the draft under review, if one can be applied
+func (m *Model) reviewSuggestion() (intelligence.TemplateRecord, bool) {
+	index := m.currentDraftIndex()
+	if index < 0 {
+		return intelligence.TemplateRecord{}, false
+	}
+	templates := m.db.FindTemplates(m.batch[index].Payee)
+	if len(templates) == 0 {
+		return intelligence.TemplateRecord{}, false
+	}
+	if _, ok := categorizeDraft(m.batch[index], templates[0]); !ok {
+		return intelligence.TemplateRecord{}, false
+	}
+	return templates[0], true
+}
+
+// updateReviewView handles keyboard input in the import review view
+func (m *Model) updateReviewView(msg tea.KeyMsg) tea.Cmd {
+	index := m.currentDraftIndex()
+	if index < 0 {
+		m.showReview()
+		return nil
+	}
+
+	switch msg.String() {
+	case "ctrl+q", "ctrl+c":
+		return tea.Quit
+	case "a", "enter":
+		record, ok := m.reviewSuggestion()
+		if !ok {
+			m.setStatus("No applicable suggestion; press e to edit", statusInfo, statusShortDuration)
+			return nil
+		}
+		tx, _ := categorizeDraft(m.batch[index], record)
+		m.batch[index] = tx
+		m.commitReviewChange("Draft categorized")
+		m.showReview()
+	case "e":
+		m.startEditingTransaction(index)
+		m.formReturnView = viewReview
+	case "s", "right":
+		m.reviewCursor++
+		m.showReview()
+	case "left":
+		if m.reviewCursor > 0 {
+			m.reviewCursor--
+		}
+	case "d":
+		m.batch = append(m.batch[:index], m.batch[index+1:]...)
+		if m.cursor >= len(m.batch) {
+			m.cursor = max(len(m.batch)-1, 0)
+		}
+		m.commitReviewChange("Draft dropped")
+		m.showReview()
+	case "esc":
+		m.currentView = viewBatch
+		m.ensureBatchCursorVisible()
+	}
+	return nil
+}
+
+// commitReviewChange refreshes runtime intelligence and persists the batch after a review action
+func (m *Model) commitReviewChange(message string) {
+	m.db.Runtime.BuildFromBatch(m.batch)
+	if err := session.SaveBatch(m.batch); err != nil {
+		m.setStatus(fmt.Sprintf("%s but session write failed: %v", message, err), statusError, statusDuration)
+		return
+	}
+	categorized, total := m.reviewProgress()
+	m.setStatus(fmt.Sprintf("%s (%d of %d categorized)", message, categorized, total), statusSuccess, statusShortDuration)
+}
+
+// categorizeDraft completes an imported draft using a template's accounts.
+// The template must have exactly one account on the side opposite the funding posting,
+// which receives the balancing amount. Returns false if the template doesn't fit.
+func categorizeDraft(draft core.Transaction, record intelligence.TemplateRecord) (core.Transaction, bool) {
+	if len(draft.Postings) != 1 {
+		return draft, false
+	}
+	funding := draft.Postings[0]
+	if funding.Amount == nil || funding.Amount.Quantity.IsZero() {
+		return draft, false
+	}
+	amount := funding.Amount.Quantity
+
+	counterAccounts := record.DebitAccounts
+	if amount.Sign() > 0 {
+		counterAccounts = record.CreditAccounts
+	}
+	if len(counterAccounts) != 1 {
+		return draft, false
+	}
+
+	counter := core.Posting{Account: counterAccounts[0], Amount: funding.Amount.Neg()}
+	tx := draft
+	tx.Draft = false
+	if amount.Sign() < 0 {
+		tx.Postings = []core.Posting{counter, funding}
+	} else {
+		tx.Postings = []core.Posting{funding, counter}
+	}
+	return tx, true
+}
@@ -1,6 +1,10 @@
 package tui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Color definitions for the TUI
 var (
@@ -24,14 +28,20 @@ var (
 	frequencyColor = lipgloss.NewStyle().Foreground(lipgloss.Color("14"))            // Cyan
 	dimmedColor    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))             // Dark grey for disabled commands
 	activeColor    = lipgloss.NewStyle().Foreground(lipgloss.Color("15"))            // White for active commands
+
+	// Suggestion colors
+	matchColor = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Bold(true) // Bold yellow for matched characters
+
+	// Prediction colors
+	predictedColor = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Italic(true) // Grey italic
 )
 
 // formatBalanced returns a colored string for the remaining balance
 func formatBalanced(amount string, isBalanced bool) string {
 	if isBalanced {
-		return balancedColor.Render("$" + amount)
+		return balancedColor.Render(amount)
 	}
-	return unbalancedColor.Render("$" + amount)
+	return unbalancedColor.Render(amount)
 }
 
 // formatStatus returns a colored status message based on the status kind
@@ -85,3 +95,28 @@ func formatCommand(text string, enabled bool) string {
 	}
 	return dimmedColor.Render(text)
 }
+
+// formatPredicted returns a marker for values predicted from history
+func formatPredicted(text string) string {
+	return predictedColor.Render(text)
+}
+
+// formatMatch returns text with the runes at the given positions highlighted
+func formatMatch(text string, positions []int) string {
+	if len(positions) == 0 {
+		return text
+	}
+	matched := make(map[int]bool, len(positions))
+	for _, pos := range positions {
+		matched[pos] = true
+	}
+	var b strings.Builder
+	for i, r := range []rune(text) {
+		if matched[i] {
+			b.WriteString(matchColor.Render(string(r)))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -1,32 +1,54 @@
 package tui
 
 import (
-	"sort"
 	"strings"
+
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
 )
 
 // refreshSuggestions updates the suggestion list for the currently focused input field
 func (m *Model) refreshSuggestions() {
+	var matches []intelligence.Match
 	switch m.form.focusedField {
 	case focusPayee:
-		m.form.payeeInput.SetSuggestions(m.db.FindPayees(m.form.payeeInput.Value()))
-	case focusSectionAccount:
-		if line := m.currentLine(); line != nil {
-			line.accountInput.SetSuggestions(m.accountSuggestions(line.accountInput.Value()))
+		if value := m.form.payeeInput.Value(); strings.TrimSpace(value) != "" {
+			matches = m.db.MatchPayees(value)
 		}
-	case focusSectionAmount:
-		if line := m.currentLine(); line != nil {
-			line.amountInput.SetSuggestions(nil)
-		}
-	case focusComment:
-		m.form.commentInput.SetSuggestions(nil)
-	case focusSectionComment:
-		if line := m.currentLine(); line != nil {
-			line.commentInput.SetSuggestions(nil)
+	case focusSectionAccount:
+		if line := m.currentLine(); line != nil && strings.TrimSpace(line.accountInput.Value()) != "" {
+			matches = m.accountSuggestions(line.accountInput.Value())
 		}
+	case focusTags:
+		matches = m.tagSuggestions(m.form.tagsInput.Value())
+	}
+	m.setSuggestions(matches)
+}
+
+// setSuggestions replaces the suggestion list, resetting the selection when the results change
+func (m *Model) setSuggestions(matches []intelligence.Match) {
+	changed := len(matches) != len(m.form.suggestions)
+	for i := 0; !changed && i < len(matches); i++ {
+		changed = matches[i].Text != m.form.suggestions[i].Text
+	}
+	m.form.suggestions = matches
+	if changed {
+		m.form.suggestionIndex = 0
+		m.form.suggestionChosen = false
 	}
 }
 
+// moveSuggestion moves the suggestion selection by delta, wrapping around the list
+// Returns true if there were suggestions to move through
+func (m *Model) moveSuggestion(delta int) bool {
+	if len(m.form.suggestions) == 0 {
+		return false
+	}
+	count := len(m.form.suggestions)
+	m.form.suggestionIndex = ((m.form.suggestionIndex+delta)%count + count) % count
+	m.form.suggestionChosen = true
+	return true
+}
+
 // refreshTemplateOptions updates the template options based on the current payee
 func (m *Model) refreshTemplateOptions() {
 	payee := strings.TrimSpace(m.form.payeeInput.Value())
@@ -44,6 +66,8 @@ func (m *Model) refreshTemplateOptions() {
 }
 
 // tryAcceptSuggestion attempts to accept the current suggestion for the focused input
+// Suggestions that merely contain the typed characters are only accepted once picked
+// with up/down, so tab never replaces a new payee with a loose fuzzy match
 // Returns true if a suggestion was accepted
 func (m *Model) tryAcceptSuggestion() bool {
 	input := m.currentTextInput()
@@ -58,19 +82,30 @@ func (m *Model) tryAcceptSuggestion() bool {
 		return false
 	}
 
-	matches := input.MatchedSuggestions()
-	idx := input.CurrentSuggestionIndex()
-	if idx < 0 || idx >= len(matches) {
+	idx := m.form.suggestionIndex
+	if idx < 0 || idx >= len(m.form.suggestions) {
 		return false
 	}
 
-	suggestion := matches[idx]
+	suggestion := m.form.suggestions[idx].Text
 	if suggestion == "" {
 		return false
 	}
-	if strings.EqualFold(strings.TrimSpace(input.Value()), suggestion) {
+	value := strings.TrimSpace(input.Value())
+	// Tag suggestions complete the last tag typed, keeping the ones before it
+	before := ""
+	if input == &m.form.tagsInput {
+		before, value = splitTagsInput(input.Value())
+	}
+	if strings.EqualFold(value, suggestion) {
 		return false
 	}
+	if !m.form.suggestionChosen && !strings.HasPrefix(strings.ToLower(suggestion), strings.ToLower(value)) {
+		return false
+	}
+	if input == &m.form.tagsInput {
+		suggestion = before + suggestion + " "
+	}
 	input.SetValue(suggestion)
 	input.CursorEnd()
 	m.refreshSuggestions()
@@ -81,14 +116,18 @@ func (m *Model) tryAcceptSuggestion() bool {
 	return true
 }
 
-// accountSuggestions generates hierarchical account suggestions based on the prefix
-func (m *Model) accountSuggestions(prefix string) []string {
-	raw := m.db.FindAccounts(prefix)
-	if len(raw) == 0 {
-		return nil
+// accountSuggestions generates hierarchical account suggestions for the prefix,
+// followed by full account paths that only match it fuzzily
+// Both are ranked by usage, favouring accounts used with the current payee
+func (m *Model) accountSuggestions(prefix string) []intelligence.Match {
+	// Virtual postings are typed with an opening bracket, which suggestions keep
+	if strings.HasPrefix(prefix, "(") || strings.HasPrefix(prefix, "[") {
+		return wrapMatches(prefix[:1], m.accountSuggestions(prefix[1:]))
 	}
+	payee := strings.TrimSpace(m.form.payeeInput.Value())
+	raw := m.db.FindAccounts(prefix, payee)
 	seen := make(map[string]struct{})
-	suggestions := make([]string, 0, len(raw))
+	hierarchical := make([]string, 0, len(raw))
 	for _, account := range raw {
 		suggestion := nextHierarchicalSuggestion(prefix, account)
 		if suggestion == "" {
@@ -99,12 +138,42 @@ func (m *Model) accountSuggestions(prefix string) []string {
 			continue
 		}
 		seen[key] = struct{}{}
-		suggestions = append(suggestions, suggestion)
+		// Accounts arrive ranked, so a segment takes the rank of its best account
+		hierarchical = append(hierarchical, suggestion)
+	}
+
+	// The typed prefix is what matched, so highlight it
+	prefixPositions := make([]int, len([]rune(prefix)))
+	for i := range prefixPositions {
+		prefixPositions[i] = i
+	}
+	suggestions := make([]intelligence.Match, 0, len(hierarchical))
+	for _, suggestion := range hierarchical {
+		suggestions = append(suggestions, intelligence.Match{Text: suggestion, Positions: prefixPositions})
+	}
+
+	for _, match := range m.db.MatchAccounts(prefix, payee) {
+		if strings.HasPrefix(strings.ToLower(match.Text), strings.ToLower(prefix)) {
+			continue
+		}
+		suggestions = append(suggestions, match)
 	}
-	sort.Strings(suggestions)
 	return suggestions
 }
 
+// wrapMatches prefixes each match with open, shifting its matched positions to suit
+func wrapMatches(open string, matches []intelligence.Match) []intelligence.Match {
+	wrapped := make([]intelligence.Match, len(matches))
+	for i, match := range matches {
+		positions := make([]int, len(match.Positions))
+		for j, pos := range match.Positions {
+			positions[j] = pos + len([]rune(open))
+		}
+		wrapped[i] = intelligence.Match{Text: open + match.Text, Positions: positions, Score: match.Score}
+	}
+	return wrapped
+}
+
 // nextHierarchicalSuggestion calculates the next hierarchical account suggestion
 // For example, given prefix "Exp" and account "Expenses:Food:Groceries", returns "Expenses"
 // Given prefix "Expenses:" and the same account, returns "Expenses:Food"
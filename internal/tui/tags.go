@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
+)
+
+// isTagSeparator reports whether r separates tags typed into the tags input
+func isTagSeparator(r rune) bool {
+	return r == ' ' || r == ',' || r == ':'
+}
+
+// parseTagsInput returns the tags typed into the tags input, in order and without repeats
+// Tags may be separated by spaces or commas, or written ledger-style as ":travel:work:"
+func parseTagsInput(value string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(value, isTagSeparator) {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// splitTagsInput splits the tags input into the text before the tag being typed and that tag
+func splitTagsInput(value string) (string, string) {
+	i := strings.LastIndexFunc(value, isTagSeparator)
+	return value[:i+1], value[i+1:]
+}
+
+// tagSuggestions matches the tag being typed against known tags, leaving out tags already entered
+func (m *Model) tagSuggestions(value string) []intelligence.Match {
+	before, token := splitTagsInput(value)
+	if token == "" {
+		return nil
+	}
+	entered := parseTagsInput(before)
+	var matches []intelligence.Match
+	for _, match := range m.db.MatchTags(token) {
+		if !slices.Contains(entered, match.Text) {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// carryNotes keeps the comment lines of the transaction being edited, which the form doesn't show
+// Posting notes follow their account
+func carryNotes(tx *core.Transaction, original core.Transaction) {
+	tx.Notes = original.Notes
+	for i := range tx.Postings {
+		for _, posting := range original.Postings {
+			if posting.Account == tx.Postings[i].Account {
+				tx.Postings[i].Notes = posting.Notes
+				break
+			}
+		}
+	}
+}
+
+// applyTags sets the tags and metadata of tx and its postings from their comments,
+// with the tags entered in the form first, along with any posting dates
+func applyTags(tx *core.Transaction, tags []string) {
+	commentTags, metadata := core.ParseTags(append([]string{tx.Comment}, tx.Notes...)...)
+	for _, tag := range commentTags {
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	tx.Tags, tx.Metadata = tags, metadata
+	for i := range tx.Postings {
+		posting := &tx.Postings[i]
+		comments := append([]string{posting.Comment}, posting.Notes...)
+		posting.Tags, posting.Metadata = core.ParseTags(comments...)
+		posting.Date, posting.AuxDate = core.ParseDateTag(comments...)
+	}
+}
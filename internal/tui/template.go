@@ -49,12 +49,20 @@ func (m *Model) updateTemplateView(msg tea.KeyMsg) tea.Cmd {
 }
 
 // applyTemplate populates the transaction form with accounts from the selected template
+// Amounts are pre-filled from the template's history when there is any
 func (m *Model) applyTemplate(record intelligence.TemplateRecord) {
+	total, predicted := record.Amounts.Predicted()
+	debitAmounts := intelligence.Split(total, record.Amounts.DebitRatios)
+	creditAmounts := intelligence.Split(total, record.Amounts.CreditRatios)
+
 	m.form.debitLines = nil
-	for _, account := range record.DebitAccounts {
+	for i, account := range record.DebitAccounts {
 		line := newPostingLine()
 		line.accountInput.SetValue(account)
 		line.accountInput.CursorEnd()
+		if predicted && i < len(debitAmounts) {
+			line.setPredictedAmount(debitAmounts[i], record.Amounts.Commodity, m.db.Formats)
+		}
 		m.form.debitLines = append(m.form.debitLines, line)
 	}
 	if len(m.form.debitLines) == 0 {
@@ -62,10 +70,13 @@ func (m *Model) applyTemplate(record intelligence.TemplateRecord) {
 	}
 
 	m.form.creditLines = nil
-	for _, account := range record.CreditAccounts {
+	for i, account := range record.CreditAccounts {
 		line := newPostingLine()
 		line.accountInput.SetValue(account)
 		line.accountInput.CursorEnd()
+		if predicted && i < len(creditAmounts) {
+			line.setPredictedAmount(creditAmounts[i].Neg(), record.Amounts.Commodity, m.db.Formats)
+		}
 		m.form.creditLines = append(m.form.creditLines, line)
 	}
 	if len(m.form.creditLines) == 0 {
@@ -6,7 +6,6 @@ import (
 	"git.sr.ht/~jakintosh/teller/internal/core"
 	"git.sr.ht/~jakintosh/teller/internal/intelligence"
 	"github.com/charmbracelet/bubbles/textinput"
-	"github.com/shopspring/decimal"
 )
 
 // Constants define UI behavior and tolerances
@@ -26,6 +25,7 @@ const (
 	viewTransaction
 	viewTemplate
 	viewConfirm
+	viewReview
 )
 
 // confirmKind represents the type of confirmation being requested
@@ -55,9 +55,12 @@ const (
 	focusCleared
 	focusPayee
 	focusComment
+	focusTags
+	focusCode
 	focusTemplateButton
 	focusSectionAccount
 	focusSectionAmount
+	focusSectionPrice
 	focusSectionComment
 )
 
@@ -76,6 +79,9 @@ const (
 	dateSegmentYear dateSegment = iota
 	dateSegmentMonth
 	dateSegmentDay
+	dateSegmentAuxYear
+	dateSegmentAuxMonth
+	dateSegmentAuxDay
 )
 
 // focusPosition uniquely identifies a focusable element in the form
@@ -91,6 +97,8 @@ type Model struct {
 	db             *intelligence.IntelligenceDB
 	ledgerFilePath string
 	buildReport    intelligence.BuildReport
+	currency       string // reporting commodity mixed-commodity totals are converted to
+	strict         bool   // only accounts declared in the ledger may be used
 
 	batch       []core.Transaction
 	cursor      int
@@ -105,7 +113,9 @@ type Model struct {
 	templatePayee     string
 	pendingConfirm    confirmKind
 	confirmReturnView viewState
+	formReturnView    viewState
 	editingIndex      int
+	reviewCursor      int
 
 	windowHeight  int
 	lastDate      time.Time
@@ -118,24 +128,40 @@ type Model struct {
 // transactionForm holds the state for the transaction entry form
 type transactionForm struct {
 	date           dateField
-	cleared        bool
+	status         core.Status
 	payeeInput     textinput.Model
 	commentInput   textinput.Model
+	tagsInput      textinput.Model
+	codeInput      textinput.Model
 	debitLines     []postingLine
 	creditLines    []postingLine
 	focusedField   focusedField
 	focusedSection sectionType
 	focusedIndex   int
-	remaining      decimal.Decimal
-	debitTotal     decimal.Decimal
-	creditTotal    decimal.Decimal
+	remaining      core.Balance
+	debitTotal     core.Balance
+	creditTotal    core.Balance
+
+	// suggestions are the autocomplete matches for the focused payee or account input;
+	// suggestionChosen is set once the user moves through them with up/down
+	suggestions      []intelligence.Match
+	suggestionIndex  int
+	suggestionChosen bool
+
+	// duplicateWarning describes a possible duplicate flagged on the last ctrl+s;
+	// confirming again with an unchanged form (duplicateSnapshot) accepts it
+	duplicateWarning  string
+	duplicateSnapshot formSnapshot
 }
 
 type formSnapshot struct {
 	date    time.Time
-	cleared bool
+	auxDate time.Time
+	status  core.Status
 	payee   string
 	comment string
+	tags    string
+	code    string
 	debit   []postingSnapshot
 	credit  []postingSnapshot
 }
@@ -143,23 +169,29 @@ type formSnapshot struct {
 type postingSnapshot struct {
 	account string
 	amount  string
+	price   string
 	comment string
 }
 
 // postingLine represents a single debit or credit line in the form
 type postingLine struct {
-	accountInput textinput.Model
-	amountInput  textinput.Model
-	commentInput textinput.Model
+	accountInput    textinput.Model
+	amountInput     textinput.Model
+	priceInput      textinput.Model // lot cost and price, offered for non-default commodities
+	commentInput    textinput.Model
+	predictedAmount string // amount pre-filled from template history, if any
 }
 
-// dateField manages a date with segment-based navigation
+// dateField manages a date and an optional auxiliary date with segment-based navigation
 type dateField struct {
-	year    int
-	month   int
-	day     int
-	segment dateSegment
-	buffer  string
+	year     int
+	month    int
+	day      int
+	auxYear  int // zero when there is no auxiliary date
+	auxMonth int
+	auxDay   int
+	segment  dateSegment
+	buffer   string
 }
 
 // statusTick is sent periodically to update status message expiry
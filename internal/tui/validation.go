@@ -2,29 +2,53 @@ package tui
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 
 	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
 	"git.sr.ht/~jakintosh/teller/internal/session"
-	"git.sr.ht/~jakintosh/teller/internal/util"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/shopspring/decimal"
 )
 
 // recalculateTotals updates the debit, credit, and remaining totals based on current posting lines
+// Totals are kept per commodity, with priced lines counted at their cost
+// Virtual postings don't have to balance, so they're left out
 func (m *Model) recalculateTotals() {
-	debit := decimal.Zero
+	debit := core.Balance{}
 	for i := range m.form.debitLines {
-		debit = debit.Add(lineAmount(&m.form.debitLines[i]))
+		line := &m.form.debitLines[i]
+		if posting, ok := linePosting(line, m.db.Formats); ok && line.kind() != core.VirtualPosting {
+			debit.Add(*posting.Weight())
+		}
 	}
-	credit := decimal.Zero
+	credit := core.Balance{}
 	for i := range m.form.creditLines {
-		credit = credit.Add(lineAmount(&m.form.creditLines[i]))
+		line := &m.form.creditLines[i]
+		if posting, ok := linePosting(line, m.db.Formats); ok && line.kind() != core.VirtualPosting {
+			credit.Add(*posting.Weight())
+		}
+	}
+	remaining := core.Balance{}
+	for commodity, quantity := range debit {
+		remaining[commodity] = quantity
+	}
+	for commodity, quantity := range credit {
+		remaining[commodity] = remaining[commodity].Add(quantity)
 	}
 	m.form.debitTotal = debit
 	m.form.creditTotal = credit
-	m.form.remaining = debit.Add(credit)
+	m.form.remaining = remaining
+}
+
+// isBalanced reports whether every commodity in a balance is within the balance tolerance
+func isBalanced(balance core.Balance) bool {
+	for _, quantity := range balance {
+		if quantity.Abs().GreaterThan(decimal.NewFromFloat(balanceTolerance)) {
+			return false
+		}
+	}
+	return true
 }
 
 // evaluateAmountField evaluates the expression in the currently focused amount field
@@ -44,36 +68,52 @@ func (m *Model) evaluateInput(input *textinput.Model) bool {
 	if value == "" {
 		return true
 	}
-	evaluated, err := util.EvaluateExpression(value)
+	amount, err := parseAmountInput(value, m.db.Formats)
 	if err != nil {
 		m.setStatus(fmt.Sprintf("Invalid expression: %v", err), statusError, statusDuration)
 		return false
 	}
-	input.SetValue(evaluated)
+	input.SetValue(formatAmountInput(amount, m.db.Formats))
 	input.CursorEnd()
 	return true
 }
 
+// validatePriceInput checks the price field of a posting line
+// Returns true if the price is valid or the field is empty
+func (m *Model) validatePriceInput(line *postingLine) bool {
+	if _, _, err := parsePriceInput(line.priceInput.Value()); err != nil {
+		m.setStatus(fmt.Sprintf("Invalid price: %v", err), statusError, statusDuration)
+		return false
+	}
+	return true
+}
+
 // canBalanceAnyLine returns true if there is exactly one unfilled amount in the entire form
 // This check works regardless of which field is currently focused
+// Virtual lines don't balance, so they're never filled
 func (m *Model) canBalanceAnyLine() bool {
 	if len(m.form.debitLines)+len(m.form.creditLines) < 2 {
 		return false
 	}
 	unfilled := 0
 	for i := range m.form.debitLines {
-		if strings.TrimSpace(m.form.debitLines[i].amountInput.Value()) == "" {
+		if needsBalancingAmount(&m.form.debitLines[i]) {
 			unfilled++
 		}
 	}
 	for i := range m.form.creditLines {
-		if strings.TrimSpace(m.form.creditLines[i].amountInput.Value()) == "" {
+		if needsBalancingAmount(&m.form.creditLines[i]) {
 			unfilled++
 		}
 	}
 	return unfilled == 1
 }
 
+// needsBalancingAmount reports whether a line is an unfilled amount that takes part in the balance
+func needsBalancingAmount(line *postingLine) bool {
+	return strings.TrimSpace(line.amountInput.Value()) == "" && line.kind() != core.VirtualPosting
+}
+
 // balanceAnyLine finds the single unfilled amount field and fills it to balance the transaction
 // Works regardless of which field is currently focused
 // Returns true if a line was successfully balanced
@@ -85,33 +125,33 @@ func (m *Model) balanceAnyLine() bool {
 	// Find the unfilled line
 	for i := range m.form.debitLines {
 		line := &m.form.debitLines[i]
-		if strings.TrimSpace(line.amountInput.Value()) == "" {
-			// Calculate the balancing amount
-			difference := m.form.remaining.Sub(lineAmount(line)).Neg()
-			if difference.IsZero() {
-				return false
-			}
-			line.amountInput.SetValue(difference.StringFixed(2))
-			line.amountInput.CursorEnd()
-			return true
+		if needsBalancingAmount(line) {
+			return m.fillBalancingAmount(line)
 		}
 	}
 	for i := range m.form.creditLines {
 		line := &m.form.creditLines[i]
-		if strings.TrimSpace(line.amountInput.Value()) == "" {
-			// Calculate the balancing amount
-			difference := m.form.remaining.Sub(lineAmount(line)).Neg()
-			if difference.IsZero() {
-				return false
-			}
-			line.amountInput.SetValue(difference.StringFixed(2))
-			line.amountInput.CursorEnd()
-			return true
+		if needsBalancingAmount(line) {
+			return m.fillBalancingAmount(line)
 		}
 	}
 	return false
 }
 
+// fillBalancingAmount sets a line's amount to whatever is left to balance
+// Only possible when a single commodity remains unbalanced
+func (m *Model) fillBalancingAmount(line *postingLine) bool {
+	commodities := m.form.remaining.Commodities()
+	if len(commodities) != 1 {
+		return false
+	}
+	commodity := commodities[0]
+	difference := core.NewAmount(m.form.remaining[commodity].Neg(), commodity)
+	line.amountInput.SetValue(formatAmountInput(difference, m.db.Formats))
+	line.amountInput.CursorEnd()
+	return true
+}
+
 // confirmTransaction validates and saves the current transaction to the batch
 // Returns true if the transaction was successfully confirmed
 func (m *Model) confirmTransaction() bool {
@@ -135,6 +175,13 @@ func (m *Model) confirmTransaction() bool {
 	for i := range m.form.creditLines {
 		_ = m.evaluateInput(&m.form.creditLines[i].amountInput)
 	}
+	for _, lines := range [][]postingLine{m.form.debitLines, m.form.creditLines} {
+		for i := range lines {
+			if !m.validatePriceInput(&lines[i]) {
+				return false
+			}
+		}
+	}
 	m.recalculateTotals()
 
 	// Validate structure
@@ -148,59 +195,54 @@ func (m *Model) confirmTransaction() bool {
 	}
 
 	// Validate balance
-	difference := m.form.debitTotal.Add(m.form.creditTotal).Abs()
-	if difference.GreaterThan(decimal.NewFromFloat(balanceTolerance)) {
+	// Every commodity must balance on its own
+	if !isBalanced(m.form.remaining) {
 		m.setStatus("Transaction must balance (sum of all amounts = 0)", statusError, statusDuration)
 		return false
 	}
 
 	// Build postings list
-	// Debits and Credits are purely organizational - amounts stored as-entered with no sign manipulation
-	postings := make([]core.Posting, 0, len(m.form.debitLines)+len(m.form.creditLines))
-	for i := range m.form.debitLines {
-		line := &m.form.debitLines[i]
-		account := strings.TrimSpace(line.accountInput.Value())
-		amount := lineAmount(line)
-		if account == "" || amount.IsZero() {
-			continue
-		}
-		postings = append(postings, core.Posting{
-			Account: account,
-			Amount:  amount.StringFixed(2),
-			Comment: strings.TrimSpace(line.commentInput.Value()),
-		})
-	}
-	for i := range m.form.creditLines {
-		line := &m.form.creditLines[i]
-		account := strings.TrimSpace(line.accountInput.Value())
-		amount := lineAmount(line)
-		if account == "" || amount.IsZero() {
-			continue
-		}
-		postings = append(postings, core.Posting{
-			Account: account,
-			Amount:  amount.StringFixed(2),
-			Comment: strings.TrimSpace(line.commentInput.Value()),
-		})
-	}
-
+	postings := m.formPostings()
 	if len(postings) < 2 {
 		m.setStatus("Incomplete transaction", statusError, statusShortDuration)
 		return false
 	}
+	if account, ok := m.undeclaredAccount(postings); ok {
+		m.setStatus(fmt.Sprintf("Account %s is not declared", account), statusError, statusDuration)
+		return false
+	}
 
 	// Create transaction
 	tx := core.Transaction{
 		Date:     date,
+		AuxDate:  m.form.date.auxTime(),
 		Payee:    m.form.payeeInput.Value(),
 		Comment:  strings.TrimSpace(m.form.commentInput.Value()),
-		Cleared:  m.form.cleared,
+		Status:   m.form.status,
+		Code:     strings.TrimSpace(m.form.codeInput.Value()),
 		Postings: postings,
 	}
+	if m.editingIndex >= 0 && m.editingIndex < len(m.batch) {
+		carryNotes(&tx, m.batch[m.editingIndex])
+	}
+	applyTags(&tx, parseTagsInput(m.form.tagsInput.Value()))
+
+	// Double entry after an import is easy, so ask before adding a likely duplicate
+	snapshot := m.currentFormSnapshot()
+	if m.form.duplicateWarning == "" || !snapshot.equals(m.form.duplicateSnapshot) {
+		if warning, ok := m.duplicateWarningFor(tx); ok {
+			m.form.duplicateWarning = warning
+			m.form.duplicateSnapshot = snapshot
+			m.setStatus("Possible duplicate - press ctrl+s again to confirm", statusError, statusDuration)
+			return false
+		}
+	}
 
 	// Add or update transaction in batch
 	wasEdit := m.editingIndex >= 0 && m.editingIndex < len(m.batch)
 	if wasEdit {
+		// Keep the raw statement text of imported drafts once they're categorized
+		tx.Description = m.batch[m.editingIndex].Description
 		m.batch[m.editingIndex] = tx
 	} else {
 		m.batch = append(m.batch, tx)
@@ -210,13 +252,7 @@ func (m *Model) confirmTransaction() bool {
 	// This ensures new payees, accounts, and templates are immediately available for suggestions
 	m.db.Runtime.BuildFromBatch(m.batch)
 
-	// Sort batch by date and payee
-	sort.SliceStable(m.batch, func(i, j int) bool {
-		if m.batch[i].Date.Equal(m.batch[j].Date) {
-			return m.batch[i].Payee < m.batch[j].Payee
-		}
-		return m.batch[i].Date.Before(m.batch[j].Date)
-	})
+	m.sortBatch()
 
 	// Update cursor to the confirmed transaction
 	m.cursor = m.findTransactionIndex(tx)
@@ -235,10 +271,78 @@ func (m *Model) confirmTransaction() bool {
 	m.lastDate = date
 	m.resetForm(date)
 	m.ensureBatchCursorVisible()
-	m.currentView = viewBatch
+	m.leaveForm()
 	return true
 }
 
+// undeclaredAccount returns the first account in postings the ledger doesn't declare
+// Only checked in strict mode
+func (m *Model) undeclaredAccount(postings []core.Posting) (string, bool) {
+	if !m.strict {
+		return "", false
+	}
+	for _, posting := range postings {
+		if !m.db.Declared[posting.Account] {
+			return posting.Account, true
+		}
+	}
+	return "", false
+}
+
+// formPostings builds the postings of every line with an account and a non-zero amount
+// Debits and Credits are purely organizational - amounts stored as-entered with no sign manipulation
+// Amounts take the placement and digit style of their commodity's declared format
+func (m *Model) formPostings() []core.Posting {
+	postings := make([]core.Posting, 0, len(m.form.debitLines)+len(m.form.creditLines))
+	for _, lines := range [][]postingLine{m.form.debitLines, m.form.creditLines} {
+		for i := range lines {
+			line := &lines[i]
+			account, kind := parseAccountInput(line.accountInput.Value())
+			posting, ok := linePosting(line, m.db.Formats)
+			if account == "" || !ok || posting.Amount.Quantity.IsZero() {
+				continue
+			}
+			posting.Account = account
+			posting.Kind = kind
+			posting.Amount = m.db.Formats.Apply(*posting.Amount)
+			posting.Comment = strings.TrimSpace(line.commentInput.Value())
+			postings = append(postings, posting)
+		}
+	}
+	return postings
+}
+
+// duplicateWarningFor describes an existing ledger or batch entry that looks like tx
+// The entry being edited is never compared against itself
+func (m *Model) duplicateWarningFor(tx core.Transaction) (string, bool) {
+	source := "ledger"
+	match, ok := intelligence.FindDuplicate(tx, m.db.Transactions)
+	if !ok {
+		others := make([]core.Transaction, 0, len(m.batch))
+		for i := range m.batch {
+			if i != m.editingIndex {
+				others = append(others, m.batch[i])
+			}
+		}
+		source = "batch"
+		match, ok = intelligence.FindDuplicate(tx, others)
+	}
+	if !ok {
+		return "", false
+	}
+	amount := intelligence.TransactionAmount(match)
+	return fmt.Sprintf("Possible duplicate of %s %s (%s) in the %s",
+		match.Date.Format("2006-01-02"), match.Payee, formatAmount(amount.Quantity, amount.Commodity), source), true
+}
+
+// activeDuplicateWarning returns the duplicate warning while the form still matches what was flagged
+func (m *Model) activeDuplicateWarning() string {
+	if m.form.duplicateWarning == "" || !m.currentFormSnapshot().equals(m.form.duplicateSnapshot) {
+		return ""
+	}
+	return m.form.duplicateWarning
+}
+
 // findTransactionIndex locates the index of a transaction in the batch
 // Returns the last index if no exact match is found
 func (m *Model) findTransactionIndex(tx core.Transaction) int {
@@ -246,7 +350,7 @@ func (m *Model) findTransactionIndex(tx core.Transaction) int {
 		if candidate.Date.Equal(tx.Date) && candidate.Payee == tx.Payee && len(candidate.Postings) == len(tx.Postings) {
 			match := true
 			for j := range candidate.Postings {
-				if candidate.Postings[j] != tx.Postings[j] {
+				if !candidate.Postings[j].Equal(tx.Postings[j]) {
 					match = false
 					break
 				}
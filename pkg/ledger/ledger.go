@@ -0,0 +1,121 @@
+// Package ledger reads ledger-cli journals for use from other Go programs.
+// It exposes teller's parser, which follows include directives, checks
+// balance assertions and reports problems as issues rather than failing,
+// and the intelligence teller builds from a journal to suggest payees,
+// accounts, amounts and expected transactions.
+package ledger
+
+import (
+	"io"
+
+	"git.sr.ht/~jakintosh/teller/internal/core"
+	"git.sr.ht/~jakintosh/teller/internal/intelligence"
+	"git.sr.ht/~jakintosh/teller/internal/parser"
+)
+
+// The journal's entries, shared with the rest of teller.
+type (
+	Transaction          = core.Transaction
+	Posting              = core.Posting
+	Amount               = core.Amount
+	MarketPrice          = core.MarketPrice
+	AutomatedTransaction = core.AutomatedTransaction
+	PeriodicTransaction  = core.PeriodicTransaction
+	Commodity            = core.Commodity
+	Price                = core.Price
+	Status               = core.Status
+	PostingKind          = core.PostingKind
+	NumberStyle          = core.NumberStyle
+	Interval             = core.Interval
+	Issue                = core.ParseIssue
+	Result               = core.ParseResult
+)
+
+// Statuses of a transaction.
+const (
+	Uncleared = core.Uncleared
+	Pending   = core.Pending
+	Cleared   = core.Cleared
+)
+
+// Kinds of posting.
+const (
+	RealPosting            = core.RealPosting
+	VirtualPosting         = core.VirtualPosting
+	BalancedVirtualPosting = core.BalancedVirtualPosting
+)
+
+// What teller learns from a journal, shared with the rest of teller.
+type (
+	Intelligence   = intelligence.IntelligenceDB
+	BuildReport    = intelligence.BuildReport
+	LoadIssue      = core.LoadIssue
+	TemplateRecord = intelligence.TemplateRecord
+	AmountStats    = intelligence.AmountStats
+	AccountUsage   = intelligence.AccountUsage
+	Match          = intelligence.Match
+	Recurrence     = intelligence.Recurrence
+	DueTransaction = intelligence.DueTransaction
+	Period         = intelligence.Period
+	PriceDB        = intelligence.PriceDB
+)
+
+// Periods of a recurring transaction.
+const (
+	Weekly    = intelligence.PeriodWeekly
+	Biweekly  = intelligence.PeriodBiweekly
+	Monthly   = intelligence.PeriodMonthly
+	Quarterly = intelligence.PeriodQuarterly
+	Yearly    = intelligence.PeriodYearly
+)
+
+// Options configures how a journal is read.
+type Options struct {
+	// Name is the journal's file name, used in issues and to resolve relative
+	// includes. Includes resolve from the working directory when it's empty,
+	// as when reading stdin.
+	Name string
+	// Year is the year of dates written without one until a year directive
+	// sets it. The current year is used when it's zero.
+	Year int
+	// DecimalComma reads a lone comma or point with three digits after it
+	// as a decimal comma or a thousands point, so "1,234" is 1.234 and
+	// "1.234" is 1234, for commodities declared without a format.
+	DecimalComma bool
+}
+
+// parser returns the parser's options for opts.
+func (opts Options) parser() parser.Options {
+	return parser.Options{Name: opts.Name, Year: opts.Year, DecimalComma: opts.DecimalComma}
+}
+
+// Parse reads a journal from r.
+func Parse(r io.Reader, opts Options) (Result, error) {
+	return parser.Parse(r, opts.parser())
+}
+
+// ParseFile reads the journal at path.
+func ParseFile(path string) (Result, error) {
+	return parser.ParseFile(path)
+}
+
+// Stream reads a journal from r, calling fn with each transaction in journal
+// order instead of keeping them, so large journals can be processed without
+// holding every transaction in memory. The result holds everything else,
+// including the issues. Reading stops with fn's error if it returns one.
+func Stream(r io.Reader, opts Options, fn func(Transaction) error) (Result, error) {
+	return parser.Stream(r, opts.parser(), fn)
+}
+
+// NewIntelligence builds the suggestion database from a parsed journal. The
+// report counts what was read and lists the parse issues along with any
+// problems found while analyzing the transactions.
+func NewIntelligence(result Result) (*Intelligence, BuildReport, error) {
+	return intelligence.NewIntelligenceDB(result)
+}
+
+// FuzzyMatch reports whether every character of query appears in text in
+// order, ignoring case, and scores the best such alignment.
+func FuzzyMatch(query, text string) (Match, bool) {
+	return intelligence.FuzzyMatch(query, text)
+}
@@ -0,0 +1,153 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const journal = `account Assets:Checking
+
+2025/01/15 Grocery Store
+    Expenses:Food    $25.00
+    Assets:Checking
+
+01/20 Coffee Shop
+    Expenses:Food    $4.50
+    Assets:Checking  = $-29.50
+
+2025/01/21 Bookshop
+    Expenses:Books   $12.00
+    Assets:Checking  $-12.00 = $0
+`
+
+func TestParseReadsFromReader(t *testing.T) {
+	result, err := Parse(strings.NewReader(journal), Options{Year: 2025})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(result.Transactions) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(result.Transactions))
+	}
+	if got := result.Transactions[1].Date.Format("2006/01/02"); got != "2025/01/20" {
+		t.Errorf("expected date without a year to fall in 2025, got %s", got)
+	}
+	if len(result.Accounts) != 1 || result.Accounts[0] != "Assets:Checking" {
+		t.Errorf("expected declared account, got %v", result.Accounts)
+	}
+	if len(result.Issues) != 1 || result.Issues[0].File != "" || result.Issues[0].Line != 13 {
+		t.Fatalf("expected one failed assertion on line 13, got %+v", result.Issues)
+	}
+	if got := result.Issues[0].Location(); got != "line 13" {
+		t.Errorf("expected issue without a file, got %q", got)
+	}
+}
+
+func TestParseResolvesIncludesFromName(t *testing.T) {
+	dir := t.TempDir()
+	included := "2025/02/01 Rent\n    Expenses:Rent    $900.00\n    Assets:Checking\n"
+	if err := os.WriteFile(filepath.Join(dir, "rent.ledger"), []byte(included), 0o600); err != nil {
+		t.Fatalf("failed to write included ledger: %v", err)
+	}
+
+	result, err := Parse(strings.NewReader("include rent.ledger\n"), Options{Name: filepath.Join(dir, "main.ledger")})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(result.Issues) != 0 || len(result.Transactions) != 1 || result.Transactions[0].Payee != "Rent" {
+		t.Fatalf("expected the included transaction, got %+v", result)
+	}
+}
+
+func TestStreamPassesTransactionsInOrder(t *testing.T) {
+	var payees []string
+	result, err := Stream(strings.NewReader(journal), Options{Year: 2025}, func(tx Transaction) error {
+		payees = append(payees, tx.Payee)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Stream returned error: %v", err)
+	}
+	if strings.Join(payees, ",") != "Grocery Store,Coffee Shop,Bookshop" {
+		t.Errorf("unexpected transactions %v", payees)
+	}
+	if len(result.Transactions) != 0 {
+		t.Errorf("expected streamed transactions not to be kept, got %d", len(result.Transactions))
+	}
+	if len(result.Issues) != 1 {
+		t.Errorf("expected the failed assertion to be reported, got %+v", result.Issues)
+	}
+}
+
+func TestStreamStopsAtCallbackError(t *testing.T) {
+	stop := errors.New("stop")
+	count := 0
+	_, err := Stream(strings.NewReader(journal), Options{Year: 2025}, func(tx Transaction) error {
+		count++
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected streaming to stop after the first transaction, got %d", count)
+	}
+}
+
+func TestExportedConstantsMatchParsedFields(t *testing.T) {
+	input := "2025/03/01 * Market\n    Expenses:Food    $10.00\n    (Budget:Food)    $-10.00\n    Assets:Checking\n"
+	result, err := Parse(strings.NewReader(input), Options{})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(result.Transactions))
+	}
+	tx := result.Transactions[0]
+	if tx.Status != Cleared {
+		t.Errorf("expected a cleared transaction, got %v", tx.Status)
+	}
+	kinds := []PostingKind{RealPosting, VirtualPosting, RealPosting}
+	for i, posting := range tx.Postings {
+		if posting.Kind != kinds[i] {
+			t.Errorf("posting %d: expected kind %v, got %v", i, kinds[i], posting.Kind)
+		}
+	}
+}
+
+func TestNewIntelligenceSuggestsFromJournal(t *testing.T) {
+	result, err := Parse(strings.NewReader(journal), Options{Year: 2025})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	db, report, err := NewIntelligence(result)
+	if err != nil {
+		t.Fatalf("NewIntelligence returned error: %v", err)
+	}
+	if report.Transactions != 3 || !report.HasIssues() {
+		t.Errorf("expected 3 transactions and the failed assertion, got %+v", report)
+	}
+	matches := db.MatchPayees("shop")
+	if len(matches) != 2 {
+		t.Fatalf("expected both shops to match, got %+v", matches)
+	}
+	if accounts := db.FindAccounts("Exp", ""); len(accounts) == 0 {
+		t.Errorf("expected account suggestions, got none")
+	}
+}
+
+func TestParseWithDecimalComma(t *testing.T) {
+	input := "2025/03/01 Bäckerei\n    Expenses:Food    1,234 €\n    Assets:Bank\n"
+	result, err := Parse(strings.NewReader(input), Options{DecimalComma: true})
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(result.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %+v", result)
+	}
+	if got := result.Transactions[0].Postings[0].Amount.Quantity.String(); got != "1.234" {
+		t.Errorf("expected a decimal comma, got %s", got)
+	}
+}